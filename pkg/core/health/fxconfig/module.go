@@ -10,9 +10,9 @@ import (
 // NewReadinessModule provides readiness management components for dependency injection.
 func NewReadinessModule() fx.Option {
 	return fx.Provide(
-		func(logger *zap.Logger, appConfig config.AppConfig) (health.ComponentManager, health.ReadinessChecker, health.ReadinessWaiter, health.TrafficController) {
+		func(logger *zap.Logger, appConfig config.AppConfig) (health.ComponentManager, health.ReadinessChecker, health.ReadinessWaiter, health.TrafficController, health.ComponentStateReporter) {
 			r := health.NewReadiness(logger, appConfig.IsKubernetes)
-			return r, r, r, r
+			return r, r, r, r, r
 		},
 	)
 }
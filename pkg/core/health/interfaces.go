@@ -11,6 +11,7 @@ type ComponentStatus struct {
 	Ready     bool      `json:"ready"`
 	StartedAt time.Time `json:"started_at"`
 	ReadyAt   time.Time `json:"ready_at,omitempty"`
+	State     string    `json:"state,omitempty"` // Operational state reported by the component, e.g. "paused"
 }
 
 // ReadinessStatus represents the overall readiness status of the service.
@@ -27,6 +28,13 @@ type ComponentManager interface {
 	AddComponent(name string) func()
 }
 
+// ComponentStateReporter annotates registered components with an operational state.
+// The state is informational and does not affect readiness.
+type ComponentStateReporter interface {
+	// SetComponentState sets the state of a component; an empty state clears it.
+	SetComponentState(name, state string)
+}
+
 // ReadinessChecker provides readiness status information.
 type ReadinessChecker interface {
 	IsReady() bool
@@ -14,6 +14,7 @@ type component struct {
 	ready     bool
 	startedAt time.Time
 	readyAt   time.Time
+	state     string
 }
 
 type Readiness struct {
@@ -111,6 +112,27 @@ func (r *Readiness) AddComponent(name string) func() {
 	}
 }
 
+func (r *Readiness) SetComponentState(name, state string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comp, exists := r.components[name]
+	if !exists {
+		r.logger.Warn("Cannot set state of unknown component",
+			zap.String("component", name),
+			zap.String("state", state),
+		)
+		return
+	}
+	if comp.state != state {
+		r.logger.Info("Component state changed",
+			zap.String("component", name),
+			zap.String("state", state),
+		)
+	}
+	comp.state = state
+}
+
 func (r *Readiness) IsReady() bool {
 	select {
 	case <-r.readyChan:
@@ -148,6 +170,7 @@ func (r *Readiness) GetStatus() ReadinessStatus {
 			Ready:     comp.ready,
 			StartedAt: comp.startedAt,
 			ReadyAt:   comp.readyAt,
+			State:     comp.state,
 		})
 	}
 
@@ -204,6 +204,38 @@ func TestGetStatus(t *testing.T) {
 	})
 }
 
+func TestSetComponentState(t *testing.T) {
+	t.Run("reports state without affecting readiness", func(t *testing.T) {
+		r := NewReadiness(zap.NewNop(), false)
+		r.AddComponent("kafka-consumer-orders")()
+
+		r.SetComponentState("kafka-consumer-orders", "paused")
+
+		status := r.GetStatus()
+		assert.True(t, status.Ready)
+		require.Len(t, status.Components, 1)
+		assert.Equal(t, "paused", status.Components[0].State)
+	})
+
+	t.Run("empty state clears it", func(t *testing.T) {
+		r := NewReadiness(zap.NewNop(), false)
+		r.AddComponent("kafka-consumer-orders")
+
+		r.SetComponentState("kafka-consumer-orders", "paused")
+		r.SetComponentState("kafka-consumer-orders", "")
+
+		assert.Empty(t, r.GetStatus().Components[0].State)
+	})
+
+	t.Run("ignores unknown component", func(t *testing.T) {
+		r := NewReadiness(zap.NewNop(), false)
+
+		r.SetComponentState("unknown", "paused")
+
+		assert.Empty(t, r.GetStatus().Components)
+	})
+}
+
 func TestWaitReady(t *testing.T) {
 	t.Run("blocks until ready", func(t *testing.T) {
 		logger := zap.NewNop()
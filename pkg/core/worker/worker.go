@@ -9,8 +9,8 @@ import (
 	"go.uber.org/zap"
 )
 
-// runnable is a type that has a Run method that can return a fatal error.
-type runnable interface {
+// Runnable is a type that has a Run method that can return a fatal error.
+type Runnable interface {
 	Run(ctx context.Context) error
 }
 
@@ -161,21 +161,34 @@ func registerWorker(lc fx.Lifecycle, w *baseWorker) {
 //	    worker.RunWorker[*reader]("reader", worker.WithTrafficReady(), worker.WithShutdown()),
 //	    worker.RunWorker[*processor]("processor", worker.WithReady()),
 //	)
-func RunWorker[T runnable](name string, opts ...Option) any {
+func RunWorker[T Runnable](name string, opts ...Option) any {
+	return func(lc fx.Lifecycle, log *zap.Logger, shutdowner fx.Shutdowner, readiness health.ReadinessWaiter, dep T) {
+		Register(lc, log, shutdowner, readiness, name, dep, opts...)
+	}
+}
+
+// Register registers r as a worker that starts and stops with the application.
+// Use it for workers that only run depending on configuration; otherwise prefer RunWorker.
+func Register(
+	lc fx.Lifecycle,
+	log *zap.Logger,
+	shutdowner fx.Shutdowner,
+	readiness health.ReadinessWaiter,
+	name string,
+	r Runnable,
+	opts ...Option,
+) {
 	options := Options{}
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return func(lc fx.Lifecycle, log *zap.Logger, shutdowner fx.Shutdowner, readiness health.ReadinessWaiter, dep T) {
-		w := &baseWorker{
-			name:       name,
-			log:        log,
-			runFunc:    dep.Run,
-			shutdowner: shutdowner,
-			readiness:  readiness,
-			options:    options,
-		}
-		registerWorker(lc, w)
-	}
+	registerWorker(lc, &baseWorker{
+		name:       name,
+		log:        log,
+		runFunc:    r.Run,
+		shutdowner: shutdowner,
+		readiness:  readiness,
+		options:    options,
+	})
 }
@@ -605,6 +605,32 @@ func TestRegisterWorker(t *testing.T) {
 	})
 }
 
+// runnableFunc adapts a function to Runnable.
+type runnableFunc func(ctx context.Context) error
+
+func (f runnableFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Test Register
+func TestRegister(t *testing.T) {
+	t.Run("shuts down on error with options", func(t *testing.T) {
+		readiness := newMockReadinessWaiter()
+		shutdowner := &mockShutdowner{}
+		lc := &mockLifecycle{}
+
+		Register(lc, zap.NewNop(), shutdowner, readiness, "test-worker",
+			runnableFunc(func(context.Context) error { return errors.New("fatal") }), WithShutdown())
+
+		require.Len(t, lc.hooks, 1)
+		require.NoError(t, lc.hooks[0].OnStart(context.Background()))
+
+		assert.Eventually(t, shutdowner.WasShutdownCalled, time.Second, 10*time.Millisecond)
+		assert.Equal(t, 1, shutdowner.GetExitCode())
+		require.NoError(t, lc.hooks[0].OnStop(context.Background()))
+	})
+}
+
 // mockLifecycle is a mock implementation of fx.Lifecycle
 type mockLifecycle struct {
 	hooks []fx.Hook
@@ -2,6 +2,14 @@ package config
 
 import "time"
 
+// Values of ConsumerConfig.ShardBy.
+const (
+	// ShardByKey keeps messages with the same record key on the same worker.
+	ShardByKey = "key"
+	// ShardByPartition keeps all messages of a partition on the same worker.
+	ShardByPartition = "partition"
+)
+
 const (
 	// Default values.
 	defaultMaxRetries                 = uint(2)
@@ -10,12 +18,18 @@ const (
 	defaultProcessingTimeout          = 30 * time.Second
 	defaultChannelBufferSize          = 100
 	defaultMaxPollRecords             = 500
+	defaultBatchSize                  = 100
+	defaultBatchLinger                = 100 * time.Millisecond
+	defaultConcurrency                = 1
+	defaultShardBy                    = ShardByKey
 	defaultConsumerReadinessTimeout   = 60
 	defaultProducerReadinessTimeout   = 30
 	defaultProducerLinger             = 5 * time.Millisecond
 	defaultProducerCompression        = "none"
 	defaultProducerDeliveryTimeout    = 30 * time.Second
 	defaultProducerMaxBufferedRecords = 10000
+	defaultAdminPathPrefix            = "/admin/consumers"
+	defaultMaxRedrives                = 3
 
 	// Validation bounds.
 	minMaxRetries         = uint(0)
@@ -30,6 +44,17 @@ const (
 	maxChannelBufferSize  = 10000
 	minMaxPollRecords     = 1
 	maxMaxPollRecords     = 10000
+	minBatchSize          = 1
+	maxBatchSize          = 10000
+	minBatchLinger        = 1 * time.Millisecond
+	maxBatchLinger        = 10 * time.Second
+	minConcurrency        = 1
+	maxConcurrency        = 256
+	minRetryDelay         = 1 * time.Second
+	maxRetryDelay         = 24 * time.Hour
+	maxRetryTiers         = 10
+	minMaxRedrives        = 1
+	maxMaxRedrives        = 100
 	maxReadinessTimeout   = 600 // 10 minutes in seconds
 
 	// Producer bounds.
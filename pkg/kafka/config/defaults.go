@@ -1,10 +1,20 @@
 package config
 
+import (
+	"math"
+	"time"
+)
+
 // ptrUint is a helper function to create a pointer to uint.
 func ptrUint(v uint) *uint {
 	return &v
 }
 
+// defaultRetryDelays are the retry tiers used when retry topics are enabled without explicit delays.
+func defaultRetryDelays() []time.Duration {
+	return []time.Duration{time.Minute, 10 * time.Minute}
+}
+
 // ApplyDefaults applies default values to the configuration.
 func (cfg *Config) ApplyDefaults() {
 	// Apply defaults for global consumer config
@@ -26,6 +36,18 @@ func (cfg *Config) ApplyDefaults() {
 	if cfg.ConsumersConfig.DefaultMaxPollRecords == 0 {
 		cfg.ConsumersConfig.DefaultMaxPollRecords = defaultMaxPollRecords
 	}
+	if cfg.ConsumersConfig.DefaultBatchSize == 0 {
+		cfg.ConsumersConfig.DefaultBatchSize = defaultBatchSize
+	}
+	if cfg.ConsumersConfig.DefaultBatchLinger == 0 {
+		cfg.ConsumersConfig.DefaultBatchLinger = defaultBatchLinger
+	}
+	if cfg.ConsumersConfig.DefaultConcurrency == 0 {
+		cfg.ConsumersConfig.DefaultConcurrency = defaultConcurrency
+	}
+	if cfg.ConsumersConfig.Admin.PathPrefix == "" {
+		cfg.ConsumersConfig.Admin.PathPrefix = defaultAdminPathPrefix
+	}
 
 	// Apply defaults from global consumer config to individual consumers
 	for i := range cfg.ConsumersConfig.ConsumerConfig {
@@ -58,8 +80,9 @@ func applyConsumerDefaults(consumer *ConsumerConfig, globalConfig *ConsumersConf
 	if consumer.AutoOffsetReset == "" {
 		consumer.AutoOffsetReset = globalConfig.DefaultAutoOffsetReset
 	}
-	// Apply default DLQ topic naming convention: {topic}.dlq
-	if consumer.EnableDLQ && consumer.DLQTopic == "" {
+	// Apply default DLQ topic naming convention: {topic}.dlq. Consumers of several topics
+	// keep it empty and use a DLQ per source topic.
+	if consumer.EnableDLQ && consumer.DLQTopic == "" && consumer.Topic != "" && len(consumer.Topics) == 0 {
 		consumer.DLQTopic = consumer.Topic + ".dlq"
 	}
 	// Apply default readiness timeout
@@ -90,4 +113,41 @@ func applyConsumerDefaults(consumer *ConsumerConfig, globalConfig *ConsumersConf
 	if consumer.MaxPollRecords == 0 {
 		consumer.MaxPollRecords = globalConfig.DefaultMaxPollRecords
 	}
+	// Apply default batch size and linger from global config
+	if consumer.BatchSize == 0 {
+		consumer.BatchSize = globalConfig.DefaultBatchSize
+	}
+	if consumer.BatchLinger == 0 {
+		consumer.BatchLinger = globalConfig.DefaultBatchLinger
+	}
+	// Apply default concurrency from global config; batch consumers process partitions in one worker
+	if consumer.Concurrency == 0 {
+		consumer.Concurrency = globalConfig.DefaultConcurrency
+		if consumer.Batch {
+			consumer.Concurrency = 1
+		}
+	}
+	if consumer.ShardBy == "" {
+		consumer.ShardBy = defaultShardBy
+	}
+	// Apply default rate burst: one second worth of messages
+	if consumer.RateLimit > 0 && consumer.RateBurst == 0 {
+		consumer.RateBurst = DefaultRateBurst(consumer.RateLimit)
+	}
+	// Apply default retry tiers
+	if consumer.RetryTopics.Enabled && len(consumer.RetryTopics.Delays) == 0 {
+		consumer.RetryTopics.Delays = defaultRetryDelays()
+	}
+	// Apply default redrive group naming convention: {group-id}.redrive
+	if consumer.Redrive.GroupID == "" && consumer.GroupID != "" {
+		consumer.Redrive.GroupID = consumer.GroupID + ".redrive"
+	}
+	if consumer.Redrive.MaxRedrives == 0 {
+		consumer.Redrive.MaxRedrives = defaultMaxRedrives
+	}
+}
+
+// DefaultRateBurst returns the burst used with a rate limit of perSecond messages: the rate rounded up, at least one.
+func DefaultRateBurst(perSecond float64) int {
+	return max(1, int(math.Ceil(perSecond)))
 }
@@ -24,6 +24,9 @@ func TestApplyDefaults_GlobalConsumerConfig(t *testing.T) {
 	assert.Equal(t, defaultInitialBackoff, cfg.ConsumersConfig.DefaultInitialBackoff)
 	assert.Equal(t, defaultMaxBackoff, cfg.ConsumersConfig.DefaultMaxBackoff)
 	assert.Equal(t, defaultChannelBufferSize, cfg.ConsumersConfig.DefaultChannelBufferSize)
+	assert.Equal(t, defaultBatchSize, cfg.ConsumersConfig.DefaultBatchSize)
+	assert.Equal(t, defaultBatchLinger, cfg.ConsumersConfig.DefaultBatchLinger)
+	assert.Equal(t, defaultAdminPathPrefix, cfg.ConsumersConfig.Admin.PathPrefix)
 }
 
 func TestApplyDefaults_GlobalConsumerConfigCustomValues(t *testing.T) {
@@ -83,6 +86,9 @@ func TestApplyConsumerDefaults_AllDefaults(t *testing.T) {
 		DefaultInitialBackoff:    2 * time.Second,
 		DefaultMaxBackoff:        1 * time.Minute,
 		DefaultChannelBufferSize: 200,
+		DefaultBatchSize:         50,
+		DefaultBatchLinger:       20 * time.Millisecond,
+		DefaultConcurrency:       4,
 	}
 
 	consumer := &ConsumerConfig{
@@ -99,6 +105,93 @@ func TestApplyConsumerDefaults_AllDefaults(t *testing.T) {
 	assert.Equal(t, 2*time.Second, consumer.InitialBackoff)
 	assert.Equal(t, 1*time.Minute, consumer.MaxBackoff)
 	assert.Equal(t, 200, consumer.ChannelBufferSize)
+	assert.Equal(t, 50, consumer.BatchSize)
+	assert.Equal(t, 20*time.Millisecond, consumer.BatchLinger)
+	assert.Equal(t, 4, consumer.Concurrency)
+	assert.Equal(t, ShardByKey, consumer.ShardBy)
+}
+
+func TestApplyConsumerDefaults_BatchConcurrency(t *testing.T) {
+	global := &ConsumersConfig{DefaultConcurrency: 4}
+
+	consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", Batch: true}
+	applyConsumerDefaults(consumer, global)
+	assert.Equal(t, 1, consumer.Concurrency)
+
+	consumer = &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", Batch: true, Concurrency: 4}
+	applyConsumerDefaults(consumer, global)
+	assert.Equal(t, 4, consumer.Concurrency, "explicit concurrency is left to validation")
+}
+
+func TestApplyConsumerDefaults_RetryTopics(t *testing.T) {
+	t.Run("enabled without delays uses default tiers", func(t *testing.T) {
+		consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", RetryTopics: RetryTopicsConfig{Enabled: true}}
+
+		applyConsumerDefaults(consumer, &ConsumersConfig{})
+
+		assert.Equal(t, []time.Duration{time.Minute, 10 * time.Minute}, consumer.RetryTopics.Delays)
+	})
+
+	t.Run("disabled keeps delays empty", func(t *testing.T) {
+		consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic"}
+
+		applyConsumerDefaults(consumer, &ConsumersConfig{})
+
+		assert.Empty(t, consumer.RetryTopics.Delays)
+	})
+}
+
+func TestApplyConsumerDefaults_RateBurst(t *testing.T) {
+	tests := []struct {
+		name      string
+		rateLimit float64
+		rateBurst int
+		expected  int
+	}{
+		{"unlimited keeps burst empty", 0, 0, 0},
+		{"rounds rate up", 2.5, 0, 3},
+		{"at least one", 0.1, 0, 1},
+		{"keeps explicit burst", 10, 50, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", RateLimit: tt.rateLimit, RateBurst: tt.rateBurst}
+
+			applyConsumerDefaults(consumer, &ConsumersConfig{})
+
+			assert.Equal(t, tt.expected, consumer.RateBurst)
+		})
+	}
+}
+
+func TestApplyConsumerDefaults_RedriveGroupID(t *testing.T) {
+	t.Run("derives redrive group from consumer group", func(t *testing.T) {
+		consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", GroupID: "orders-group"}
+
+		applyConsumerDefaults(consumer, &ConsumersConfig{})
+
+		assert.Equal(t, "orders-group.redrive", consumer.Redrive.GroupID)
+	})
+
+	t.Run("keeps explicit redrive group", func(t *testing.T) {
+		consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", GroupID: "orders-group",
+			Redrive: RedriveConfig{GroupID: "custom-redrive"}}
+
+		applyConsumerDefaults(consumer, &ConsumersConfig{})
+
+		assert.Equal(t, "custom-redrive", consumer.Redrive.GroupID)
+	})
+}
+
+func TestApplyConsumerDefaults_RedriveMaxRedrives(t *testing.T) {
+	consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic"}
+	applyConsumerDefaults(consumer, &ConsumersConfig{})
+	assert.Equal(t, defaultMaxRedrives, consumer.Redrive.MaxRedrives)
+
+	consumer = &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", Redrive: RedriveConfig{MaxRedrives: 10}}
+	applyConsumerDefaults(consumer, &ConsumersConfig{})
+	assert.Equal(t, 10, consumer.Redrive.MaxRedrives)
 }
 
 func TestApplyConsumerDefaults_CustomValues(t *testing.T) {
@@ -171,6 +264,24 @@ func TestApplyConsumerDefaults_DLQTopicNaming(t *testing.T) {
 			},
 			expectedDLQTopic: "",
 		},
+		{
+			name: "DLQ enabled, several topics",
+			consumer: ConsumerConfig{
+				Name:      "consumer4",
+				Topics:    []string{"orders", "payments"},
+				EnableDLQ: true,
+			},
+			expectedDLQTopic: "",
+		},
+		{
+			name: "DLQ enabled, topic pattern",
+			consumer: ConsumerConfig{
+				Name:         "consumer5",
+				TopicPattern: "^orders\\..*",
+				EnableDLQ:    true,
+			},
+			expectedDLQTopic: "",
+		},
 	}
 
 	for _, tt := range tests {
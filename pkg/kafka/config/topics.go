@@ -0,0 +1,37 @@
+package config
+
+// SourceTopics returns the topics listed in topic and topics without duplicates,
+// empty for a topic-pattern subscription.
+func (c *ConsumerConfig) SourceTopics() []string {
+	topics := make([]string, 0, len(c.Topics)+1)
+	seen := make(map[string]bool, len(c.Topics)+1)
+	for _, topic := range append([]string{c.Topic}, c.Topics...) {
+		if topic == "" || seen[topic] {
+			continue
+		}
+		seen[topic] = true
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// DLQTopicFor returns the DLQ topic of messages consumed from sourceTopic.
+func (c *ConsumerConfig) DLQTopicFor(sourceTopic string) string {
+	if c.DLQTopic != "" {
+		return c.DLQTopic
+	}
+	return sourceTopic + ".dlq"
+}
+
+// DLQTopics returns the DLQ topics of all source topics.
+func (c *ConsumerConfig) DLQTopics() []string {
+	if c.DLQTopic != "" {
+		return []string{c.DLQTopic}
+	}
+	sources := c.SourceTopics()
+	topics := make([]string, 0, len(sources))
+	for _, topic := range sources {
+		topics = append(topics, c.DLQTopicFor(topic))
+	}
+	return topics
+}
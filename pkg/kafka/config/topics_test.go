@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumerConfig_SourceTopics(t *testing.T) {
+	t.Run("single topic", func(t *testing.T) {
+		c := &ConsumerConfig{Topic: "orders"}
+		assert.Equal(t, []string{"orders"}, c.SourceTopics())
+	})
+
+	t.Run("topic and topics without duplicates", func(t *testing.T) {
+		c := &ConsumerConfig{Topic: "orders", Topics: []string{"payments", "orders", "shipments"}}
+		assert.Equal(t, []string{"orders", "payments", "shipments"}, c.SourceTopics())
+	})
+
+	t.Run("topic pattern has no source topics", func(t *testing.T) {
+		c := &ConsumerConfig{TopicPattern: "^orders\\..*"}
+		assert.Empty(t, c.SourceTopics())
+	})
+}
+
+func TestConsumerConfig_DLQTopics(t *testing.T) {
+	t.Run("configured DLQ topic is shared", func(t *testing.T) {
+		c := &ConsumerConfig{Topics: []string{"orders", "payments"}, DLQTopic: "events.dlq"}
+
+		assert.Equal(t, []string{"events.dlq"}, c.DLQTopics())
+		assert.Equal(t, "events.dlq", c.DLQTopicFor("payments"))
+	})
+
+	t.Run("DLQ per source topic", func(t *testing.T) {
+		c := &ConsumerConfig{Topics: []string{"orders", "payments"}}
+
+		assert.Equal(t, []string{"orders.dlq", "payments.dlq"}, c.DLQTopics())
+		assert.Equal(t, "payments.dlq", c.DLQTopicFor("payments"))
+	})
+}
@@ -19,25 +19,69 @@ type ConsumersConfig struct {
 	DefaultProcessingTimeout time.Duration    `koanf:"default-processing-timeout"`  // Default timeout for processing a single message (1s-10m)
 	DefaultChannelBufferSize int              `koanf:"default-channel-buffer-size"` // Default internal message channel buffer size (10-10000)
 	DefaultMaxPollRecords    int              `koanf:"default-max-poll-records"`    // Default max records per poll iteration (1-10000)
+	DefaultBatchSize         int              `koanf:"default-batch-size"`          // Default max messages per batch handler call (1-10000)
+	DefaultBatchLinger       time.Duration    `koanf:"default-batch-linger"`        // Default max wait for a batch to fill up (1ms-10s)
+	DefaultConcurrency       int              `koanf:"default-concurrency"`         // Default number of processor workers per consumer (1-256)
 	ConsumerConfig           []ConsumerConfig `koanf:"consumers"`                   // Individual consumer configurations
+	Admin                    AdminConfig      `koanf:"admin"`                       // HTTP endpoints to pause, resume and rate limit consumers at runtime
+}
+
+// AdminConfig configures the consumer admin endpoints. They are registered on the shared
+// *http.ServeMux, are not authenticated and should be exposed on internal networks only.
+type AdminConfig struct {
+	Enabled    bool   `koanf:"enabled"`     // Register the admin endpoints
+	PathPrefix string `koanf:"path-prefix"` // Route prefix of the admin endpoints (default "/admin/consumers")
 }
 
 // ConsumerConfig represents configuration for an individual Kafka consumer.
 type ConsumerConfig struct {
-	Name                    string        `koanf:"name"`                      // Unique consumer name/identifier (required)
-	Topic                   string        `koanf:"topic"`                     // Kafka topic to consume from (required)
-	GroupID                 string        `koanf:"group-id"`                  // Consumer group ID (defaults to DefaultGroupID)
-	AutoOffsetReset         string        `koanf:"auto-offset-reset"`         // Offset reset policy: "earliest" or "latest" (defaults to DefaultAutoOffsetReset)
-	EnableDLQ               bool          `koanf:"enable-dlq"`                // Enable Dead Letter Queue for failed messages
-	DLQTopic                string        `koanf:"dlq-topic"`                 // DLQ topic name (defaults to "{topic}.dlq" if EnableDLQ is true)
-	ReadinessTimeoutSeconds int           `koanf:"readiness-timeout-seconds"` // Timeout in seconds for waiting topic readiness (0 = no timeout, max 600s)
-	FailOnTopicError        bool          `koanf:"fail-on-topic-error"`       // Whether to fail application startup if topic is not available
-	MaxRetries              *uint         `koanf:"max-retries"`               // Maximum retries for message processing (0-99, defaults to DefaultMaxRetries)
-	InitialBackoff          time.Duration `koanf:"initial-backoff"`           // Initial backoff duration between retries (100ms-30s, defaults to DefaultInitialBackoff)
-	MaxBackoff              time.Duration `koanf:"max-backoff"`               // Maximum backoff duration between retries (1s-5m, defaults to DefaultMaxBackoff)
-	ProcessingTimeout       time.Duration `koanf:"processing-timeout"`        // Timeout for processing a single message attempt (1s-10m, defaults to DefaultProcessingTimeout)
-	ChannelBufferSize       int           `koanf:"channel-buffer-size"`       // Internal message channel buffer size (10-10000, defaults to DefaultChannelBufferSize)
-	MaxPollRecords          int           `koanf:"max-poll-records"`          // Max records fetched per poll iteration (1-10000, defaults to DefaultMaxPollRecords)
+	Name                    string            `koanf:"name"`                      // Unique consumer name/identifier (required)
+	Topic                   string            `koanf:"topic"`                     // Kafka topic to consume from (one of topic, topics or topic-pattern is required)
+	Topics                  []string          `koanf:"topics"`                    // Further topics consumed by the same group and handler
+	TopicPattern            string            `koanf:"topic-pattern"`             // Regular expression of topics to consume, excludes topic and topics; must not match the DLQ or retry topics
+	EventTypes              []string          `koanf:"event-types"`               // Proto full names of the expected events; startup fails if any of them has no handler
+	GroupID                 string            `koanf:"group-id"`                  // Consumer group ID (defaults to DefaultGroupID)
+	AutoOffsetReset         string            `koanf:"auto-offset-reset"`         // Offset reset policy: "earliest" or "latest" (defaults to DefaultAutoOffsetReset)
+	EnableDLQ               bool              `koanf:"enable-dlq"`                // Enable Dead Letter Queue for failed messages
+	DLQTopic                string            `koanf:"dlq-topic"`                 // DLQ topic name (defaults to "{topic}.dlq" for a single topic, otherwise each source topic has its own "{source}.dlq")
+	ReadinessTimeoutSeconds int               `koanf:"readiness-timeout-seconds"` // Timeout in seconds for waiting topic readiness (0 = no timeout, max 600s)
+	FailOnTopicError        bool              `koanf:"fail-on-topic-error"`       // Whether to fail application startup if topic is not available
+	MaxRetries              *uint             `koanf:"max-retries"`               // Maximum retries for message processing (0-99, defaults to DefaultMaxRetries)
+	InitialBackoff          time.Duration     `koanf:"initial-backoff"`           // Initial backoff duration between retries (100ms-30s, defaults to DefaultInitialBackoff)
+	MaxBackoff              time.Duration     `koanf:"max-backoff"`               // Maximum backoff duration between retries (1s-5m, defaults to DefaultMaxBackoff)
+	ProcessingTimeout       time.Duration     `koanf:"processing-timeout"`        // Timeout for processing a single message attempt (1s-10m, defaults to DefaultProcessingTimeout)
+	ChannelBufferSize       int               `koanf:"channel-buffer-size"`       // Internal message channel buffer size (10-10000, defaults to DefaultChannelBufferSize)
+	MaxPollRecords          int               `koanf:"max-poll-records"`          // Max records fetched per poll iteration (1-10000, defaults to DefaultMaxPollRecords)
+	Batch                   bool              `koanf:"batch"`                     // Consume with a batch handler (RegisterBatchHandlerAndConsumer); excludes retry topics, concurrency, rate limits and the inbox
+	BatchSize               int               `koanf:"batch-size"`                // Max messages of one partition per batch handler call (1-10000, defaults to DefaultBatchSize)
+	BatchLinger             time.Duration     `koanf:"batch-linger"`              // Max wait for a batch to fill up before it is processed (1ms-10s, defaults to DefaultBatchLinger)
+	Concurrency             int               `koanf:"concurrency"`               // Number of processor workers (1-256, defaults to DefaultConcurrency)
+	ShardBy                 string            `koanf:"shard-by"`                  // How messages are spread across workers: "key" or "partition" (default "key"); order is kept per shard
+	RateLimit               float64           `koanf:"rate-limit"`                // Max messages processed per second (0 = unlimited), adjustable at runtime through the admin endpoints
+	RateBurst               int               `koanf:"rate-burst"`                // Messages processed at once above the rate limit (defaults to the rate limit rounded up)
+	RetryTopics             RetryTopicsConfig `koanf:"retry-topics"`              // Non-blocking retries through delayed retry topics
+	Redrive                 RedriveConfig     `koanf:"redrive"`                   // Selection of DLQ records republished by the redriver
+}
+
+// RetryTopicsConfig configures retry topics. A message that fails with a retriable error is
+// republished to {topic}.retry.{delay} of the next tier and reprocessed once the delay passed;
+// after the last tier it goes to the DLQ. The retry topics must exist. In-process retries are disabled
+// while retry topics are enabled.
+type RetryTopicsConfig struct {
+	Enabled bool            `koanf:"enabled"` // Enable retry topics instead of in-process backoff
+	Delays  []time.Duration `koanf:"delays"`  // Delay of each retry tier in ascending order (1s-24h each, default [1m, 10m])
+}
+
+// RedriveConfig selects the DLQ records the redriver republishes to their original topic.
+// Empty filter fields match every record.
+type RedriveConfig struct {
+	GroupID       string    `koanf:"group-id"`       // Consumer group of the redrive worker (defaults to "{group-id}.redrive")
+	DryRun        bool      `koanf:"dry-run"`        // Only log and count matching records without republishing them
+	FailedAfter   time.Time `koanf:"failed-after"`   // Only records moved to the DLQ at or after this time (RFC 3339)
+	FailedBefore  time.Time `koanf:"failed-before"`  // Only records moved to the DLQ before this time (RFC 3339)
+	ErrorContains string    `koanf:"error-contains"` // Only records whose DLQ error contains this substring
+	EventType     string    `koanf:"event-type"`     // Only records with this event_type header
+	MaxRedrives   int       `koanf:"max-redrives"`   // Records already redriven this many times stay in the DLQ (1-100, default 3)
 }
 
 // ProducerConfig represents configuration for Kafka producer.
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -16,6 +18,9 @@ func (cfg *Config) Validate() error {
 	if err := validateIndividualConsumers(cfg.ConsumersConfig.ConsumerConfig); err != nil {
 		return err
 	}
+	if err := validateAdmin(&cfg.ConsumersConfig.Admin); err != nil {
+		return err
+	}
 	if err := validateProducerConfig(&cfg.ProducerConfig); err != nil {
 		return err
 	}
@@ -65,6 +70,21 @@ func validateGlobalConsumerConfig(cfg *ConsumersConfig) error {
 		return fmt.Errorf("default max poll records must be between %d and %d, got: %d",
 			minMaxPollRecords, maxMaxPollRecords, cfg.DefaultMaxPollRecords)
 	}
+	if cfg.DefaultBatchSize > 0 &&
+		(cfg.DefaultBatchSize < minBatchSize || cfg.DefaultBatchSize > maxBatchSize) {
+		return fmt.Errorf("default batch size must be between %d and %d, got: %d",
+			minBatchSize, maxBatchSize, cfg.DefaultBatchSize)
+	}
+	if cfg.DefaultBatchLinger > 0 &&
+		(cfg.DefaultBatchLinger < minBatchLinger || cfg.DefaultBatchLinger > maxBatchLinger) {
+		return fmt.Errorf("default batch linger must be between %v and %v, got: %v",
+			minBatchLinger, maxBatchLinger, cfg.DefaultBatchLinger)
+	}
+	if cfg.DefaultConcurrency > 0 &&
+		(cfg.DefaultConcurrency < minConcurrency || cfg.DefaultConcurrency > maxConcurrency) {
+		return fmt.Errorf("default concurrency must be between %d and %d, got: %d",
+			minConcurrency, maxConcurrency, cfg.DefaultConcurrency)
+	}
 	return nil
 }
 
@@ -85,8 +105,8 @@ func validateConsumer(index int, consumer *ConsumerConfig) error {
 	if strings.TrimSpace(consumer.Name) == "" {
 		return fmt.Errorf("consumer[%d]: name cannot be empty", index)
 	}
-	if strings.TrimSpace(consumer.Topic) == "" {
-		return fmt.Errorf("consumer[%d] (%s): topic cannot be empty", index, consumer.Name)
+	if err := validateTopics(index, consumer); err != nil {
+		return err
 	}
 	if consumer.AutoOffsetReset != "" && consumer.AutoOffsetReset != "earliest" && consumer.AutoOffsetReset != "latest" {
 		return fmt.Errorf("consumer[%d] (%s): auto offset reset must be 'earliest' or 'latest', got: %s",
@@ -129,13 +149,137 @@ func validateConsumer(index int, consumer *ConsumerConfig) error {
 		return fmt.Errorf("consumer[%d] (%s): max poll records must be between %d and %d, got: %d",
 			index, consumer.Name, minMaxPollRecords, maxMaxPollRecords, consumer.MaxPollRecords)
 	}
-	if consumer.EnableDLQ && strings.TrimSpace(consumer.DLQTopic) != "" && consumer.DLQTopic == consumer.Topic {
+	if consumer.BatchSize > 0 &&
+		(consumer.BatchSize < minBatchSize || consumer.BatchSize > maxBatchSize) {
+		return fmt.Errorf("consumer[%d] (%s): batch size must be between %d and %d, got: %d",
+			index, consumer.Name, minBatchSize, maxBatchSize, consumer.BatchSize)
+	}
+	if consumer.BatchLinger > 0 &&
+		(consumer.BatchLinger < minBatchLinger || consumer.BatchLinger > maxBatchLinger) {
+		return fmt.Errorf("consumer[%d] (%s): batch linger must be between %v and %v, got: %v",
+			index, consumer.Name, minBatchLinger, maxBatchLinger, consumer.BatchLinger)
+	}
+	if consumer.Concurrency > 0 &&
+		(consumer.Concurrency < minConcurrency || consumer.Concurrency > maxConcurrency) {
+		return fmt.Errorf("consumer[%d] (%s): concurrency must be between %d and %d, got: %d",
+			index, consumer.Name, minConcurrency, maxConcurrency, consumer.Concurrency)
+	}
+	if consumer.ShardBy != "" && consumer.ShardBy != ShardByKey && consumer.ShardBy != ShardByPartition {
+		return fmt.Errorf("consumer[%d] (%s): shard by must be '%s' or '%s', got: %s",
+			index, consumer.Name, ShardByKey, ShardByPartition, consumer.ShardBy)
+	}
+	if consumer.RateLimit < 0 || consumer.RateBurst < 0 {
+		return fmt.Errorf("consumer[%d] (%s): rate limit and rate burst cannot be negative, got: %v and %d",
+			index, consumer.Name, consumer.RateLimit, consumer.RateBurst)
+	}
+	for _, eventType := range consumer.EventTypes {
+		if strings.TrimSpace(eventType) == "" {
+			return fmt.Errorf("consumer[%d] (%s): event types cannot contain an empty name", index, consumer.Name)
+		}
+	}
+	if err := validateRetryTopics(index, consumer); err != nil {
+		return err
+	}
+	if err := validateBatch(index, consumer); err != nil {
+		return err
+	}
+	if !consumer.Redrive.FailedAfter.IsZero() && !consumer.Redrive.FailedBefore.IsZero() &&
+		!consumer.Redrive.FailedBefore.After(consumer.Redrive.FailedAfter) {
+		return fmt.Errorf("consumer[%d] (%s): redrive failed-before (%v) must be after failed-after (%v)",
+			index, consumer.Name, consumer.Redrive.FailedBefore, consumer.Redrive.FailedAfter)
+	}
+	if consumer.Redrive.MaxRedrives != 0 &&
+		(consumer.Redrive.MaxRedrives < minMaxRedrives || consumer.Redrive.MaxRedrives > maxMaxRedrives) {
+		return fmt.Errorf("consumer[%d] (%s): redrive max-redrives must be between %d and %d, got: %d",
+			index, consumer.Name, minMaxRedrives, maxMaxRedrives, consumer.Redrive.MaxRedrives)
+	}
+	if consumer.EnableDLQ && strings.TrimSpace(consumer.DLQTopic) != "" && slices.Contains(consumer.SourceTopics(), consumer.DLQTopic) {
 		return fmt.Errorf("consumer[%d] (%s): DLQ topic cannot be the same as main topic",
 			index, consumer.Name)
 	}
 	return nil
 }
 
+// validateAdmin validates the consumer admin endpoints configuration.
+func validateAdmin(cfg *AdminConfig) error {
+	if cfg.Enabled && (!strings.HasPrefix(cfg.PathPrefix, "/") || strings.HasSuffix(cfg.PathPrefix, "/")) {
+		return fmt.Errorf("consumer admin path prefix must start with '/' and must not end with '/', got: %q", cfg.PathPrefix)
+	}
+	return nil
+}
+
+// validateTopics validates the subscription of a consumer: topic and topics, or topic-pattern.
+func validateTopics(index int, consumer *ConsumerConfig) error {
+	if consumer.TopicPattern != "" {
+		if consumer.Topic != "" || len(consumer.Topics) > 0 {
+			return fmt.Errorf("consumer[%d] (%s): topic-pattern cannot be combined with topic or topics",
+				index, consumer.Name)
+		}
+		if _, err := regexp.Compile(consumer.TopicPattern); err != nil {
+			return fmt.Errorf("consumer[%d] (%s): invalid topic-pattern: %w", index, consumer.Name, err)
+		}
+		if consumer.RetryTopics.Enabled {
+			return fmt.Errorf("consumer[%d] (%s): retry topics require topic or topics, not topic-pattern",
+				index, consumer.Name)
+		}
+		return nil
+	}
+	for _, topic := range consumer.Topics {
+		if strings.TrimSpace(topic) == "" {
+			return fmt.Errorf("consumer[%d] (%s): topics cannot contain an empty name", index, consumer.Name)
+		}
+	}
+	if len(consumer.SourceTopics()) == 0 {
+		return fmt.Errorf("consumer[%d] (%s): topic cannot be empty, set topic, topics or topic-pattern",
+			index, consumer.Name)
+	}
+	return nil
+}
+
+// validateRetryTopics validates the retry tiers of a consumer.
+func validateRetryTopics(index int, consumer *ConsumerConfig) error {
+	if !consumer.RetryTopics.Enabled {
+		return nil
+	}
+	delays := consumer.RetryTopics.Delays
+	if len(delays) > maxRetryTiers {
+		return fmt.Errorf("consumer[%d] (%s): at most %d retry tiers are supported, got: %d",
+			index, consumer.Name, maxRetryTiers, len(delays))
+	}
+	for i, delay := range delays {
+		if delay < minRetryDelay || delay > maxRetryDelay {
+			return fmt.Errorf("consumer[%d] (%s): retry delay must be between %v and %v, got: %v",
+				index, consumer.Name, minRetryDelay, maxRetryDelay, delay)
+		}
+		if i > 0 && delay <= delays[i-1] {
+			return fmt.Errorf("consumer[%d] (%s): retry delays must be in ascending order, got: %v",
+				index, consumer.Name, delays)
+		}
+	}
+	return nil
+}
+
+// validateBatch rejects the settings a batch consumer does not support: its messages are
+// retried in-process only and dispatched by a single worker without a Controller.
+func validateBatch(index int, consumer *ConsumerConfig) error {
+	if !consumer.Batch {
+		return nil
+	}
+	if consumer.RetryTopics.Enabled {
+		return fmt.Errorf("consumer[%d] (%s): retry topics are not supported by batch consumers",
+			index, consumer.Name)
+	}
+	if consumer.Concurrency > 1 {
+		return fmt.Errorf("consumer[%d] (%s): concurrency is not supported by batch consumers, got: %d",
+			index, consumer.Name, consumer.Concurrency)
+	}
+	if consumer.RateLimit > 0 {
+		return fmt.Errorf("consumer[%d] (%s): rate limit is not supported by batch consumers",
+			index, consumer.Name)
+	}
+	return nil
+}
+
 // validateProducerConfig validates producer configuration.
 func validateProducerConfig(cfg *ProducerConfig) error {
 	if cfg.ReadinessTimeoutSeconds > maxReadinessTimeout {
@@ -196,6 +196,278 @@ func TestValidateGlobalConsumerConfig_ChannelBufferSize(t *testing.T) {
 	}
 }
 
+func TestValidateConsumer_Batch(t *testing.T) {
+	tests := []struct {
+		name        string
+		batchSize   int
+		batchLinger time.Duration
+		expectError string
+	}{
+		{"zero (will use default)", 0, 0, ""},
+		{"valid", 200, 50 * time.Millisecond, ""},
+		{"batch size above maximum", maxBatchSize + 1, 0, "batch size must be between"},
+		{"linger below minimum", 0, time.Microsecond, "batch linger must be between"},
+		{"linger above maximum", 0, maxBatchLinger + time.Second, "batch linger must be between"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &ConsumerConfig{
+				Name:        "test-consumer",
+				Topic:       "test-topic",
+				BatchSize:   tt.batchSize,
+				BatchLinger: tt.batchLinger,
+			}
+			err := validateConsumer(0, consumer)
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateConsumer_BatchUnsupportedSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(*ConsumerConfig)
+		expectError string
+	}{
+		{"plain batch consumer", func(*ConsumerConfig) {}, ""},
+		{"single worker", func(c *ConsumerConfig) { c.Concurrency = 1 }, ""},
+		{"retry topics", func(c *ConsumerConfig) { c.RetryTopics = RetryTopicsConfig{Enabled: true} }, "retry topics are not supported"},
+		{"concurrency", func(c *ConsumerConfig) { c.Concurrency = 4 }, "concurrency is not supported"},
+		{"rate limit", func(c *ConsumerConfig) { c.RateLimit = 10 }, "rate limit is not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", Batch: true}
+			tt.modify(consumer)
+			err := validateConsumer(0, consumer)
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateConsumer_Concurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		shardBy     string
+		expectError string
+	}{
+		{"zero (will use default)", 0, "", ""},
+		{"shard by key", 8, ShardByKey, ""},
+		{"shard by partition", maxConcurrency, ShardByPartition, ""},
+		{"concurrency above maximum", maxConcurrency + 1, "", "concurrency must be between"},
+		{"unknown shard by", 4, "tenant", "shard by must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &ConsumerConfig{
+				Name:        "test-consumer",
+				Topic:       "test-topic",
+				Concurrency: tt.concurrency,
+				ShardBy:     tt.shardBy,
+			}
+			err := validateConsumer(0, consumer)
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateConsumer_RateLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		rateLimit   float64
+		rateBurst   int
+		expectError bool
+	}{
+		{"unlimited", 0, 0, false},
+		{"fractional rate", 0.5, 1, false},
+		{"negative rate", -1, 0, true},
+		{"negative burst", 10, -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", RateLimit: tt.rateLimit, RateBurst: tt.rateBurst}
+			err := validateConsumer(0, consumer)
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "cannot be negative")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateConsumer_EventTypes(t *testing.T) {
+	consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", EventTypes: []string{"tenant.v1.TenantCreatedEvent"}}
+	require.NoError(t, validateConsumer(0, consumer))
+
+	consumer.EventTypes = append(consumer.EventTypes, " ")
+	err := validateConsumer(0, consumer)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "event types cannot contain an empty name")
+}
+
+func TestValidateAdmin(t *testing.T) {
+	tests := []struct {
+		name        string
+		admin       AdminConfig
+		expectError bool
+	}{
+		{"disabled ignores prefix", AdminConfig{PathPrefix: "admin"}, false},
+		{"valid prefix", AdminConfig{Enabled: true, PathPrefix: "/admin/consumers"}, false},
+		{"relative prefix", AdminConfig{Enabled: true, PathPrefix: "admin/consumers"}, true},
+		{"trailing slash", AdminConfig{Enabled: true, PathPrefix: "/admin/consumers/"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAdmin(&tt.admin)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateConsumer_RetryTopics(t *testing.T) {
+	tests := []struct {
+		name        string
+		retryTopics RetryTopicsConfig
+		expectError string
+	}{
+		{"disabled ignores delays", RetryTopicsConfig{Delays: []time.Duration{time.Millisecond}}, ""},
+		{"ascending delays", RetryTopicsConfig{Enabled: true, Delays: []time.Duration{time.Minute, time.Hour}}, ""},
+		{"delay below minimum", RetryTopicsConfig{Enabled: true, Delays: []time.Duration{time.Millisecond}}, "retry delay must be between"},
+		{"descending delays", RetryTopicsConfig{Enabled: true, Delays: []time.Duration{time.Hour, time.Minute}}, "ascending order"},
+		{"duplicate delays", RetryTopicsConfig{Enabled: true, Delays: []time.Duration{time.Minute, time.Minute}}, "ascending order"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &ConsumerConfig{
+				Name:        "test-consumer",
+				Topic:       "test-topic",
+				RetryTopics: tt.retryTopics,
+			}
+			err := validateConsumer(0, consumer)
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateConsumer_RedriveTimeRange(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		redrive     RedriveConfig
+		expectError bool
+	}{
+		{"no time range", RedriveConfig{}, false},
+		{"only lower bound", RedriveConfig{FailedAfter: now}, false},
+		{"valid range", RedriveConfig{FailedAfter: now, FailedBefore: now.Add(time.Hour)}, false},
+		{"empty range", RedriveConfig{FailedAfter: now, FailedBefore: now}, true},
+		{"inverted range", RedriveConfig{FailedAfter: now, FailedBefore: now.Add(-time.Hour)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", Redrive: tt.redrive}
+			err := validateConsumer(0, consumer)
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "redrive failed-before")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateConsumer_RedriveMaxRedrives(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxRedrives int
+		expectError bool
+	}{
+		{"zero (will use default)", 0, false},
+		{"valid", 5, false},
+		{"negative", -1, true},
+		{"above maximum", maxMaxRedrives + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &ConsumerConfig{Name: "test-consumer", Topic: "test-topic", Redrive: RedriveConfig{MaxRedrives: tt.maxRedrives}}
+			err := validateConsumer(0, consumer)
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "redrive max-redrives must be between")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateConsumer_Topics(t *testing.T) {
+	tests := []struct {
+		name        string
+		consumer    ConsumerConfig
+		expectError string
+	}{
+		{"topics list", ConsumerConfig{Topics: []string{"orders", "payments"}}, ""},
+		{"topic and topics", ConsumerConfig{Topic: "orders", Topics: []string{"payments"}}, ""},
+		{"topic pattern", ConsumerConfig{TopicPattern: `^orders\.[a-z]+$`}, ""},
+		{"no topic", ConsumerConfig{}, "topic cannot be empty"},
+		{"empty name in topics", ConsumerConfig{Topics: []string{"orders", " "}}, "empty name"},
+		{"pattern with topic", ConsumerConfig{Topic: "orders", TopicPattern: "orders.*"}, "cannot be combined"},
+		{"invalid pattern", ConsumerConfig{TopicPattern: "orders.(*"}, "invalid topic-pattern"},
+		{"pattern with retry topics", ConsumerConfig{TopicPattern: "orders.*", RetryTopics: RetryTopicsConfig{Enabled: true}}, "retry topics require"},
+		{"DLQ topic among topics", ConsumerConfig{Topics: []string{"orders", "payments"}, EnableDLQ: true, DLQTopic: "payments"}, "DLQ topic cannot be the same"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := tt.consumer
+			consumer.Name = "test-consumer"
+			err := validateConsumer(0, &consumer)
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestValidateConsumer_EmptyName(t *testing.T) {
 	consumer := &ConsumerConfig{
 		Name:  "",
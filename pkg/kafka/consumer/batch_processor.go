@@ -0,0 +1,311 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// pendingBatch collects messages of one partition until it is full or lingered long enough.
+type pendingBatch struct {
+	envelopes []*MessageEnvelope
+	deadline  time.Time
+}
+
+// BatchProcessor groups envelopes by partition and hands them to a BatchHandler.
+// A batch is processed once it holds BatchSize messages or its first message
+// waited BatchLinger, whichever comes first.
+//
+// Results are classified by the ErrorClassifier of the handler. On ErrPauseConsumer for any message
+// the processor pauses the consumer and processes those messages again once resumed; on
+// ErrCrashConsumer Run returns the error without marking any message of the batch.
+type BatchProcessor struct {
+	envelopeChan  <-chan *MessageEnvelope
+	handler       BatchHandler
+	classifier    ErrorClassifier
+	log           *zap.Logger
+	resultHandler *ResultHandler
+	tracer        MessageTracer
+	controller    *Controller
+	metrics       *Metrics
+	consumerName  string
+
+	batchSize   int
+	batchLinger time.Duration
+
+	// Retry configuration
+	maxRetries        uint64
+	initialBackoff    time.Duration
+	maxBackoff        time.Duration
+	processingTimeout time.Duration
+}
+
+func NewBatchProcessor(
+	envelopeChan chan *MessageEnvelope,
+	handler BatchHandler,
+	log *zap.Logger,
+	resultHandler *ResultHandler,
+	tracer MessageTracer,
+	controller *Controller,
+	metrics *Metrics,
+	consumerConf config.ConsumerConfig,
+) *BatchProcessor {
+	return &BatchProcessor{
+		envelopeChan:      envelopeChan,
+		handler:           handler,
+		classifier:        errorClassifierFor(handler),
+		log:               log,
+		resultHandler:     resultHandler,
+		tracer:            tracer,
+		controller:        controller,
+		metrics:           metrics,
+		consumerName:      consumerConf.Name,
+		batchSize:         consumerConf.BatchSize,
+		batchLinger:       consumerConf.BatchLinger,
+		maxRetries:        uint64(*consumerConf.MaxRetries),
+		initialBackoff:    consumerConf.InitialBackoff,
+		maxBackoff:        consumerConf.MaxBackoff,
+		processingTimeout: consumerConf.ProcessingTimeout,
+	}
+}
+
+func (p *BatchProcessor) Run(ctx context.Context) error {
+	batches := make(map[topicPartition]*pendingBatch)
+	timer := time.NewTimer(p.batchLinger)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Unprocessed messages are not marked and are consumed again after restart.
+			return nil
+		case envelope := <-p.envelopeChan:
+			tp := topicPartition{topic: envelope.Record.Topic, partition: envelope.Record.Partition}
+			batch, ok := batches[tp]
+			if !ok {
+				batch = &pendingBatch{deadline: time.Now().Add(p.batchLinger)}
+				batches[tp] = batch
+			}
+			batch.envelopes = append(batch.envelopes, envelope)
+			if len(batch.envelopes) >= p.batchSize {
+				delete(batches, tp)
+				if err := p.processBatch(ctx, batch.envelopes); err != nil {
+					return err
+				}
+			}
+		case <-timer.C:
+			now := time.Now()
+			for tp, batch := range batches {
+				if !batch.deadline.After(now) {
+					delete(batches, tp)
+					if err := p.processBatch(ctx, batch.envelopes); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		timer.Stop()
+		if next, ok := nextDeadline(batches); ok {
+			timer.Reset(time.Until(next))
+		}
+	}
+}
+
+func nextDeadline(batches map[topicPartition]*pendingBatch) (time.Time, bool) {
+	var next time.Time
+	for _, batch := range batches {
+		if next.IsZero() || batch.deadline.Before(next) {
+			next = batch.deadline
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// processBatch processes a batch and returns an error only when the consumer must crash.
+func (p *BatchProcessor) processBatch(ctx context.Context, batch []*MessageEnvelope) error {
+	start := time.Now()
+	contexts := make([]context.Context, len(batch))
+	spans := make([]trace.Span, len(batch))
+	for i, envelope := range batch {
+		msgCtx := p.tracer.ExtractContext(ctx, envelope.Record)
+		msgCtx = tenant.ContextFromKafkaHeaders(msgCtx, envelope.Record.Headers)
+		contexts[i], spans[i] = p.tracer.StartConsumerSpan(msgCtx, envelope.Record)
+	}
+	defer func() {
+		for _, span := range spans {
+			span.End()
+		}
+	}()
+
+	results := p.executeWithRetry(ctx, batch)
+
+	// Призупиняємо consumer і повторюємо повідомлення після відновлення
+	for p.controller != nil {
+		var paused []*MessageEnvelope
+		var indexes []int
+		for i, err := range results {
+			if errors.Is(err, ErrPauseConsumer) {
+				p.log.Error("pausing consumer on message processing error", p.resultHandler.recordFieldsWithError(batch[i].Record, err)...)
+				spans[i].RecordError(err)
+				paused = append(paused, batch[i])
+				indexes = append(indexes, i)
+			}
+		}
+		if len(paused) == 0 {
+			break
+		}
+		p.controller.Pause()
+		if !p.resultHandler.park(ctx, p.controller, batchRecords(batch)...) {
+			// Shutting down, or the offsets were reset past the batch; it is not marked.
+			return nil
+		}
+		for i, err := range p.executeWithRetry(ctx, paused) {
+			results[indexes[i]] = err
+		}
+	}
+
+	for i, err := range results {
+		if errors.Is(err, ErrCrashConsumer) {
+			// No message of the batch is marked; the batch is consumed again after restart.
+			spans[i].RecordError(err)
+			spans[i].SetStatus(codes.Error, "message processing failed - crashing consumer")
+			record := batch[i].Record
+			return fmt.Errorf("consumer %s crashed on %s/%d@%d: %w", p.consumerName,
+				record.Topic, record.Partition, record.Offset, err)
+		}
+	}
+
+	// Every message of the batch is recorded with the processing duration of the whole batch.
+	duration := time.Since(start)
+	for i, envelope := range batch {
+		outcome := p.resultHandler.handle(contexts[i], results[i], envelope.Record, spans[i])
+		p.metrics.recordProcessed(contexts[i], envelope.Record.Topic, outcome, duration)
+	}
+	return nil
+}
+
+// executeWithRetry calls the handler and retries the messages that failed with a
+// retriable error, with exponential backoff. It returns the final result of every message.
+func (p *BatchProcessor) executeWithRetry(ctx context.Context, batch []*MessageEnvelope) []error {
+	expBackoff := backoff.NewExponentialBackOff(
+		backoff.WithInitialInterval(p.initialBackoff),
+		backoff.WithMaxInterval(p.maxBackoff),
+		backoff.WithMaxElapsedTime(0), // No time limit, use max retries instead
+	)
+
+	results := make([]error, len(batch))
+	pending := batch
+	indexes := make([]int, len(batch))
+	for i := range batch {
+		indexes[i] = i
+	}
+
+	for attempt := uint64(1); ; attempt++ {
+		attemptResults := p.process(ctx, pending)
+
+		var retryPending []*MessageEnvelope
+		var retryIndexes []int
+		for i, err := range attemptResults {
+			err = classifyError(p.classifier, err)
+			results[indexes[i]] = err
+			// Skip, permanent, pause and crash errors should not be retried
+			if err != nil && !isControlError(err) {
+				retryPending = append(retryPending, pending[i])
+				retryIndexes = append(retryIndexes, indexes[i])
+			}
+		}
+		if len(retryPending) == 0 {
+			return results
+		}
+
+		logFields := []zap.Field{
+			zap.Uint64("attempt", attempt),
+			zap.Uint64("maxAttempts", p.maxRetries+1),
+			zap.Int("failed", len(retryPending)),
+			zap.Int("batchSize", len(batch)),
+		}
+		if attempt > p.maxRetries {
+			p.log.Error("failed to process messages of batch", logFields...)
+			return results
+		}
+		p.log.Debug("failed to process messages of batch, retrying", logFields...)
+		for _, envelope := range retryPending {
+			p.metrics.recordRetry(ctx, envelope.Record.Topic)
+		}
+
+		timer := time.NewTimer(expBackoff.NextBackOff())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return results
+		case <-timer.C:
+		}
+		pending, indexes = retryPending, retryIndexes
+	}
+}
+
+// process executes the handler with panic recovery and returns one result per message.
+func (p *BatchProcessor) process(ctx context.Context, batch []*MessageEnvelope) (results []error) {
+	// Apply processing timeout to prevent hanging
+	ctx, cancel := context.WithTimeout(ctx, p.processingTimeout)
+	defer cancel()
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			stack := debug.Stack()
+			p.log.Error("panic while processing kafka message batch",
+				zap.Any("panic", rec),
+				zap.String("stack", string(stack)))
+			//nolint:errorlint // panicError is intentionally not wrapped, only ErrPermanent is
+			results = batchError(len(batch), fmt.Errorf("%w: %v", ErrPermanent, &panicError{
+				Panic: rec,
+				Stack: stack,
+			}))
+		}
+	}()
+
+	results = p.handler.ProcessBatch(ctx, batch)
+	if results == nil {
+		return make([]error, len(batch))
+	}
+	if len(results) != len(batch) {
+		return batchError(len(batch), fmt.Errorf("%w: batch handler returned %d results for %d messages",
+			ErrPermanent, len(results), len(batch)))
+	}
+	return results
+}
+
+// batchRecords returns the records of batch.
+func batchRecords(batch []*MessageEnvelope) []*kgo.Record {
+	records := make([]*kgo.Record, len(batch))
+	for i, envelope := range batch {
+		records[i] = envelope.Record
+	}
+	return records
+}
+
+// batchError returns n copies of err.
+func batchError(n int, err error) []error {
+	results := make([]error, n)
+	for i := range results {
+		results[i] = err
+	}
+	return results
+}
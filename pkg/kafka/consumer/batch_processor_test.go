@@ -0,0 +1,248 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// mockBatchHandler is a test implementation of BatchHandler
+type mockBatchHandler struct {
+	processFunc func(ctx context.Context, batch []*MessageEnvelope) []error
+
+	mu      sync.Mutex
+	batches [][]*MessageEnvelope
+}
+
+func (m *mockBatchHandler) ProcessBatch(ctx context.Context, batch []*MessageEnvelope) []error {
+	m.mu.Lock()
+	m.batches = append(m.batches, batch)
+	m.mu.Unlock()
+	if m.processFunc != nil {
+		return m.processFunc(ctx, batch)
+	}
+	return nil
+}
+
+func (m *mockBatchHandler) batchSizes() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sizes := make([]int, 0, len(m.batches))
+	for _, b := range m.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func newTestEnvelope(partition int32, offset int64) *MessageEnvelope {
+	return &MessageEnvelope{
+		Event:  &emptypb.Empty{},
+		Record: &kgo.Record{Topic: "test-topic", Partition: partition, Offset: offset},
+	}
+}
+
+func newTestBatchProcessor(handler BatchHandler, envelopeChan chan *MessageEnvelope, batchSize int, linger time.Duration) (*BatchProcessor, *mockOffsetMarker, *mockDLQHandler) {
+	log := zap.NewNop()
+	marker := &mockOffsetMarker{}
+	dlqHandler := &mockDLQHandler{}
+	resultHandler := &ResultHandler{log: log, dlqHandler: dlqHandler, offsetMarker: marker}
+
+	conf := createTestConsumerConfig()
+	conf.BatchSize = batchSize
+	conf.BatchLinger = linger
+
+	return NewBatchProcessor(envelopeChan, handler, log, resultHandler, newMockTracer(), nil, nil, conf), marker, dlqHandler
+}
+
+func TestBatchProcessor_Run(t *testing.T) {
+	t.Run("flushes full batches per partition", func(t *testing.T) {
+		handler := &mockBatchHandler{}
+		envelopeChan := make(chan *MessageEnvelope, 10)
+		p, _, _ := newTestBatchProcessor(handler, envelopeChan, 2, time.Hour)
+
+		envelopeChan <- newTestEnvelope(0, 1)
+		envelopeChan <- newTestEnvelope(1, 1)
+		envelopeChan <- newTestEnvelope(0, 2)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			_ = p.Run(ctx)
+			close(done)
+		}()
+
+		require.Eventually(t, func() bool { return len(handler.batchSizes()) == 1 }, time.Second, 5*time.Millisecond)
+		cancel()
+		<-done
+
+		handler.mu.Lock()
+		defer handler.mu.Unlock()
+		assert.Equal(t, int64(1), handler.batches[0][0].Record.Offset)
+		assert.Equal(t, int64(2), handler.batches[0][1].Record.Offset)
+		assert.Equal(t, int32(0), handler.batches[0][1].Record.Partition)
+	})
+
+	t.Run("flushes partial batch after linger", func(t *testing.T) {
+		handler := &mockBatchHandler{}
+		envelopeChan := make(chan *MessageEnvelope, 10)
+		p, marker, _ := newTestBatchProcessor(handler, envelopeChan, 100, 20*time.Millisecond)
+
+		envelopeChan <- newTestEnvelope(0, 1)
+		envelopeChan <- newTestEnvelope(0, 2)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			_ = p.Run(ctx)
+			close(done)
+		}()
+
+		require.Eventually(t, func() bool { return len(handler.batchSizes()) == 1 }, time.Second, 5*time.Millisecond)
+		cancel()
+		<-done
+
+		assert.Equal(t, []int{2}, handler.batchSizes())
+		assert.Len(t, marker.markedRecords, 2)
+	})
+}
+
+func TestBatchProcessor_ProcessBatch(t *testing.T) {
+	t.Run("commits successes and sends permanent failures to DLQ", func(t *testing.T) {
+		handler := &mockBatchHandler{
+			processFunc: func(ctx context.Context, batch []*MessageEnvelope) []error {
+				return []error{nil, ErrPermanent, ErrSkipMessage}
+			},
+		}
+		p, marker, dlqHandler := newTestBatchProcessor(handler, nil, 10, time.Second)
+
+		require.NoError(t, p.processBatch(context.Background(), []*MessageEnvelope{
+			newTestEnvelope(0, 1), newTestEnvelope(0, 2), newTestEnvelope(0, 3),
+		}))
+
+		assert.Len(t, marker.markedRecords, 3)
+		assert.Equal(t, int32(1), dlqHandler.callCount.Load())
+	})
+
+	t.Run("retries only failed messages", func(t *testing.T) {
+		var calls int
+		handler := &mockBatchHandler{
+			processFunc: func(ctx context.Context, batch []*MessageEnvelope) []error {
+				calls++
+				if calls == 1 {
+					return []error{nil, errors.New("temporary"), nil}
+				}
+				return nil
+			},
+		}
+		p, _, dlqHandler := newTestBatchProcessor(handler, nil, 10, time.Second)
+
+		require.NoError(t, p.processBatch(context.Background(), []*MessageEnvelope{
+			newTestEnvelope(0, 1), newTestEnvelope(0, 2), newTestEnvelope(0, 3),
+		}))
+
+		assert.Equal(t, []int{3, 1}, handler.batchSizes())
+		assert.Equal(t, int64(2), handler.batches[1][0].Record.Offset)
+		assert.Zero(t, dlqHandler.callCount.Load())
+	})
+
+	t.Run("sends message to DLQ after max retries", func(t *testing.T) {
+		handler := &mockBatchHandler{
+			processFunc: func(ctx context.Context, batch []*MessageEnvelope) []error {
+				return batchError(len(batch), errors.New("persistent"))
+			},
+		}
+		p, marker, dlqHandler := newTestBatchProcessor(handler, nil, 10, time.Second)
+
+		require.NoError(t, p.processBatch(context.Background(), []*MessageEnvelope{newTestEnvelope(0, 1)}))
+
+		assert.Equal(t, []int{1, 1, 1}, handler.batchSizes()) // 1 attempt + 2 retries
+		assert.Equal(t, int32(1), dlqHandler.callCount.Load())
+		assert.Len(t, marker.markedRecords, 1)
+	})
+
+	t.Run("crashes without marking the batch", func(t *testing.T) {
+		handler := &mockBatchHandler{
+			processFunc: func(ctx context.Context, batch []*MessageEnvelope) []error {
+				return []error{nil, ErrCrashConsumer}
+			},
+		}
+		p, marker, dlqHandler := newTestBatchProcessor(handler, nil, 10, time.Second)
+
+		err := p.processBatch(context.Background(), []*MessageEnvelope{newTestEnvelope(0, 1), newTestEnvelope(0, 2)})
+
+		assert.ErrorIs(t, err, ErrCrashConsumer)
+		assert.Equal(t, []int{2}, handler.batchSizes()) // Not retried
+		assert.Empty(t, marker.markedRecords)
+		assert.Zero(t, dlqHandler.callCount.Load())
+	})
+
+	t.Run("pauses the consumer and reprocesses paused messages once resumed", func(t *testing.T) {
+		var calls int
+		handler := &mockBatchHandler{
+			processFunc: func(ctx context.Context, batch []*MessageEnvelope) []error {
+				calls++
+				if calls == 1 {
+					return []error{nil, ErrPauseConsumer}
+				}
+				return nil
+			},
+		}
+		p, marker, dlqHandler := newTestBatchProcessor(handler, nil, 10, time.Second)
+		controller := newTestController(t, newTestClient(t, "test-topic"), newMockStateReporter())
+		p.controller = controller
+
+		done := make(chan error, 1)
+		go func() {
+			done <- p.processBatch(context.Background(), []*MessageEnvelope{newTestEnvelope(0, 1), newTestEnvelope(0, 2)})
+		}()
+
+		require.Eventually(t, func() bool { return controller.Status().State == StatePaused }, time.Second, 5*time.Millisecond)
+		assert.Empty(t, marker.markedRecords)
+
+		controller.Resume()
+		require.NoError(t, <-done)
+		assert.Equal(t, []int{2, 1}, handler.batchSizes())
+		assert.Equal(t, int64(2), handler.batches[1][0].Record.Offset)
+		assert.Len(t, marker.markedRecords, 2)
+		assert.Zero(t, dlqHandler.callCount.Load())
+	})
+
+	t.Run("panic fails the whole batch permanently", func(t *testing.T) {
+		handler := &mockBatchHandler{
+			processFunc: func(ctx context.Context, batch []*MessageEnvelope) []error {
+				panic("boom")
+			},
+		}
+		p, _, _ := newTestBatchProcessor(handler, nil, 10, time.Second)
+
+		results := p.executeWithRetry(context.Background(), []*MessageEnvelope{newTestEnvelope(0, 1), newTestEnvelope(0, 2)})
+
+		require.Len(t, results, 2)
+		for _, err := range results {
+			assert.ErrorIs(t, err, ErrPermanent)
+		}
+		assert.Len(t, handler.batchSizes(), 1)
+	})
+
+	t.Run("result count mismatch is a permanent error", func(t *testing.T) {
+		handler := &mockBatchHandler{
+			processFunc: func(ctx context.Context, batch []*MessageEnvelope) []error {
+				return []error{nil}
+			},
+		}
+		p, _, _ := newTestBatchProcessor(handler, nil, 10, time.Second)
+
+		results := p.executeWithRetry(context.Background(), []*MessageEnvelope{newTestEnvelope(0, 1), newTestEnvelope(0, 2)})
+
+		require.Len(t, results, 2)
+		assert.ErrorIs(t, results[1], ErrPermanent)
+	})
+}
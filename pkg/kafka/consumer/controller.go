@@ -0,0 +1,201 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/core/health"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// Consumer states reported by Controller.
+const (
+	StateRunning = "running"
+	StatePaused  = "paused"
+)
+
+// ErrInvalidRateLimit is returned by Controller.SetRateLimit for a negative rate or burst.
+var ErrInvalidRateLimit = errors.New("rate limit and burst cannot be negative")
+
+// errParkedDropped is returned by Controller.waitRunning when an offset reset dropped the waiting messages.
+var errParkedDropped = errors.New("parked messages dropped by an offset reset")
+
+// ControllerStatus is the runtime state of a consumer.
+type ControllerStatus struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	PausedAt  time.Time `json:"paused_at,omitzero"`
+	RateLimit float64   `json:"rate_limit"` // Messages per second, zero when unlimited
+	RateBurst int       `json:"rate_burst"`
+}
+
+// Controller pauses, resumes and rate limits a consumer at runtime.
+//
+// Pausing stops fetching from the consumer topics, including the retry tiers, while the client
+// stays in the consumer group and keeps its partitions. Messages fetched before the pause are
+// still processed. With a topic pattern, topics matched after the pause are fetched.
+// A paused consumer is reported with the "paused" state of its readiness component; the
+// service stays ready.
+type Controller struct {
+	name      string
+	component string
+	states    health.ComponentStateReporter
+	limiter   *rate.Limiter
+	log       *zap.Logger
+
+	mu       sync.Mutex
+	clients  []*kgo.Client
+	pausedAt time.Time
+	resumed  chan struct{} // Closed on Resume, nil while running
+}
+
+// NewController creates a Controller for the consumer of client, rate limited by the
+// rate-limit and rate-burst consumer settings.
+func NewController(
+	client *kgo.Client,
+	states health.ComponentStateReporter,
+	consumerConf config.ConsumerConfig,
+	log *zap.Logger,
+) *Controller {
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if consumerConf.RateLimit > 0 {
+		limiter = rate.NewLimiter(rate.Limit(consumerConf.RateLimit), max(consumerConf.RateBurst, 1))
+	}
+	return &Controller{
+		name:      consumerConf.Name,
+		component: "kafka-consumer-" + consumerConf.Name,
+		states:    states,
+		limiter:   limiter,
+		log:       log,
+		clients:   []*kgo.Client{client},
+	}
+}
+
+// AddClient puts a further client of the consumer, such as a retry tier, under the Controller.
+func (c *Controller) AddClient(client *kgo.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clients = append(c.clients, client)
+	if !c.pausedAt.IsZero() {
+		client.PauseFetchTopics(client.GetConsumeTopics()...)
+	}
+}
+
+// Pause stops fetching new messages. Pausing a paused consumer has no effect.
+func (c *Controller) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.pausedAt.IsZero() {
+		return
+	}
+	for _, client := range c.clients {
+		client.PauseFetchTopics(client.GetConsumeTopics()...)
+	}
+	c.pausedAt = time.Now()
+	c.resumed = make(chan struct{})
+	c.states.SetComponentState(c.component, StatePaused)
+	c.log.Warn("consumer paused")
+}
+
+// Resume continues fetching after Pause. Resuming a running consumer has no effect.
+func (c *Controller) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pausedAt.IsZero() {
+		return
+	}
+	for _, client := range c.clients {
+		client.ResumeFetchTopics(client.PauseFetchTopics()...)
+	}
+	c.log.Info("consumer resumed", zap.Duration("paused_for", time.Since(c.pausedAt)))
+	c.pausedAt = time.Time{}
+	close(c.resumed)
+	c.resumed = nil
+	c.states.SetComponentState(c.component, "")
+}
+
+// SetRateLimit limits processing to perSecond messages with bursts of up to burst messages.
+// A zero perSecond removes the limit; a zero burst defaults to the rate rounded up.
+func (c *Controller) SetRateLimit(perSecond float64, burst int) error {
+	if perSecond < 0 || burst < 0 || math.IsNaN(perSecond) || math.IsInf(perSecond, 0) {
+		return fmt.Errorf("%w, got: %v and %d", ErrInvalidRateLimit, perSecond, burst)
+	}
+
+	if perSecond == 0 {
+		c.limiter.SetLimit(rate.Inf)
+		c.limiter.SetBurst(0)
+		c.log.Info("consumer rate limit removed")
+		return nil
+	}
+	if burst == 0 {
+		burst = config.DefaultRateBurst(perSecond)
+	}
+	c.limiter.SetLimit(rate.Limit(perSecond))
+	c.limiter.SetBurst(burst)
+	c.log.Info("consumer rate limit changed", zap.Float64("rate_limit", perSecond), zap.Int("rate_burst", burst))
+	return nil
+}
+
+// Status returns the current state of the consumer.
+func (c *Controller) Status() ControllerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := ControllerStatus{
+		Name:     c.name,
+		State:    StateRunning,
+		PausedAt: c.pausedAt,
+	}
+	if !c.pausedAt.IsZero() {
+		status.State = StatePaused
+	}
+	if limit := c.limiter.Limit(); limit != rate.Inf {
+		status.RateLimit = float64(limit)
+		status.RateBurst = c.limiter.Burst()
+	}
+	return status
+}
+
+// acquire waits until the rate limit admits the next message. It returns false if ctx is done first.
+// A nil Controller admits every message.
+func (c *Controller) acquire(ctx context.Context) bool {
+	if c == nil {
+		return true
+	}
+	return c.limiter.Wait(ctx) == nil
+}
+
+// waitRunning blocks while the consumer is paused. It returns errParkedDropped if dropped is
+// closed first, or closed by the time the consumer is resumed, and the error of ctx if it is done first.
+func (c *Controller) waitRunning(ctx context.Context, dropped <-chan struct{}) error {
+	c.mu.Lock()
+	resumed := c.resumed
+	c.mu.Unlock()
+
+	if resumed == nil {
+		return nil
+	}
+	select {
+	case <-dropped:
+		return errParkedDropped
+	case <-resumed:
+		select {
+		case <-dropped:
+			return errParkedDropped
+		default:
+			return nil
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
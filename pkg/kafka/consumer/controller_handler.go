@@ -0,0 +1,123 @@
+package consumer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ControllerHandler exposes a Controller over HTTP for on-call operations.
+// Every endpoint responds with the ControllerStatus of the consumer, except the offset reset
+// which responds with the OffsetResetResult.
+type ControllerHandler struct {
+	controller *Controller
+	resetter   *OffsetResetter
+	logger     *zap.Logger
+}
+
+func NewControllerHandler(controller *Controller, resetter *OffsetResetter, logger *zap.Logger) *ControllerHandler {
+	return &ControllerHandler{
+		controller: controller,
+		resetter:   resetter,
+		logger:     logger,
+	}
+}
+
+// rateLimitRequest is the JSON body of the rate limit endpoint.
+type rateLimitRequest struct {
+	RateLimit float64 `json:"rate_limit"`
+	RateBurst int     `json:"rate_burst"`
+}
+
+// offsetResetRequest is the JSON body of the offset reset endpoint.
+type offsetResetRequest struct {
+	To        string                     `json:"to"`
+	Timestamp time.Time                  `json:"timestamp"`
+	Offsets   map[string]map[int32]int64 `json:"offsets"`
+	Topics    []string                   `json:"topics"`
+	DryRun    bool                       `json:"dry_run"`
+}
+
+// Status handles GET {prefix}/{name}.
+func (h *ControllerHandler) Status(w http.ResponseWriter, _ *http.Request) {
+	h.writeJSON(w, http.StatusOK, h.controller.Status())
+}
+
+// Pause handles POST {prefix}/{name}/pause.
+func (h *ControllerHandler) Pause(w http.ResponseWriter, _ *http.Request) {
+	h.controller.Pause()
+	h.logger.Info("consumer paused via admin API")
+	h.writeJSON(w, http.StatusOK, h.controller.Status())
+}
+
+// Resume handles POST {prefix}/{name}/resume.
+func (h *ControllerHandler) Resume(w http.ResponseWriter, _ *http.Request) {
+	h.controller.Resume()
+	h.logger.Info("consumer resumed via admin API")
+	h.writeJSON(w, http.StatusOK, h.controller.Status())
+}
+
+// SetRateLimit handles PUT {prefix}/{name}/rate-limit.
+// The body sets rate_limit in messages per second, zero removes the limit, and optionally rate_burst.
+func (h *ControllerHandler) SetRateLimit(w http.ResponseWriter, r *http.Request) {
+	var body rateLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.writeError(w, http.StatusBadRequest, errors.New("request body must be a JSON rate limit request"))
+		return
+	}
+	if err := h.controller.SetRateLimit(body.RateLimit, body.RateBurst); err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, h.controller.Status())
+}
+
+// ResetOffsets handles POST {prefix}/{name}/offsets/reset.
+// The body selects the target: {"to": "earliest"}, {"to": "latest"},
+// {"to": "timestamp", "timestamp": "2024-01-02T15:04:05Z"} or {"to": "offsets", "offsets": {"orders": {"0": 42}}},
+// optionally limited to "topics" and previewed with "dry_run".
+func (h *ControllerHandler) ResetOffsets(w http.ResponseWriter, r *http.Request) {
+	var body offsetResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.writeError(w, http.StatusBadRequest, errors.New("request body must be a JSON offset reset request"))
+		return
+	}
+
+	result, err := h.resetter.Reset(r.Context(), OffsetResetRequest(body))
+	switch {
+	case errors.Is(err, ErrInvalidOffsetReset):
+		h.writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, ErrNotPaused), errors.Is(err, ErrGroupActive), errors.Is(err, ErrRecordsInFlight):
+		h.writeError(w, http.StatusConflict, err)
+	case err != nil:
+		h.logger.Error("failed to reset consumer offsets", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, err)
+	default:
+		h.logger.Info("consumer offsets reset via admin API", zap.String("to", body.To), zap.Bool("dry_run", body.DryRun))
+		h.writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func (h *ControllerHandler) writeError(w http.ResponseWriter, status int, err error) {
+	h.writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (h *ControllerHandler) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body) //nolint:errcheck // HTTP handler, error logged by net/http
+}
+
+// RegisterControllerRoutes registers the admin endpoints of one consumer under {prefix}/{name}
+// (e.g. "/admin/consumers/orders").
+func RegisterControllerRoutes(mux *http.ServeMux, prefix string, h *ControllerHandler) {
+	base := prefix + "/" + h.controller.name
+	mux.HandleFunc("GET "+base, h.Status)
+	mux.HandleFunc("POST "+base+"/pause", h.Pause)
+	mux.HandleFunc("POST "+base+"/resume", h.Resume)
+	mux.HandleFunc("PUT "+base+"/rate-limit", h.SetRateLimit)
+	mux.HandleFunc("POST "+base+"/offsets/reset", h.ResetOffsets)
+}
@@ -0,0 +1,212 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+// mockStateReporter records component states.
+type mockStateReporter struct {
+	mu     sync.Mutex
+	states map[string]string
+}
+
+func newMockStateReporter() *mockStateReporter {
+	return &mockStateReporter{states: make(map[string]string)}
+}
+
+func (m *mockStateReporter) SetComponentState(name, state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[name] = state
+}
+
+func (m *mockStateReporter) state(name string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.states[name]
+}
+
+// newTestClient creates a client that is never used to connect; pausing is tracked locally.
+func newTestClient(t *testing.T, topics ...string) *kgo.Client {
+	t.Helper()
+
+	client, err := kgo.NewClient(kgo.SeedBrokers("127.0.0.1:1"), kgo.ConsumeTopics(topics...))
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func newTestController(t *testing.T, client *kgo.Client, states *mockStateReporter) *Controller {
+	t.Helper()
+
+	conf := createTestConsumerConfig()
+	return NewController(client, states, conf, zap.NewNop())
+}
+
+func TestController_PauseResume(t *testing.T) {
+	client := newTestClient(t, "orders")
+	states := newMockStateReporter()
+	c := newTestController(t, client, states)
+
+	assert.Equal(t, StateRunning, c.Status().State)
+
+	c.Pause()
+	status := c.Status()
+	assert.Equal(t, StatePaused, status.State)
+	assert.False(t, status.PausedAt.IsZero())
+	assert.Equal(t, []string{"orders"}, client.PauseFetchTopics())
+	assert.Equal(t, StatePaused, states.state("kafka-consumer-test-consumer"))
+
+	t.Run("pausing twice keeps the first pause time", func(t *testing.T) {
+		c.Pause()
+		assert.Equal(t, status.PausedAt, c.Status().PausedAt)
+	})
+
+	t.Run("pauses clients added while paused", func(t *testing.T) {
+		tier := newTestClient(t, "orders.retry.1m")
+		c.AddClient(tier)
+		assert.Equal(t, []string{"orders.retry.1m"}, tier.PauseFetchTopics())
+	})
+
+	c.Resume()
+	assert.Equal(t, StateRunning, c.Status().State)
+	assert.True(t, c.Status().PausedAt.IsZero())
+	assert.Empty(t, client.PauseFetchTopics())
+	assert.Empty(t, states.state("kafka-consumer-test-consumer"))
+}
+
+func TestController_RateLimit(t *testing.T) {
+	t.Run("unlimited by default", func(t *testing.T) {
+		c := newTestController(t, newTestClient(t, "orders"), newMockStateReporter())
+
+		status := c.Status()
+		assert.Zero(t, status.RateLimit)
+		assert.Zero(t, status.RateBurst)
+		for range 100 {
+			assert.True(t, c.acquire(context.Background()))
+		}
+	})
+
+	t.Run("applies configured rate", func(t *testing.T) {
+		conf := createTestConsumerConfig()
+		conf.RateLimit = 5
+		conf.RateBurst = 2
+		c := NewController(newTestClient(t, "orders"), newMockStateReporter(), conf, zap.NewNop())
+
+		status := c.Status()
+		assert.Equal(t, 5.0, status.RateLimit)
+		assert.Equal(t, 2, status.RateBurst)
+	})
+
+	t.Run("changes rate at runtime", func(t *testing.T) {
+		c := newTestController(t, newTestClient(t, "orders"), newMockStateReporter())
+
+		require.NoError(t, c.SetRateLimit(2.5, 0))
+		status := c.Status()
+		assert.Equal(t, 2.5, status.RateLimit)
+		assert.Equal(t, 3, status.RateBurst, "burst defaults to the rate rounded up")
+
+		require.NoError(t, c.SetRateLimit(0, 0))
+		assert.Zero(t, c.Status().RateLimit)
+	})
+
+	t.Run("rejects negative values", func(t *testing.T) {
+		c := newTestController(t, newTestClient(t, "orders"), newMockStateReporter())
+
+		assert.ErrorIs(t, c.SetRateLimit(-1, 0), ErrInvalidRateLimit)
+		assert.ErrorIs(t, c.SetRateLimit(1, -1), ErrInvalidRateLimit)
+	})
+
+	t.Run("stops waiting when context is cancelled", func(t *testing.T) {
+		c := newTestController(t, newTestClient(t, "orders"), newMockStateReporter())
+		require.NoError(t, c.SetRateLimit(0.001, 1))
+		require.True(t, c.acquire(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.False(t, c.acquire(ctx))
+	})
+
+	t.Run("nil controller admits every message", func(t *testing.T) {
+		var c *Controller
+		assert.True(t, c.acquire(context.Background()))
+	})
+}
+
+func TestControllerHandler(t *testing.T) {
+	client := newTestClient(t, "orders")
+	c := newTestController(t, client, newMockStateReporter())
+	mux := http.NewServeMux()
+	resetter := NewOffsetResetter(client, c, NewInFlightCounter(), createTestConsumerConfig(), zap.NewNop())
+	RegisterControllerRoutes(mux, "/admin/consumers", NewControllerHandler(c, resetter, zap.NewNop()))
+
+	serve := func(method, path, body string) (*httptest.ResponseRecorder, ControllerStatus) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		var status ControllerStatus
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+		}
+		return rec, status
+	}
+
+	t.Run("returns status", func(t *testing.T) {
+		rec, status := serve(http.MethodGet, "/admin/consumers/test-consumer", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "test-consumer", status.Name)
+		assert.Equal(t, StateRunning, status.State)
+	})
+
+	t.Run("pauses and resumes", func(t *testing.T) {
+		rec, status := serve(http.MethodPost, "/admin/consumers/test-consumer/pause", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, StatePaused, status.State)
+
+		rec, status = serve(http.MethodPost, "/admin/consumers/test-consumer/resume", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, StateRunning, status.State)
+	})
+
+	t.Run("sets rate limit", func(t *testing.T) {
+		rec, status := serve(http.MethodPut, "/admin/consumers/test-consumer/rate-limit", `{"rate_limit": 10, "rate_burst": 20}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 10.0, status.RateLimit)
+		assert.Equal(t, 20, status.RateBurst)
+	})
+
+	t.Run("rejects invalid rate limit", func(t *testing.T) {
+		rec, _ := serve(http.MethodPut, "/admin/consumers/test-consumer/rate-limit", `{"rate_limit": -1}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec, _ = serve(http.MethodPut, "/admin/consumers/test-consumer/rate-limit", `not json`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("rejects offset reset of a running consumer", func(t *testing.T) {
+		rec, _ := serve(http.MethodPost, "/admin/consumers/test-consumer/offsets/reset", `{"to": "earliest"}`)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), ErrNotPaused.Error())
+
+		rec, _ = serve(http.MethodPost, "/admin/consumers/test-consumer/offsets/reset", `not json`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("returns 404 for other consumers", func(t *testing.T) {
+		rec, _ := serve(http.MethodPost, "/admin/consumers/other/pause", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -24,8 +24,12 @@ type MessageDeserializer struct {
 	log          *zap.Logger
 	tracer       MessageTracer
 	dlqHandler   DLQHandler
+	metrics      *Metrics
+	inFlight     *InFlightCounter
 }
 
+// NewMessageDeserializer creates a MessageDeserializer. Records sent to the DLQ are finished in
+// inFlight, which may be nil.
 func NewMessageDeserializer(
 	inputChan chan *kgo.Record,
 	outputChan chan *MessageEnvelope,
@@ -33,6 +37,8 @@ func NewMessageDeserializer(
 	log *zap.Logger,
 	tracer MessageTracer,
 	dlqHandler DLQHandler,
+	metrics *Metrics,
+	inFlight *InFlightCounter,
 ) *MessageDeserializer {
 	return &MessageDeserializer{
 		inputChan:    inputChan,
@@ -41,6 +47,8 @@ func NewMessageDeserializer(
 		log:          log,
 		tracer:       tracer,
 		dlqHandler:   dlqHandler,
+		metrics:      metrics,
+		inFlight:     inFlight,
 	}
 }
 
@@ -72,6 +80,8 @@ func (d *MessageDeserializer) deserializeAndSend(ctx context.Context, record *kg
 
 		tracedCtx := d.tracer.ExtractContext(ctx, record)
 		d.dlqHandler.SendToDLQ(tracedCtx, record, fmt.Errorf("deserialization failed: %w", err))
+		d.metrics.recordOutcome(tracedCtx, record.Topic, OutcomeInvalid)
+		d.inFlight.done()
 		return
 	}
 
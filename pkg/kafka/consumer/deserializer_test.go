@@ -48,7 +48,7 @@ func TestNewMessageDeserializer(t *testing.T) {
 		tracer := newMockTracer()
 		dlqHandler := &mockDLQHandler{}
 
-		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler)
+		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler, nil, nil)
 
 		assert.NotNil(t, d)
 	})
@@ -68,7 +68,7 @@ func TestMessageDeserializer_Run(t *testing.T) {
 		tracer := newMockTracer()
 		dlqHandler := &mockDLQHandler{}
 
-		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler)
+		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler, nil, nil)
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -109,7 +109,7 @@ func TestMessageDeserializer_Run(t *testing.T) {
 			},
 		}
 
-		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler)
+		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler, nil, nil)
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -147,7 +147,7 @@ func TestMessageDeserializer_Run(t *testing.T) {
 		tracer := newMockTracer()
 		dlqHandler := &mockDLQHandler{}
 
-		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler)
+		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler, nil, nil)
 
 		ctx, cancel := context.WithCancel(context.Background())
 
@@ -178,7 +178,7 @@ func TestMessageDeserializer_Run(t *testing.T) {
 		tracer := newMockTracer()
 		dlqHandler := &mockDLQHandler{}
 
-		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler)
+		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler, nil, nil)
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -226,7 +226,7 @@ func TestMessageDeserializer_DeserializeAndSend(t *testing.T) {
 
 		dlqHandler := &mockDLQHandler{}
 
-		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler)
+		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler, nil, nil)
 
 		d.deserializeAndSend(context.Background(), createTestMessage())
 
@@ -245,7 +245,7 @@ func TestMessageDeserializer_DeserializeAndSend(t *testing.T) {
 		tracer := newMockTracer()
 		dlqHandler := &mockDLQHandler{}
 
-		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler)
+		d := NewMessageDeserializer(inputChan, outputChan, deserializer, log, tracer, dlqHandler, nil, nil)
 
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel() // Cancel before sending
@@ -11,6 +11,15 @@ import (
 	"go.uber.org/zap"
 )
 
+// Headers written on messages moved to the DLQ.
+const (
+	HeaderDLQOriginalTopic     = "dlq.original.topic"
+	HeaderDLQOriginalPartition = "dlq.original.partition"
+	HeaderDLQOriginalOffset    = "dlq.original.offset"
+	HeaderDLQError             = "dlq.error"
+	HeaderDLQTimestamp         = "dlq.timestamp"
+)
+
 // DLQHandler відповідає за відправку повідомлень, які не вдалось обробити, в Dead Letter Queue.
 type DLQHandler interface {
 	// SendToDLQ відправляє повідомлення в DLQ з інформацією про помилку
@@ -24,6 +33,8 @@ type DlqHandler struct {
 	log      *zap.Logger
 }
 
+// NewDLQHandler creates a DLQHandler that sends failed messages to dlqTopic. An empty dlqTopic
+// sends each message to the DLQ of the topic it was first consumed from, {source}.dlq.
 func NewDLQHandler(
 	producer producer.Producer,
 	dlqTopic string,
@@ -40,22 +51,23 @@ func NewDLQHandler(
 
 func (h *DlqHandler) SendToDLQ(ctx context.Context, record *kgo.Record, processingErr error) {
 	// Створюємо span для операції відправки в DLQ
-	ctx, span := h.tracer.StartDLQSpan(ctx, record, h.dlqTopic)
+	dlqTopic := h.topicFor(record)
+	ctx, span := h.tracer.StartDLQSpan(ctx, record, dlqTopic)
 	defer span.End()
 
 	// Створюємо DLQ повідомлення з оригінальними даними
 	dlqHeaders := make([]kgo.RecordHeader, len(record.Headers), len(record.Headers)+5)
 	copy(dlqHeaders, record.Headers)
 	dlqHeaders = append(dlqHeaders,
-		kgo.RecordHeader{Key: "dlq.original.topic", Value: []byte(record.Topic)},
-		kgo.RecordHeader{Key: "dlq.original.partition", Value: []byte(fmt.Sprintf("%d", record.Partition))},
-		kgo.RecordHeader{Key: "dlq.original.offset", Value: []byte(fmt.Sprintf("%d", record.Offset))},
-		kgo.RecordHeader{Key: "dlq.error", Value: []byte(processingErr.Error())},
-		kgo.RecordHeader{Key: "dlq.timestamp", Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+		kgo.RecordHeader{Key: HeaderDLQOriginalTopic, Value: []byte(record.Topic)},
+		kgo.RecordHeader{Key: HeaderDLQOriginalPartition, Value: []byte(fmt.Sprintf("%d", record.Partition))},
+		kgo.RecordHeader{Key: HeaderDLQOriginalOffset, Value: []byte(fmt.Sprintf("%d", record.Offset))},
+		kgo.RecordHeader{Key: HeaderDLQError, Value: []byte(processingErr.Error())},
+		kgo.RecordHeader{Key: HeaderDLQTimestamp, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
 	)
 
 	dlqRecord := &kgo.Record{
-		Topic:   h.dlqTopic,
+		Topic:   dlqTopic,
 		Key:     record.Key,
 		Value:   record.Value,
 		Headers: dlqHeaders,
@@ -74,19 +86,31 @@ func (h *DlqHandler) SendToDLQ(ctx context.Context, record *kgo.Record, processi
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "failed to deliver message to DLQ")
 		h.log.Error("failed to deliver message to DLQ",
-			zap.String("dlq_topic", h.dlqTopic),
+			zap.String("dlq_topic", dlqTopic),
 			zap.String("key", string(record.Key)),
 			zap.Error(err))
 	} else {
 		span.SetStatus(codes.Ok, "message sent to DLQ")
 		h.log.Info("message sent to DLQ",
-			zap.String("dlq_topic", h.dlqTopic),
+			zap.String("dlq_topic", dlqTopic),
 			zap.String("key", string(record.Key)),
 			zap.Int32("original_partition", record.Partition),
 			zap.Int64("original_offset", record.Offset))
 	}
 }
 
+// topicFor returns the DLQ topic of a record. Messages from a retry tier go to the DLQ of their source topic.
+func (h *DlqHandler) topicFor(record *kgo.Record) string {
+	if h.dlqTopic != "" {
+		return h.dlqTopic
+	}
+	source := headerValue(record, HeaderRetryOriginalTopic)
+	if source == "" {
+		source = record.Topic
+	}
+	return source + ".dlq"
+}
+
 func NewNoopDLQHandler(log *zap.Logger) DLQHandler {
 	return &noopDLQHandler{
 		log: log,
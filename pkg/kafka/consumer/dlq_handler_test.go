@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
 	"go.uber.org/zap"
 )
 
@@ -30,3 +32,31 @@ func TestNoopDLQHandler_SendToDLQ(t *testing.T) {
 		})
 	})
 }
+
+func TestDlqHandler_SendToDLQ(t *testing.T) {
+	t.Run("sends to configured DLQ topic", func(t *testing.T) {
+		producer := &mockProducer{}
+		handler := NewDLQHandler(producer, "custom.dlq", newMockTracer(), zap.NewNop())
+
+		handler.SendToDLQ(context.Background(), createTestMessage(), assert.AnError)
+
+		require.Len(t, producer.records, 1)
+		assert.Equal(t, "custom.dlq", producer.records[0].Topic)
+		assert.Equal(t, "test-topic", headerValue(producer.records[0], HeaderDLQOriginalTopic))
+	})
+
+	t.Run("sends to DLQ of the source topic without configured topic", func(t *testing.T) {
+		producer := &mockProducer{}
+		handler := NewDLQHandler(producer, "", newMockTracer(), zap.NewNop())
+		fromRetryTier := createTestMessage()
+		fromRetryTier.Topic = "payments.retry.1m"
+		fromRetryTier.Headers = []kgo.RecordHeader{{Key: HeaderRetryOriginalTopic, Value: []byte("payments")}}
+
+		handler.SendToDLQ(context.Background(), createTestMessage(), assert.AnError)
+		handler.SendToDLQ(context.Background(), fromRetryTier, assert.AnError)
+
+		require.Len(t, producer.records, 2)
+		assert.Equal(t, "test-topic.dlq", producer.records[0].Topic)
+		assert.Equal(t, "payments.dlq", producer.records[1].Topic)
+	})
+}
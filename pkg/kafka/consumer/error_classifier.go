@@ -0,0 +1,167 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var (
+	// ErrPauseConsumer pauses the consumer through its Controller without committing the message.
+	// The message is processed again once the consumer is resumed.
+	// Use this when processing cannot continue until an operator intervenes, e.g. a missing
+	// reference entity or an expired credential.
+	ErrPauseConsumer = errors.New("pause consumer")
+
+	// ErrCrashConsumer stops the consumer and shuts the service down without committing the message.
+	// Use this when continuing could corrupt data, e.g. an unknown schema version.
+	ErrCrashConsumer = errors.New("crash consumer")
+)
+
+// ErrorDecision is the action taken for a handler error.
+type ErrorDecision int
+
+const (
+	// DecisionDefault leaves the error to the next classifier; unclassified errors are retried.
+	DecisionDefault ErrorDecision = iota
+	DecisionRetry
+	DecisionSkip  // Commit without retry, as ErrSkipMessage
+	DecisionDLQ   // Send to the DLQ, as ErrPermanent
+	DecisionPause // Pause the consumer, as ErrPauseConsumer
+	DecisionCrash // Shut the service down, as ErrCrashConsumer
+)
+
+// ErrorClassifier maps handler errors to an ErrorDecision, so handlers don't have to wrap
+// every error with a sentinel. Classification is opt-in per consumer: only a Handler or
+// BatchHandler implementing ErrorClassifier has its errors classified; errors of other handlers
+// are retried as before. Errors already wrapping ErrSkipMessage, ErrPermanent, ErrPauseConsumer
+// or ErrCrashConsumer are not classified.
+//
+// A Router opts in with Router.SetErrorClassifier:
+//
+//	router.SetErrorClassifier(consumer.DefaultErrorClassifier())
+//
+// Other handlers opt in by implementing ErrorClassifier, after their own rules if any:
+//
+//	func (h *orderHandler) ClassifyError(err error) consumer.ErrorDecision {
+//		return consumer.DefaultErrorClassifier().ClassifyError(err)
+//	}
+type ErrorClassifier interface {
+	ClassifyError(err error) ErrorDecision
+}
+
+// ErrorClassifierFunc adapts a function to ErrorClassifier.
+type ErrorClassifierFunc func(err error) ErrorDecision
+
+func (f ErrorClassifierFunc) ClassifyError(err error) ErrorDecision {
+	return f(err)
+}
+
+// ChainErrorClassifiers returns the first decision other than DecisionDefault of classifiers.
+func ChainErrorClassifiers(classifiers ...ErrorClassifier) ErrorClassifier {
+	return ErrorClassifierFunc(func(err error) ErrorDecision {
+		for _, c := range classifiers {
+			if decision := c.ClassifyError(err); decision != DecisionDefault {
+				return decision
+			}
+		}
+		return DecisionDefault
+	})
+}
+
+// DefaultErrorClassifier classifies common infrastructure errors. It is not applied unless a
+// handler's ClassifyError uses it, since it changes the outcome of errors that are otherwise retried:
+//   - Mongo duplicate key errors are skipped, assuming the event was already applied; handlers
+//     whose unique indexes can reject new data should not use it.
+//   - mongo.ErrOptimisticLocking and context deadlines are retried.
+//   - connect.Error codes are classified by ConnectErrorClassifier.
+//   - Any other error is left to the default, a retry.
+func DefaultErrorClassifier() ErrorClassifier {
+	return ChainErrorClassifiers(
+		ErrorClassifierFunc(classifyMongoError),
+		ErrorClassifierFunc(classifyContextError),
+		ErrorClassifierFunc(ConnectErrorClassifier),
+	)
+}
+
+func classifyMongoError(err error) ErrorDecision {
+	switch {
+	case mongodriver.IsDuplicateKeyError(err):
+		return DecisionSkip
+	case errors.Is(err, mongo.ErrOptimisticLocking):
+		return DecisionRetry
+	default:
+		return DecisionDefault
+	}
+}
+
+func classifyContextError(err error) ErrorDecision {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return DecisionRetry
+	}
+	return DecisionDefault
+}
+
+// ConnectErrorClassifier classifies errors of Connect RPC calls by code:
+//   - AlreadyExists is skipped.
+//   - InvalidArgument, NotFound, FailedPrecondition, OutOfRange, Unimplemented, PermissionDenied,
+//     Unauthenticated and DataLoss are sent to the DLQ without retry, so a NotFound of an entity
+//     that is not replicated yet is not retried.
+//   - Canceled, Unknown, DeadlineExceeded, ResourceExhausted, Aborted, Internal and Unavailable
+//     are retried.
+func ConnectErrorClassifier(err error) ErrorDecision {
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		return DecisionDefault
+	}
+	switch connectErr.Code() {
+	case connect.CodeAlreadyExists:
+		return DecisionSkip
+	case connect.CodeInvalidArgument, connect.CodeNotFound, connect.CodeFailedPrecondition,
+		connect.CodeOutOfRange, connect.CodeUnimplemented, connect.CodePermissionDenied,
+		connect.CodeUnauthenticated, connect.CodeDataLoss:
+		return DecisionDLQ
+	case connect.CodeCanceled, connect.CodeUnknown, connect.CodeDeadlineExceeded,
+		connect.CodeResourceExhausted, connect.CodeAborted, connect.CodeInternal, connect.CodeUnavailable:
+		return DecisionRetry
+	default:
+		return DecisionDefault
+	}
+}
+
+// errorClassifierFor returns the classifier of a handler, nil if the handler classifies no errors.
+func errorClassifierFor(handler any) ErrorClassifier {
+	if c, ok := handler.(ErrorClassifier); ok {
+		return c
+	}
+	return nil
+}
+
+// classifyError wraps err with the sentinel of its decision.
+func classifyError(classifier ErrorClassifier, err error) error {
+	if err == nil || classifier == nil || isControlError(err) {
+		return err
+	}
+	switch classifier.ClassifyError(err) {
+	case DecisionSkip:
+		return fmt.Errorf("%w: %w", ErrSkipMessage, err)
+	case DecisionDLQ:
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	case DecisionPause:
+		return fmt.Errorf("%w: %w", ErrPauseConsumer, err)
+	case DecisionCrash:
+		return fmt.Errorf("%w: %w", ErrCrashConsumer, err)
+	default:
+		return err
+	}
+}
+
+// isControlError reports whether err wraps a sentinel that is not retried in-process.
+func isControlError(err error) bool {
+	return errors.Is(err, ErrSkipMessage) || errors.Is(err, ErrPermanent) ||
+		errors.Is(err, ErrPauseConsumer) || errors.Is(err, ErrCrashConsumer)
+}
@@ -0,0 +1,188 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// classifyingHandler is a Handler that classifies its own errors.
+type classifyingHandler struct {
+	mockHandler
+	classify func(err error) ErrorDecision
+}
+
+func (h *classifyingHandler) ClassifyError(err error) ErrorDecision {
+	return h.classify(err)
+}
+
+func TestDefaultErrorClassifier(t *testing.T) {
+	duplicateKey := mongodriver.WriteException{WriteErrors: []mongodriver.WriteError{{Code: 11000, Message: "E11000 duplicate key"}}}
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorDecision
+	}{
+		{"duplicate key", fmt.Errorf("insert order: %w", duplicateKey), DecisionSkip},
+		{"optimistic locking", fmt.Errorf("update order: %w", mongo.ErrOptimisticLocking), DecisionRetry},
+		{"deadline", fmt.Errorf("call: %w", context.DeadlineExceeded), DecisionRetry},
+		{"connect unavailable", connect.NewError(connect.CodeUnavailable, errors.New("down")), DecisionRetry},
+		{"connect invalid argument", connect.NewError(connect.CodeInvalidArgument, errors.New("bad")), DecisionDLQ},
+		{"connect already exists", connect.NewError(connect.CodeAlreadyExists, errors.New("exists")), DecisionSkip},
+		{"unknown error", errors.New("boom"), DecisionDefault},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DefaultErrorClassifier().ClassifyError(tt.err))
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	errNotReady := errors.New("reference data not ready")
+	classifier := ChainErrorClassifiers(
+		ErrorClassifierFunc(func(err error) ErrorDecision {
+			if errors.Is(err, errNotReady) {
+				return DecisionPause
+			}
+			return DecisionDefault
+		}),
+		DefaultErrorClassifier(),
+	)
+
+	t.Run("wraps the sentinel of the decision", func(t *testing.T) {
+		err := classifyError(classifier, errNotReady)
+		assert.ErrorIs(t, err, ErrPauseConsumer)
+		assert.ErrorIs(t, err, errNotReady)
+
+		err = classifyError(classifier, connect.NewError(connect.CodeNotFound, errors.New("missing")))
+		assert.ErrorIs(t, err, ErrPermanent)
+	})
+
+	t.Run("keeps explicit sentinels", func(t *testing.T) {
+		err := fmt.Errorf("%w: %w", ErrSkipMessage, errNotReady)
+		assert.Equal(t, err, classifyError(classifier, err))
+	})
+
+	t.Run("leaves unclassified errors retriable", func(t *testing.T) {
+		err := errors.New("boom")
+		assert.Equal(t, err, classifyError(classifier, err))
+		assert.NoError(t, classifyError(classifier, nil))
+	})
+}
+
+func TestProcessor_ErrorClassifier(t *testing.T) {
+	errNotReady := errors.New("reference data not ready")
+	newHandler := func(decision ErrorDecision, failures int32) *classifyingHandler {
+		h := &classifyingHandler{classify: func(err error) ErrorDecision {
+			if errors.Is(err, errNotReady) {
+				return decision
+			}
+			return DecisionDefault
+		}}
+		h.processFunc = func(context.Context, proto.Message) error {
+			if h.callCount.Load() <= failures {
+				return errNotReady
+			}
+			return nil
+		}
+		return h
+	}
+
+	t.Run("sends classified errors to the DLQ without retry", func(t *testing.T) {
+		handler := newHandler(DecisionDLQ, 1)
+		conf := createTestConsumerConfig()
+		conf.MaxRetries = uintPtr(3)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, zap.NewNop(), &ResultHandler{log: zap.NewNop()}, newMockTracer(), nil, nil, nil, conf)
+
+		assert.ErrorIs(t, p.executeWithRetry(context.Background(), newTestEnvelope(0, 1)), ErrPermanent)
+		assert.Equal(t, int32(1), handler.callCount.Load())
+	})
+
+	t.Run("retries errors of handlers without a classifier", func(t *testing.T) {
+		duplicateKey := mongodriver.WriteException{WriteErrors: []mongodriver.WriteError{{Code: 11000, Message: "E11000 duplicate key"}}}
+		handler := &mockHandler{processFunc: func(context.Context, proto.Message) error { return duplicateKey }}
+		conf := createTestConsumerConfig()
+		conf.MaxRetries = uintPtr(1)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, zap.NewNop(), &ResultHandler{log: zap.NewNop()}, newMockTracer(), nil, nil, nil, conf)
+
+		err := p.executeWithRetry(context.Background(), newTestEnvelope(0, 1))
+		assert.False(t, isControlError(err))
+		assert.Equal(t, int32(2), handler.callCount.Load())
+	})
+
+	t.Run("classifies errors of a router with a classifier", func(t *testing.T) {
+		var calls int
+		router := NewRouter(zap.NewNop())
+		Register(router, func(context.Context, *emptypb.Empty) error {
+			calls++
+			return connect.NewError(connect.CodeNotFound, errors.New("product not found"))
+		})
+		router.SetErrorClassifier(DefaultErrorClassifier())
+		conf := createTestConsumerConfig()
+		conf.MaxRetries = uintPtr(3)
+		p := NewProcessor(make(chan *MessageEnvelope), router, zap.NewNop(), &ResultHandler{log: zap.NewNop()}, newMockTracer(), nil, nil, nil, conf)
+
+		assert.ErrorIs(t, p.executeWithRetry(context.Background(), newTestEnvelope(0, 1)), ErrPermanent)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("retries errors of a router without a classifier", func(t *testing.T) {
+		var calls int
+		router := NewRouter(zap.NewNop())
+		Register(router, func(context.Context, *emptypb.Empty) error {
+			calls++
+			return connect.NewError(connect.CodeNotFound, errors.New("product not found"))
+		})
+		conf := createTestConsumerConfig()
+		conf.MaxRetries = uintPtr(1)
+		p := NewProcessor(make(chan *MessageEnvelope), router, zap.NewNop(), &ResultHandler{log: zap.NewNop()}, newMockTracer(), nil, nil, nil, conf)
+
+		err := p.executeWithRetry(context.Background(), newTestEnvelope(0, 1))
+		assert.False(t, isControlError(err))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("pauses the consumer and reprocesses the message once resumed", func(t *testing.T) {
+		handler := newHandler(DecisionPause, 1)
+		conf := createTestConsumerConfig()
+		controller := NewController(newTestClient(t, "test-topic"), newMockStateReporter(), conf, zap.NewNop())
+		marker := &mockOffsetMarker{}
+		rh := &ResultHandler{log: zap.NewNop(), dlqHandler: &mockDLQHandler{}, offsetMarker: marker}
+		p := NewProcessor(make(chan *MessageEnvelope), handler, zap.NewNop(), rh, newMockTracer(), nil, controller, nil, conf)
+
+		done := make(chan error, 1)
+		go func() { done <- p.processMessage(context.Background(), newTestEnvelope(0, 1)) }()
+
+		require.Eventually(t, func() bool { return controller.Status().State == StatePaused }, time.Second, 5*time.Millisecond)
+		assert.Empty(t, marker.markedRecords)
+
+		controller.Resume()
+		require.NoError(t, <-done)
+		assert.Equal(t, int32(2), handler.callCount.Load())
+		assert.Len(t, marker.markedRecords, 1)
+	})
+
+	t.Run("crashes without marking the message", func(t *testing.T) {
+		handler := newHandler(DecisionCrash, 1)
+		marker := &mockOffsetMarker{}
+		rh := &ResultHandler{log: zap.NewNop(), dlqHandler: &mockDLQHandler{}, offsetMarker: marker}
+		p := NewProcessor(make(chan *MessageEnvelope), handler, zap.NewNop(), rh, newMockTracer(), nil, nil, nil, createTestConsumerConfig())
+
+		err := p.processMessage(context.Background(), newTestEnvelope(0, 1))
+		assert.ErrorIs(t, err, ErrCrashConsumer)
+		assert.Empty(t, marker.markedRecords)
+	})
+}
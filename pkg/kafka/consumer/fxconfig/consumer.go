@@ -2,12 +2,16 @@ package fxconfig
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/Sokol111/ecommerce-commons/pkg/core/health"
+	"github.com/Sokol111/ecommerce-commons/pkg/core/worker"
 	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/consumer"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/kafkaproto"
 	"github.com/twmb/franz-go/pkg/kadm"
 	"github.com/twmb/franz-go/pkg/kgo"
 	"go.uber.org/fx"
@@ -15,17 +19,38 @@ import (
 )
 
 func provideConsumerClient(lc fx.Lifecycle, conf config.Config, consumerConf config.ConsumerConfig, log *zap.Logger, componentMgr health.ComponentManager) (*kgo.Client, error) {
-	brokers := strings.Split(conf.Brokers, ",")
-
+	componentName := "kafka-consumer-" + consumerConf.Name
 	resetOffset := kgo.NewOffset().AtEnd()
 	if consumerConf.AutoOffsetReset == "earliest" {
 		resetOffset = kgo.NewOffset().AtStart()
 	}
+	if consumerConf.TopicPattern != "" {
+		return newConsumerClient(lc, conf, consumerConf, consumerConf.GroupID, []string{consumerConf.TopicPattern}, true, resetOffset, componentName, log, componentMgr)
+	}
+	return newConsumerClient(lc, conf, consumerConf, consumerConf.GroupID, consumerConf.SourceTopics(), false, resetOffset, componentName, log, componentMgr)
+}
+
+// newConsumerClient creates a client that consumes topics in groupID and registers it as a readiness component.
+// With regex set, topics are regular expressions and every matching topic is consumed. Partitions without
+// a committed offset are consumed from resetOffset.
+func newConsumerClient(
+	lc fx.Lifecycle,
+	conf config.Config,
+	consumerConf config.ConsumerConfig,
+	groupID string,
+	topics []string,
+	regex bool,
+	resetOffset kgo.Offset,
+	componentName string,
+	log *zap.Logger,
+	componentMgr health.ComponentManager,
+) (*kgo.Client, error) {
+	brokers := strings.Split(conf.Brokers, ",")
 
 	opts := []kgo.Opt{
 		kgo.SeedBrokers(brokers...),
-		kgo.ConsumerGroup(consumerConf.GroupID),
-		kgo.ConsumeTopics(consumerConf.Topic),
+		kgo.ConsumerGroup(groupID),
+		kgo.ConsumeTopics(topics...),
 		kgo.ConsumeResetOffset(resetOffset),
 		kgo.AutoCommitInterval(3 * time.Second),
 		kgo.AutoCommitMarks(),
@@ -55,20 +80,30 @@ func provideConsumerClient(lc fx.Lifecycle, conf config.Config, consumerConf con
 		}),
 	}
 
+	if regex {
+		opts = append(opts, kgo.ConsumeRegex())
+	}
+
 	client, err := kgo.NewClient(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create kafka consumer, name: %s: %w", consumerConf.Name, err)
 	}
 
-	componentName := "kafka-consumer-" + consumerConf.Name
 	markReady := componentMgr.AddComponent(componentName)
 
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
-			log.Info("subscribing to topic", zap.String("topic", consumerConf.Topic))
+			if regex {
+				// Matching topics are discovered by the client; there is no fixed topic to verify.
+				log.Info("subscribing to topic pattern", zap.Strings("patterns", topics))
+				markReady()
+				return nil
+			}
 
-			// Verify topic is available
-			if err := verifyTopicAvailable(ctx, client, consumerConf.Topic, log); err != nil {
+			log.Info("subscribing to topics", zap.Strings("topics", topics))
+
+			// Verify topics are available
+			if err := verifyTopicsAvailable(ctx, client, topics, log); err != nil {
 				if consumerConf.FailOnTopicError {
 					return err
 				}
@@ -79,7 +114,7 @@ func provideConsumerClient(lc fx.Lifecycle, conf config.Config, consumerConf con
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
-			log.Info("closing kafka consumer")
+			log.Info("closing kafka consumer", zap.Strings("topics", topics))
 			client.Close()
 			return nil
 		},
@@ -88,6 +123,73 @@ func provideConsumerClient(lc fx.Lifecycle, conf config.Config, consumerConf con
 	return client, nil
 }
 
+// provideRetryConsumer creates the consumer of the retry tiers, nil if retry topics are disabled.
+// Each tier is consumed in its own group, {group}.retry.{delay}, from the tier topics of all source topics.
+// The tier groups start at the earliest offset regardless of auto-offset-reset: every record of a retry
+// topic is a failed message that must not be skipped.
+func provideRetryConsumer(
+	lc fx.Lifecycle,
+	conf config.Config,
+	consumerConf config.ConsumerConfig,
+	handler consumer.Handler,
+	deserializer kafkaproto.Deserializer,
+	tracer consumer.MessageTracer,
+	dlqHandler consumer.DLQHandler,
+	retryPublisher consumer.RetryPublisher,
+	inbox consumer.Inbox,
+	controller *consumer.Controller,
+	metrics *consumer.Metrics,
+	log *zap.Logger,
+	componentMgr health.ComponentManager,
+) (*consumer.RetryConsumer, error) {
+	tiers := consumer.RetryTiers(consumerConf)
+	if len(tiers) == 0 {
+		//nolint:nilnil // No retry consumer is run without tiers, see runRetryConsumer.
+		return nil, nil
+	}
+	clients := make([]*kgo.Client, 0, len(tiers))
+	sources := consumerConf.SourceTopics()
+	for _, tier := range tiers {
+		topics := make([]string, 0, len(sources))
+		for _, source := range sources {
+			topics = append(topics, tier.Topic(source))
+		}
+		suffix := ".retry." + tier.Name
+		client, err := newConsumerClient(lc, conf, consumerConf, consumerConf.GroupID+suffix, topics, false,
+			kgo.NewOffset().AtStart(), "kafka-consumer-"+consumerConf.Name+suffix, log, componentMgr)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return consumer.NewRetryConsumer(clients, handler, deserializer, tracer, dlqHandler, retryPublisher, inbox, controller, metrics, consumerConf, log), nil
+}
+
+// runRetryConsumer runs the retry consumer as a worker of the consumer module, if it has tiers.
+func runRetryConsumer(
+	lc fx.Lifecycle,
+	log *zap.Logger,
+	shutdowner fx.Shutdowner,
+	readiness health.ReadinessWaiter,
+	retryConsumer *consumer.RetryConsumer,
+) {
+	if retryConsumer == nil {
+		return
+	}
+	worker.Register(lc, log, shutdowner, readiness, "retry-consumer", retryConsumer, worker.WithTrafficReady(), worker.WithShutdown())
+}
+
+// verifyTopicsAvailable checks that every topic exists and has partitions.
+func verifyTopicsAvailable(ctx context.Context, client *kgo.Client, topics []string, log *zap.Logger) error {
+	var errs []error
+	for _, topic := range topics {
+		if err := verifyTopicAvailable(ctx, client, topic, log); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // verifyTopicAvailable checks if topic exists and has partitions.
 func verifyTopicAvailable(ctx context.Context, client *kgo.Client, topic string, log *zap.Logger) error {
 	admClient := kadm.NewClient(client)
@@ -2,6 +2,7 @@ package fxconfig
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/Sokol111/ecommerce-commons/pkg/core/worker"
 	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
@@ -22,18 +23,86 @@ func getConsumerConfig(conf config.Config, consumerName string) (config.Consumer
 }
 
 // RegisterHandlerAndConsumer creates a Kafka consumer module with the specified handler.
+// With retry topics enabled in the consumer config, it also consumes the retry tiers.
+// With the inbox module installed, events already processed by the consumer are skipped.
+// The consumer can be paused and rate limited at runtime through its consumer.Controller,
+// and its group offsets reset through its consumer.OffsetResetter while paused.
+// Startup fails if the handler is a consumer.Router without a handler for one of the event-types of the config.
 func RegisterHandlerAndConsumer(
 	consumerName string,
 	handlerConstructor any,
 ) fx.Option {
-	return fx.Module(
-		consumerName, // Unique module name
+	return consumerModule(
+		consumerName,
+		fx.Provide(
+			fx.Annotate(
+				handlerConstructor,
+				fx.As(new(consumer.Handler)),
+			),
+			fx.Annotate(
+				consumer.NewProcessor,
+				fx.ParamTags(``, ``, ``, ``, ``, `optional:"true"`),
+			),
+			provideRetryPublisher,
+			fx.Annotate(
+				provideRetryConsumer,
+				fx.ParamTags(``, ``, ``, ``, ``, ``, ``, ``, `optional:"true"`),
+			),
+			fx.Private,
+		),
+		fx.Invoke(
+			checkHandlerMode,
+			checkEventTypes,
+			worker.RunWorker[*consumer.Processor]("processor", worker.WithShutdown()),
+			runRetryConsumer,
+		),
+	)
+}
+
+// RegisterBatchHandlerAndConsumer creates a Kafka consumer module with the specified batch handler.
+// Batches are sized by the batch-size and batch-linger consumer settings. The consumer config must set
+// batch, which rejects the settings batch consumers don't support, and startup fails with the inbox
+// module installed. The consumer can be paused and its group offsets reset like any other consumer;
+// rate limits set at runtime don't apply to batch consumers.
+func RegisterBatchHandlerAndConsumer(
+	consumerName string,
+	handlerConstructor any,
+) fx.Option {
+	return consumerModule(
+		consumerName,
+		fx.Provide(
+			fx.Annotate(
+				handlerConstructor,
+				fx.As(new(consumer.BatchHandler)),
+			),
+			consumer.NewBatchProcessor,
+			consumer.NewNoopRetryPublisher,
+			fx.Private,
+		),
+		fx.Invoke(
+			fx.Annotate(
+				checkBatchMode,
+				fx.ParamTags(``, `optional:"true"`),
+			),
+			worker.RunWorker[*consumer.BatchProcessor]("batch-processor", worker.WithShutdown()),
+		),
+	)
+}
+
+// consumerModule wires the reader, deserializer and metrics shared by all consumer modes;
+// processing provides the handler and runs its processor.
+func consumerModule(consumerName string, processing ...fx.Option) fx.Option {
+	opts := []fx.Option{
 		fx.Decorate(
 			func(log *zap.Logger, consumerConf config.ConsumerConfig) *zap.Logger {
+				subscription := zap.Strings("topics", consumerConf.SourceTopics())
+				if consumerConf.TopicPattern != "" {
+					subscription = zap.String("topic_pattern", consumerConf.TopicPattern)
+				}
 				return log.With(
 					zap.String("component", "consumer"),
 					zap.String("consumer_name", consumerConf.Name),
-					zap.String("topic", consumerConf.Topic),
+					subscription,
 					zap.String("group_id", consumerConf.GroupID),
 				)
 			},
@@ -50,16 +119,16 @@ func RegisterHandlerAndConsumer(
 				getConsumerConfig,
 				fx.ParamTags(``, `name:"consumerName"`),
 			),
-			fx.Annotate(
-				handlerConstructor,
-				fx.As(new(consumer.Handler)),
-			),
 			provideConsumerClient,
-			consumer.NewProcessor,
 			consumer.NewMessageDeserializer,
 			consumer.NewMessageTracer,
 			consumer.NewResultHandler,
 			consumer.NewReader,
+			consumer.NewMetrics,
+			consumer.NewLagMonitor,
+			consumer.NewInFlightCounter,
+			consumer.NewController,
+			consumer.NewOffsetResetter,
 			provideMessageChannel,
 			provideEnvelopeChannel,
 			provideDLQHandler,
@@ -68,11 +137,67 @@ func RegisterHandlerAndConsumer(
 		fx.Invoke(
 			worker.RunWorker[*consumer.Reader]("reader", worker.WithTrafficReady(), worker.WithShutdown()),
 			worker.RunWorker[*consumer.MessageDeserializer]("deserializer"),
-			worker.RunWorker[*consumer.Processor]("processor"),
+			worker.RunWorker[*consumer.LagMonitor]("lag-monitor", worker.WithTrafficReady()),
+			fx.Annotate(
+				registerControllerRoutes,
+				fx.ParamTags(``, ``, ``, `optional:"true"`, ``),
+			),
 		),
+	}
+	return fx.Module(
+		consumerName, // Unique module name
+		append(opts, processing...)...,
 	)
 }
 
+// checkHandlerMode fails startup if the consumer config is meant for a batch handler.
+func checkHandlerMode(consumerConf config.ConsumerConfig) error {
+	if consumerConf.Batch {
+		return fmt.Errorf("consumer %s: batch is set, register it with RegisterBatchHandlerAndConsumer", consumerConf.Name)
+	}
+	return nil
+}
+
+// checkBatchMode fails startup if the consumer config is not meant for a batch handler, or if
+// the inbox module is installed, which batch consumers don't support.
+func checkBatchMode(consumerConf config.ConsumerConfig, inbox consumer.Inbox) error {
+	if !consumerConf.Batch {
+		return fmt.Errorf("consumer %s: batch handlers require batch to be set in the consumer config", consumerConf.Name)
+	}
+	if inbox != nil {
+		return fmt.Errorf("consumer %s: the inbox is not supported by batch consumers", consumerConf.Name)
+	}
+	return nil
+}
+
+// checkEventTypes fails startup if the handler routes a configured event type to no handler.
+func checkEventTypes(consumerConf config.ConsumerConfig, handler consumer.Handler) error {
+	if err := consumer.CheckEventTypes(handler, consumerConf.EventTypes); err != nil {
+		return fmt.Errorf("consumer %s: %w", consumerConf.Name, err)
+	}
+	return nil
+}
+
+// registerControllerRoutes exposes the consumer controller when the admin API is enabled and an HTTP server is present.
+func registerControllerRoutes(
+	conf config.Config,
+	controller *consumer.Controller,
+	resetter *consumer.OffsetResetter,
+	mux *http.ServeMux,
+	log *zap.Logger,
+) {
+	admin := conf.ConsumersConfig.Admin
+	if !admin.Enabled {
+		return
+	}
+	if mux == nil {
+		log.Warn("consumer admin API is enabled but no HTTP server is configured")
+		return
+	}
+	consumer.RegisterControllerRoutes(mux, admin.PathPrefix, consumer.NewControllerHandler(controller, resetter, log))
+	log.Info("consumer admin API registered", zap.String("path_prefix", admin.PathPrefix))
+}
+
 func provideMessageChannel(consumerConf config.ConsumerConfig) chan *kgo.Record {
 	return make(chan *kgo.Record, consumerConf.ChannelBufferSize)
 }
@@ -81,6 +206,13 @@ func provideEnvelopeChannel(consumerConf config.ConsumerConfig) chan *consumer.M
 	return make(chan *consumer.MessageEnvelope, consumerConf.ChannelBufferSize)
 }
 
+func provideRetryPublisher(consumerConf config.ConsumerConfig, tracer consumer.MessageTracer, retryProducer producer.Producer, logger *zap.Logger) consumer.RetryPublisher {
+	if consumerConf.RetryTopics.Enabled {
+		return consumer.NewRetryPublisher(retryProducer, consumer.RetryTiers(consumerConf), tracer, logger)
+	}
+	return consumer.NewNoopRetryPublisher()
+}
+
 func provideDLQHandler(consumerConf config.ConsumerConfig, tracer consumer.MessageTracer, dlqProducer producer.Producer, logger *zap.Logger) consumer.DLQHandler {
 	if consumerConf.EnableDLQ {
 		return consumer.NewDLQHandler(dlqProducer, consumerConf.DLQTopic, tracer, logger)
@@ -0,0 +1,67 @@
+package fxconfig
+
+import (
+	"fmt"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/core/health"
+	"github.com/Sokol111/ecommerce-commons/pkg/core/worker"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/consumer"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/producer"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// RegisterRedriver creates a module that continuously redrives the DLQ of the named consumer
+// to the original topics. Records are selected by the redrive section of the consumer config.
+func RegisterRedriver(consumerName string) fx.Option {
+	return fx.Module(
+		consumerName+"-redriver",
+		fx.Decorate(
+			func(log *zap.Logger, consumerConf config.ConsumerConfig) *zap.Logger {
+				return log.With(
+					zap.String("component", "redriver"),
+					zap.String("consumer_name", consumerConf.Name),
+					zap.String("group_id", consumerConf.Redrive.GroupID),
+				)
+			},
+		),
+		fx.Supply(
+			fx.Annotate(
+				consumerName,
+				fx.ResultTags(`name:"consumerName"`),
+			),
+			fx.Private,
+		),
+		fx.Provide(
+			fx.Annotate(
+				getConsumerConfig,
+				fx.ParamTags(``, `name:"consumerName"`),
+			),
+			provideRedriveClient,
+			provideRedriver,
+			fx.Private,
+		),
+		fx.Invoke(
+			worker.RunWorker[*consumer.Redriver]("redriver", worker.WithTrafficReady()),
+		),
+	)
+}
+
+// provideRedriveClient creates a client that consumes the DLQ topic in the redrive group from the start.
+func provideRedriveClient(lc fx.Lifecycle, conf config.Config, consumerConf config.ConsumerConfig, log *zap.Logger, componentMgr health.ComponentManager) (*kgo.Client, error) {
+	dlqTopics := consumerConf.DLQTopics()
+	if len(dlqTopics) == 0 {
+		return nil, fmt.Errorf("consumer %s has no DLQ topic to redrive, set dlq-topic for a topic-pattern consumer", consumerConf.Name)
+	}
+	if consumerConf.Redrive.GroupID == "" {
+		return nil, fmt.Errorf("consumer %s has no redrive group id", consumerConf.Name)
+	}
+	return newConsumerClient(lc, conf, consumerConf, consumerConf.Redrive.GroupID, dlqTopics, false,
+		kgo.NewOffset().AtStart(), "kafka-redriver-"+consumerConf.Name, log, componentMgr)
+}
+
+func provideRedriver(client *kgo.Client, redriveProducer producer.Producer, consumerConf config.ConsumerConfig, log *zap.Logger) *consumer.Redriver {
+	return consumer.NewRedriver(client, redriveProducer, consumerConf.DLQTopics(), consumerConf.Redrive, log)
+}
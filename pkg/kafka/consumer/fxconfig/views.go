@@ -0,0 +1,59 @@
+package fxconfig
+
+import (
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/consumer"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// MetricViews returns SDK views that keep consumer metrics low-cardinality.
+//
+// Consumer instruments are recorded with the consumer, topic and outcome attributes only, and the
+// event duration of consumer.MetricsMiddleware with the consumer, event type and outcome; the
+// views drop anything else that may be attached and give the duration histograms buckets from
+// a millisecond up to handlers that hit the processing timeout. The views are shared by all
+// consumers and must be provided once, which NewKafkaModule does.
+func MetricViews() []sdkmetric.View {
+	allowed := attribute.NewAllowKeysFilter(
+		attribute.Key(consumer.MetricAttributeConsumer),
+		attribute.Key(consumer.MetricAttributeTopic),
+		attribute.Key(consumer.MetricAttributeOutcome),
+	)
+
+	durationBuckets := sdkmetric.AggregationExplicitBucketHistogram{
+		Boundaries: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 600},
+	}
+
+	views := []sdkmetric.View{
+		sdkmetric.NewView(
+			sdkmetric.Instrument{Name: consumer.MetricProcessingDuration},
+			sdkmetric.Stream{AttributeFilter: allowed, Aggregation: durationBuckets},
+		),
+		sdkmetric.NewView(
+			sdkmetric.Instrument{Name: consumer.MetricEventDuration},
+			sdkmetric.Stream{
+				AttributeFilter: attribute.NewAllowKeysFilter(
+					attribute.Key(consumer.MetricAttributeConsumer),
+					attribute.Key(consumer.MetricAttributeEventType),
+					attribute.Key(consumer.MetricAttributeOutcome),
+				),
+				Aggregation: durationBuckets,
+			},
+		),
+	}
+	for _, name := range []string{
+		consumer.MetricLag,
+		consumer.MetricLagMax,
+		consumer.MetricMessages,
+		consumer.MetricDLQMessages,
+		consumer.MetricRetryAttempts,
+		consumer.MetricRecordsBuffered,
+		consumer.MetricEnvelopesBuffered,
+	} {
+		views = append(views, sdkmetric.NewView(
+			sdkmetric.Instrument{Name: name},
+			sdkmetric.Stream{AttributeFilter: allowed},
+		))
+	}
+	return views
+}
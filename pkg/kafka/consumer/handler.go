@@ -23,11 +23,25 @@ var ErrSkipMessage = errors.New("skip message processing")
 // - Data integrity violation
 // - Panic occurred (bug in code)
 // - Any error that retrying won't fix
-// If you don't wrap your error with ErrPermanent or ErrSkipMessage,
-// it will be treated as a retryable error with exponential backoff.
+// If you don't wrap your error with ErrPermanent or ErrSkipMessage and no ErrorClassifier
+// classifies it, it will be treated as a retryable error with exponential backoff.
 var ErrPermanent = errors.New("permanent error")
 
 // Handler defines the interface for processing Kafka messages.
 type Handler interface {
 	Process(ctx context.Context, event proto.Message) error
 }
+
+// BatchHandler defines the interface for processing Kafka messages in batches.
+// Every batch holds messages of a single partition in offset order.
+//
+// ProcessBatch returns one result per message, in the same order as batch.
+// A nil result marks the message as processed. ErrSkipMessage and ErrPermanent
+// have the same meaning as for Handler; any other error is retried for that
+// message only. A nil slice means the whole batch succeeded.
+//
+// ctx is shared by the batch; per-message trace and tenant context can be read
+// from the record headers, e.g. with tenant.ContextFromKafkaHeaders.
+type BatchHandler interface {
+	ProcessBatch(ctx context.Context, batch []*MessageEnvelope) []error
+}
@@ -0,0 +1,94 @@
+package consumer
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// InFlightCounter counts the records of a consumer that were fetched but not finished yet:
+// finished records are marked for commit, or sent to the DLQ when they cannot be deserialized.
+// OffsetResetter refuses to reset while records are in flight, since marking them after the
+// reset would commit their offsets over the new ones. Records parked by a handler that paused
+// the consumer don't count; the reset drops them instead. A nil InFlightCounter counts nothing.
+type InFlightCounter struct {
+	n atomic.Int64
+
+	mu      sync.Mutex
+	parked  int64
+	dropped chan struct{} // Closed by dropParked, nil while no record is parked
+}
+
+// NewInFlightCounter creates an InFlightCounter.
+func NewInFlightCounter() *InFlightCounter {
+	return &InFlightCounter{}
+}
+
+func (c *InFlightCounter) add() {
+	if c != nil {
+		c.n.Add(1)
+	}
+}
+
+func (c *InFlightCounter) done() {
+	if c != nil {
+		c.n.Add(-1)
+	}
+}
+
+func (c *InFlightCounter) count() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.n.Load()
+}
+
+// park excludes n records held by a paused consumer from the records in flight and returns
+// a channel that is closed once an offset reset drops them. A nil counter returns nil.
+func (c *InFlightCounter) park(n int) <-chan struct{} {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.parked += int64(n)
+	if c.dropped == nil {
+		c.dropped = make(chan struct{})
+	}
+	return c.dropped
+}
+
+func (c *InFlightCounter) unpark(n int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.parked -= int64(n)
+}
+
+// dropParked tells the parked records to be dropped.
+func (c *InFlightCounter) dropParked() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dropped != nil {
+		close(c.dropped)
+		c.dropped = nil
+	}
+}
+
+// active returns the number of records in flight that are not parked.
+func (c *InFlightCounter) active() int64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.n.Load() - c.parked
+}
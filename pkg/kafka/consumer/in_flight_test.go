@@ -0,0 +1,50 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestInFlightCounter(t *testing.T) {
+	t.Run("finishes records when they are marked", func(t *testing.T) {
+		inFlight := NewInFlightCounter()
+		rh := &ResultHandler{log: zap.NewNop(), dlqHandler: &mockDLQHandler{}, offsetMarker: &mockOffsetMarker{}, inFlight: inFlight}
+
+		inFlight.add()
+		inFlight.add()
+		rh.handle(context.Background(), nil, createTestMessage(), newMockSpan())
+
+		assert.Equal(t, int64(1), inFlight.count())
+	})
+
+	t.Run("parked records are not active", func(t *testing.T) {
+		inFlight := NewInFlightCounter()
+		inFlight.add()
+		inFlight.add()
+
+		dropped := inFlight.park(1)
+		assert.Equal(t, int64(1), inFlight.active())
+
+		inFlight.dropParked()
+		assert.NotNil(t, dropped)
+		_, open := <-dropped
+		assert.False(t, open)
+
+		inFlight.unpark(1)
+		assert.Equal(t, int64(2), inFlight.active())
+	})
+
+	t.Run("nil counter counts nothing", func(t *testing.T) {
+		var inFlight *InFlightCounter
+		inFlight.add()
+		inFlight.done()
+		inFlight.dropParked()
+
+		assert.Nil(t, inFlight.park(1))
+		assert.Zero(t, inFlight.count())
+		assert.Zero(t, inFlight.active())
+	})
+}
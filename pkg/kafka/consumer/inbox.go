@@ -0,0 +1,35 @@
+package consumer
+
+import (
+	"context"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
+)
+
+// HeaderEventID is the header with the unique id of an event, written by kafkaproto.HeaderPopulator.
+const HeaderEventID = "event_id"
+
+// Inbox records the events a consumer processed, so that redelivered messages are skipped.
+// Events are identified by the event_id header and tracked per consumer; messages without
+// the header are always processed. Batch handlers do not use the inbox.
+type Inbox interface {
+	// Seen reports whether the consumer already processed the event.
+	Seen(ctx context.Context, consumerName, eventID string) (bool, error)
+
+	// Record stores the event as processed by the consumer. With a transaction context the record
+	// is committed together with the handler's writes. It returns an error wrapping ErrSkipMessage
+	// if the event is already recorded.
+	Record(ctx context.Context, consumerName, eventID, topic string) error
+}
+
+// TransactionalHandler is a Handler that opts in to processing each event in a MongoDB transaction
+// together with its inbox record: the handler's writes and the record are committed or rolled
+// back as one, so an event is never applied twice. Process receives the transaction context.
+// Without an inbox it is called like any other Handler. A Router opts in with Router.SetTxManager.
+type TransactionalHandler interface {
+	Handler
+
+	// TxManager returns the transaction manager of the database the handler writes to,
+	// nil to process without a transaction.
+	TxManager() mongo.TxManager
+}
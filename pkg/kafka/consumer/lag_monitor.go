@@ -0,0 +1,86 @@
+package consumer
+
+import (
+	"context"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+// lagRefreshInterval is how often the consumer group lag is refreshed.
+const lagRefreshInterval = 30 * time.Second
+
+// LagMonitor periodically records the lag of the consumer group in Metrics.
+type LagMonitor struct {
+	admin    *kadm.Client
+	groupID  string
+	metrics  *Metrics
+	interval time.Duration
+	log      *zap.Logger
+}
+
+// NewLagMonitor creates a LagMonitor that queries the lag of the consumer group through client.
+func NewLagMonitor(client *kgo.Client, metrics *Metrics, consumerConf config.ConsumerConfig, log *zap.Logger) *LagMonitor {
+	return &LagMonitor{
+		admin:    kadm.NewClient(client),
+		groupID:  consumerConf.GroupID,
+		metrics:  metrics,
+		interval: lagRefreshInterval,
+		log:      log,
+	}
+}
+
+// Run refreshes the lag until ctx is cancelled.
+func (l *LagMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		l.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *LagMonitor) refresh(ctx context.Context) {
+	lags, err := l.admin.Lag(ctx, l.groupID)
+	if err != nil {
+		if ctx.Err() == nil {
+			l.log.Warn("failed to fetch consumer group lag", zap.Error(err))
+		}
+		return
+	}
+	described, ok := lags[l.groupID]
+	if !ok {
+		return
+	}
+	if err = described.Error(); err != nil {
+		l.log.Warn("failed to fetch consumer group lag", zap.Error(err))
+		return
+	}
+	l.metrics.recordLag(summarizeLag(described.Lag))
+}
+
+// summarizeLag sums the partition lags per topic and keeps the largest one.
+// Partitions whose lag could not be computed are left out.
+func summarizeLag(lag kadm.GroupLag) map[string]topicLag {
+	byTopic := make(map[string]topicLag, len(lag))
+	for topic, partitions := range lag {
+		var tl topicLag
+		for _, pl := range partitions {
+			if pl.Err != nil || pl.Lag < 0 {
+				continue
+			}
+			tl.total += pl.Lag
+			tl.max = max(tl.max, pl.Lag)
+		}
+		byTopic[topic] = tl
+	}
+	return byTopic
+}
@@ -0,0 +1,202 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metric instrument names.
+const (
+	MetricLag                = "kafka.consumer.lag"
+	MetricLagMax             = "kafka.consumer.lag.max"
+	MetricProcessingDuration = "kafka.consumer.processing.duration"
+	MetricMessages           = "kafka.consumer.messages"
+	MetricDLQMessages        = "kafka.consumer.dlq.messages"
+	MetricRetryAttempts      = "kafka.consumer.retry.attempts"
+	MetricRecordsBuffered    = "kafka.consumer.buffer.records"
+	MetricEnvelopesBuffered  = "kafka.consumer.buffer.envelopes"
+	MetricEventDuration      = "kafka.consumer.event.duration" // Recorded by MetricsMiddleware
+
+	// The only attributes recorded on consumer metrics; the event type only by MetricsMiddleware.
+	MetricAttributeConsumer  = "consumer"
+	MetricAttributeTopic     = "topic"
+	MetricAttributeOutcome   = "outcome"
+	MetricAttributeEventType = "event_type"
+)
+
+// Values of the outcome attribute.
+const (
+	OutcomeSuccess    = "success"
+	OutcomeSkipped    = "skipped"
+	OutcomePermanent  = "permanent"   // Permanent error, sent to the DLQ
+	OutcomeRetryTopic = "retry_topic" // Retriable error, sent to the next retry tier
+	OutcomeFailed     = "failed"      // Retries exhausted, sent to the DLQ
+	OutcomeInvalid    = "invalid"     // Deserialization failed, sent to the DLQ
+	OutcomeError      = "error"       // Handler error, recorded by MetricsMiddleware before classification
+)
+
+// topicLag is the consumer group lag of a topic.
+type topicLag struct {
+	total int64
+	max   int64 // Largest lag of a single partition
+}
+
+// Metrics records consumer metrics. All instruments carry the consumer attribute and at most
+// the topic and outcome attributes, so cardinality is bounded by the subscribed topics.
+// Batch consumers record every message with the processing duration of its batch.
+// A nil *Metrics records nothing.
+type Metrics struct {
+	consumer           attribute.KeyValue
+	processingDuration metric.Float64Histogram
+	messages           metric.Int64Counter
+	dlqMessages        metric.Int64Counter
+	retryAttempts      metric.Int64Counter
+
+	mu sync.Mutex
+	// lag is the latest snapshot per topic; topics that left the assignment are kept with
+	// a zero lag so their gauges drop to zero instead of disappearing.
+	lag map[string]topicLag
+}
+
+// NewMetrics creates the instruments of a consumer on the "kafka.consumer" meter.
+// The buffer gauges observe the occupancy of messagesChan and envelopeChan.
+func NewMetrics(
+	meterProvider metric.MeterProvider,
+	messagesChan chan *kgo.Record,
+	envelopeChan chan *MessageEnvelope,
+	consumerConf config.ConsumerConfig,
+) (*Metrics, error) {
+	meter := meterProvider.Meter("kafka.consumer")
+	m := &Metrics{
+		consumer: attribute.String(MetricAttributeConsumer, consumerConf.Name),
+		lag:      make(map[string]topicLag),
+	}
+
+	var err error
+	if m.processingDuration, err = meter.Float64Histogram(MetricProcessingDuration,
+		metric.WithDescription("Time to process a consumed message, including in-process retries."),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to create %s histogram: %w", MetricProcessingDuration, err)
+	}
+	if m.messages, err = meter.Int64Counter(MetricMessages,
+		metric.WithDescription("Number of consumed messages by processing outcome."),
+		metric.WithUnit("{message}"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to create %s counter: %w", MetricMessages, err)
+	}
+	if m.dlqMessages, err = meter.Int64Counter(MetricDLQMessages,
+		metric.WithDescription("Number of consumed messages sent to the DLQ."),
+		metric.WithUnit("{message}"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to create %s counter: %w", MetricDLQMessages, err)
+	}
+	if m.retryAttempts, err = meter.Int64Counter(MetricRetryAttempts,
+		metric.WithDescription("Number of in-process retries of failed messages."),
+		metric.WithUnit("{attempt}"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to create %s counter: %w", MetricRetryAttempts, err)
+	}
+
+	lag, err := meter.Int64ObservableGauge(MetricLag,
+		metric.WithDescription("Consumer group lag summed over the partitions of a topic."),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s gauge: %w", MetricLag, err)
+	}
+	lagMax, err := meter.Int64ObservableGauge(MetricLagMax,
+		metric.WithDescription("Largest consumer group lag of a single partition of a topic."),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s gauge: %w", MetricLagMax, err)
+	}
+	recordsBuffered, err := meter.Int64ObservableGauge(MetricRecordsBuffered,
+		metric.WithDescription("Number of fetched records waiting for deserialization."),
+		metric.WithUnit("{record}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s gauge: %w", MetricRecordsBuffered, err)
+	}
+	envelopesBuffered, err := meter.Int64ObservableGauge(MetricEnvelopesBuffered,
+		metric.WithDescription("Number of deserialized messages waiting for processing."),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s gauge: %w", MetricEnvelopesBuffered, err)
+	}
+
+	if _, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		consumerOnly := metric.WithAttributes(m.consumer)
+		o.ObserveInt64(recordsBuffered, int64(len(messagesChan)), consumerOnly)
+		o.ObserveInt64(envelopesBuffered, int64(len(envelopeChan)), consumerOnly)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for topic, l := range m.lag {
+			attrs := metric.WithAttributes(m.consumer, attribute.String(MetricAttributeTopic, topic))
+			o.ObserveInt64(lag, l.total, attrs)
+			o.ObserveInt64(lagMax, l.max, attrs)
+		}
+		return nil
+	}, lag, lagMax, recordsBuffered, envelopesBuffered); err != nil {
+		return nil, fmt.Errorf("failed to register consumer %s metrics callback: %w", consumerConf.Name, err)
+	}
+
+	return m, nil
+}
+
+func (m *Metrics) topicAttributes(topic string, outcome string) metric.MeasurementOption {
+	return metric.WithAttributes(m.consumer,
+		attribute.String(MetricAttributeTopic, topic),
+		attribute.String(MetricAttributeOutcome, outcome))
+}
+
+func (m *Metrics) recordProcessed(ctx context.Context, topic, outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.processingDuration.Record(ctx, duration.Seconds(), m.topicAttributes(topic, outcome))
+}
+
+// recordOutcome counts a message by outcome, and as sent to the DLQ for the DLQ outcomes.
+func (m *Metrics) recordOutcome(ctx context.Context, topic, outcome string) {
+	if m == nil {
+		return
+	}
+	attrs := m.topicAttributes(topic, outcome)
+	m.messages.Add(ctx, 1, attrs)
+	if outcome == OutcomePermanent || outcome == OutcomeFailed || outcome == OutcomeInvalid {
+		m.dlqMessages.Add(ctx, 1, attrs)
+	}
+}
+
+func (m *Metrics) recordRetry(ctx context.Context, topic string) {
+	if m == nil {
+		return
+	}
+	m.retryAttempts.Add(ctx, 1, metric.WithAttributes(m.consumer, attribute.String(MetricAttributeTopic, topic)))
+}
+
+// recordLag replaces the lag snapshot.
+func (m *Metrics) recordLag(lag map[string]topicLag) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for topic := range m.lag {
+		m.lag[topic] = topicLag{}
+	}
+	for topic, l := range lag {
+		m.lag[topic] = l
+	}
+}
@@ -0,0 +1,229 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// newRecordingMetrics returns Metrics backed by a manual reader for inspecting recorded values.
+func newRecordingMetrics(t *testing.T, messagesChan chan *kgo.Record, envelopeChan chan *MessageEnvelope) (*Metrics, *sdkmetric.ManualReader) {
+	t.Helper()
+
+	reader := sdkmetric.NewManualReader()
+	m, err := NewMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), messagesChan, envelopeChan, createTestConsumerConfig())
+	require.NoError(t, err)
+	return m, reader
+}
+
+func collectMetric(t *testing.T, reader *sdkmetric.ManualReader, name string) metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	t.Fatalf("metric %s not recorded", name)
+	return nil
+}
+
+func attributeOf(attrs attribute.Set, key string) string {
+	v, _ := attrs.Value(attribute.Key(key))
+	return v.AsString()
+}
+
+// countsByOutcome returns the counter values by outcome attribute.
+func countsByOutcome(t *testing.T, reader *sdkmetric.ManualReader, name string) map[string]int64 {
+	t.Helper()
+
+	sum, ok := collectMetric(t, reader, name).(metricdata.Sum[int64])
+	require.True(t, ok)
+	counts := make(map[string]int64)
+	for _, dp := range sum.DataPoints {
+		assert.Equal(t, "test-consumer", attributeOf(dp.Attributes, MetricAttributeConsumer))
+		assert.Equal(t, "test-topic", attributeOf(dp.Attributes, MetricAttributeTopic))
+		counts[attributeOf(dp.Attributes, MetricAttributeOutcome)] = dp.Value
+	}
+	return counts
+}
+
+func TestMetrics_ResultHandler(t *testing.T) {
+	m, reader := newRecordingMetrics(t, nil, nil)
+	rh := &ResultHandler{
+		log:            zap.NewNop(),
+		dlqHandler:     &mockDLQHandler{},
+		retryPublisher: &mockRetryPublisher{published: true},
+		offsetMarker:   &mockOffsetMarker{},
+		metrics:        m,
+	}
+	record := createTestMessage()
+
+	assert.Equal(t, OutcomeSuccess, rh.handle(context.Background(), nil, record, newMockSpan()))
+	assert.Equal(t, OutcomeSkipped, rh.handle(context.Background(), ErrSkipMessage, record, newMockSpan()))
+	assert.Equal(t, OutcomePermanent, rh.handle(context.Background(), ErrPermanent, record, newMockSpan()))
+	assert.Equal(t, OutcomeRetryTopic, rh.handle(context.Background(), errors.New("timeout"), record, newMockSpan()))
+
+	rh.retryPublisher = NewNoopRetryPublisher()
+	assert.Equal(t, OutcomeFailed, rh.handle(context.Background(), errors.New("timeout"), record, newMockSpan()))
+
+	assert.Equal(t, map[string]int64{
+		OutcomeSuccess:    1,
+		OutcomeSkipped:    1,
+		OutcomePermanent:  1,
+		OutcomeRetryTopic: 1,
+		OutcomeFailed:     1,
+	}, countsByOutcome(t, reader, MetricMessages))
+	assert.Equal(t, map[string]int64{
+		OutcomePermanent: 1,
+		OutcomeFailed:    1,
+	}, countsByOutcome(t, reader, MetricDLQMessages))
+}
+
+func TestMetrics_Deserializer(t *testing.T) {
+	m, reader := newRecordingMetrics(t, nil, nil)
+	deserializer := &mockDeserializer{
+		deserializeFunc: func([]byte, map[string][]byte) (proto.Message, error) {
+			return nil, errors.New("invalid payload")
+		},
+	}
+	d := NewMessageDeserializer(nil, make(chan *MessageEnvelope, 1), deserializer, zap.NewNop(), newMockTracer(), &mockDLQHandler{}, m, nil)
+
+	d.deserializeAndSend(context.Background(), createTestMessage())
+
+	assert.Equal(t, map[string]int64{OutcomeInvalid: 1}, countsByOutcome(t, reader, MetricDLQMessages))
+}
+
+func TestMetrics_Processor(t *testing.T) {
+	m, reader := newRecordingMetrics(t, nil, nil)
+	var calls int
+	handler := &mockHandler{
+		processFunc: func(context.Context, proto.Message) error {
+			calls++
+			if calls < 3 {
+				return errors.New("temporary error")
+			}
+			return nil
+		},
+	}
+	rh := &ResultHandler{log: zap.NewNop(), dlqHandler: &mockDLQHandler{}, offsetMarker: &mockOffsetMarker{}, metrics: m}
+	p := NewProcessor(make(chan *MessageEnvelope), handler, zap.NewNop(), rh, newMockTracer(), nil, nil, m, createTestConsumerConfig())
+
+	require.NoError(t, p.processMessage(context.Background(), newTestEnvelope(0, 1)))
+
+	t.Run("records processing duration by outcome", func(t *testing.T) {
+		hist, ok := collectMetric(t, reader, MetricProcessingDuration).(metricdata.Histogram[float64])
+		require.True(t, ok)
+		require.Len(t, hist.DataPoints, 1)
+		assert.Equal(t, OutcomeSuccess, attributeOf(hist.DataPoints[0].Attributes, MetricAttributeOutcome))
+		assert.Equal(t, uint64(1), hist.DataPoints[0].Count)
+	})
+
+	t.Run("counts in-process retries", func(t *testing.T) {
+		sum, ok := collectMetric(t, reader, MetricRetryAttempts).(metricdata.Sum[int64])
+		require.True(t, ok)
+		require.Len(t, sum.DataPoints, 1)
+		assert.Equal(t, int64(2), sum.DataPoints[0].Value)
+	})
+}
+
+func TestMetrics_BatchProcessor(t *testing.T) {
+	m, reader := newRecordingMetrics(t, nil, nil)
+	var calls int
+	handler := &mockBatchHandler{
+		processFunc: func(ctx context.Context, batch []*MessageEnvelope) []error {
+			calls++
+			if calls == 1 {
+				return []error{nil, errors.New("temporary error")}
+			}
+			return nil
+		},
+	}
+	rh := &ResultHandler{log: zap.NewNop(), dlqHandler: &mockDLQHandler{}, offsetMarker: &mockOffsetMarker{}, metrics: m}
+	p := NewBatchProcessor(nil, handler, zap.NewNop(), rh, newMockTracer(), nil, m, createTestConsumerConfig())
+
+	require.NoError(t, p.processBatch(context.Background(), []*MessageEnvelope{newTestEnvelope(0, 1), newTestEnvelope(0, 2)}))
+
+	t.Run("records processing duration of every message", func(t *testing.T) {
+		hist, ok := collectMetric(t, reader, MetricProcessingDuration).(metricdata.Histogram[float64])
+		require.True(t, ok)
+		require.Len(t, hist.DataPoints, 1)
+		assert.Equal(t, OutcomeSuccess, attributeOf(hist.DataPoints[0].Attributes, MetricAttributeOutcome))
+		assert.Equal(t, uint64(2), hist.DataPoints[0].Count)
+	})
+
+	t.Run("counts retries of failed messages", func(t *testing.T) {
+		sum, ok := collectMetric(t, reader, MetricRetryAttempts).(metricdata.Sum[int64])
+		require.True(t, ok)
+		require.Len(t, sum.DataPoints, 1)
+		assert.Equal(t, int64(1), sum.DataPoints[0].Value)
+	})
+}
+
+func TestMetrics_Buffers(t *testing.T) {
+	messagesChan := make(chan *kgo.Record, 10)
+	envelopeChan := make(chan *MessageEnvelope, 10)
+	_, reader := newRecordingMetrics(t, messagesChan, envelopeChan)
+
+	messagesChan <- createTestMessage()
+	messagesChan <- createTestMessage()
+	envelopeChan <- newTestEnvelope(0, 1)
+
+	records, ok := collectMetric(t, reader, MetricRecordsBuffered).(metricdata.Gauge[int64])
+	require.True(t, ok)
+	require.Len(t, records.DataPoints, 1)
+	assert.Equal(t, int64(2), records.DataPoints[0].Value)
+
+	envelopes, ok := collectMetric(t, reader, MetricEnvelopesBuffered).(metricdata.Gauge[int64])
+	require.True(t, ok)
+	require.Len(t, envelopes.DataPoints, 1)
+	assert.Equal(t, int64(1), envelopes.DataPoints[0].Value)
+}
+
+func TestMetrics_Lag(t *testing.T) {
+	m, reader := newRecordingMetrics(t, nil, nil)
+
+	lagByTopic := func(name string) map[string]int64 {
+		gauge, ok := collectMetric(t, reader, name).(metricdata.Gauge[int64])
+		require.True(t, ok)
+		byTopic := make(map[string]int64)
+		for _, dp := range gauge.DataPoints {
+			byTopic[attributeOf(dp.Attributes, MetricAttributeTopic)] = dp.Value
+		}
+		return byTopic
+	}
+
+	t.Run("sums partitions and keeps the largest", func(t *testing.T) {
+		m.recordLag(summarizeLag(kadm.GroupLag{
+			"orders": {
+				0: {Lag: 5},
+				1: {Lag: 12},
+				2: {Lag: 100, Err: errors.New("not leader")},
+			},
+			"payments": {0: {Lag: 0}},
+		}))
+
+		assert.Equal(t, map[string]int64{"orders": 17, "payments": 0}, lagByTopic(MetricLag))
+		assert.Equal(t, map[string]int64{"orders": 12, "payments": 0}, lagByTopic(MetricLagMax))
+	})
+
+	t.Run("topics no longer assigned drop to zero", func(t *testing.T) {
+		m.recordLag(summarizeLag(kadm.GroupLag{"payments": {0: {Lag: 3}}}))
+
+		assert.Equal(t, map[string]int64{"orders": 0, "payments": 3}, lagByTopic(MetricLag))
+	})
+}
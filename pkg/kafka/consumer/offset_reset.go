@@ -0,0 +1,356 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"go.uber.org/zap"
+)
+
+// Targets of an offset reset.
+const (
+	ResetToEarliest  = "earliest"
+	ResetToLatest    = "latest"
+	ResetToTimestamp = "timestamp"
+	ResetToOffsets   = "offsets"
+)
+
+var (
+	// ErrNotPaused is returned when an offset reset is requested for a running consumer.
+	ErrNotPaused = errors.New("consumer must be paused before its offsets are reset")
+
+	// ErrGroupActive is returned when other members are in the consumer group, typically other replicas.
+	ErrGroupActive = errors.New("consumer group has other active members")
+
+	// ErrRecordsInFlight is returned while messages fetched before the pause are still being processed.
+	ErrRecordsInFlight = errors.New("messages fetched before the pause are still being processed")
+
+	// ErrInvalidOffsetReset is returned for a malformed offset reset request.
+	ErrInvalidOffsetReset = errors.New("invalid offset reset")
+)
+
+// OffsetResetRequest selects the new offsets of the consumer group.
+type OffsetResetRequest struct {
+	To        string                     // ResetToEarliest, ResetToLatest, ResetToTimestamp or ResetToOffsets
+	Timestamp time.Time                  // First record at or after this time, for ResetToTimestamp
+	Offsets   map[string]map[int32]int64 // Offset per topic and partition, for ResetToOffsets
+	Topics    []string                   // Topics to reset, all consumed topics if empty; ignored for ResetToOffsets
+	DryRun    bool                       // Only compute the new offsets
+}
+
+// PartitionOffset is the change of the committed offset of a partition.
+type PartitionOffset struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	From      int64  `json:"from"` // Committed offset before the reset, -1 if none
+	To        int64  `json:"to"`
+}
+
+// OffsetResetResult lists the offsets that were, or on a dry run would be, committed.
+type OffsetResetResult struct {
+	Offsets []PartitionOffset `json:"offsets"`
+	DryRun  bool              `json:"dry_run"`
+}
+
+// OffsetResetter moves the committed offsets of a consumer group, e.g. to rebuild a projection.
+//
+// Kafka accepts offset commits only from group members, or from admins once the group is empty.
+// The resetter commits as the consumer's own member, so the consumer must be paused through its
+// Controller and must be the only member of the group: other replicas have to be stopped or scaled
+// down first. After the commit the consumer fetches from the new offsets once resumed. Messages
+// fetched before the pause are still processed and marked, which would commit over the reset, so
+// the reset is refused until they drained. Messages held by a consumer that its handler paused
+// don't hold back the reset and are dropped unmarked once it committed, e.g. to skip a poison
+// message. The groups of the retry tiers are not reset.
+type OffsetResetter struct {
+	client     *kgo.Client
+	admin      *kadm.Client
+	controller *Controller
+	inFlight   *InFlightCounter
+	groupID    string
+	log        *zap.Logger
+}
+
+// NewOffsetResetter creates an OffsetResetter for the consumer group of client, whose fetched
+// records are counted in inFlight.
+func NewOffsetResetter(
+	client *kgo.Client,
+	controller *Controller,
+	inFlight *InFlightCounter,
+	consumerConf config.ConsumerConfig,
+	log *zap.Logger,
+) *OffsetResetter {
+	return &OffsetResetter{
+		client:     client,
+		admin:      kadm.NewClient(client),
+		controller: controller,
+		inFlight:   inFlight,
+		groupID:    consumerConf.GroupID,
+		log:        log,
+	}
+}
+
+// Reset commits the offsets selected by req for the consumer group.
+func (r *OffsetResetter) Reset(ctx context.Context, req OffsetResetRequest) (OffsetResetResult, error) {
+	if r.controller.Status().State != StatePaused {
+		return OffsetResetResult{}, ErrNotPaused
+	}
+	if err := r.ensureDrained(); err != nil {
+		return OffsetResetResult{}, err
+	}
+	if err := r.ensureSoleMember(ctx); err != nil {
+		return OffsetResetResult{}, err
+	}
+
+	targets, err := r.targetOffsets(ctx, req)
+	if err != nil {
+		return OffsetResetResult{}, err
+	}
+	committed, err := r.admin.FetchOffsets(ctx, r.groupID)
+	if err == nil {
+		err = committed.Error()
+	}
+	if err != nil {
+		return OffsetResetResult{}, fmt.Errorf("failed to fetch committed offsets of group %s: %w", r.groupID, err)
+	}
+
+	result := OffsetResetResult{DryRun: req.DryRun}
+	for topic, partitions := range targets {
+		for partition, offset := range partitions {
+			from := int64(-1)
+			if c, ok := committed.Lookup(topic, partition); ok {
+				from = c.At
+			}
+			result.Offsets = append(result.Offsets, PartitionOffset{Topic: topic, Partition: partition, From: from, To: offset})
+		}
+	}
+	sort.Slice(result.Offsets, func(i, j int) bool {
+		a, b := result.Offsets[i], result.Offsets[j]
+		return a.Topic < b.Topic || (a.Topic == b.Topic && a.Partition < b.Partition)
+	})
+	if req.DryRun {
+		return result, nil
+	}
+
+	// Checked again right before the commit: nothing is fetched while paused, so none can follow.
+	if err = r.ensureDrained(); err != nil {
+		return OffsetResetResult{}, err
+	}
+	if err = r.commit(ctx, targets); err != nil {
+		return OffsetResetResult{}, err
+	}
+	r.inFlight.dropParked()
+	r.log.Warn("consumer group offsets reset",
+		zap.String("group_id", r.groupID),
+		zap.String("to", req.To),
+		zap.Int("partitions", len(result.Offsets)))
+	return result, nil
+}
+
+// ensureDrained checks that no message fetched before the pause is still waiting for its mark,
+// other than the parked ones.
+func (r *OffsetResetter) ensureDrained() error {
+	if n := r.inFlight.active(); n > 0 {
+		return fmt.Errorf("%w: %d messages, retry once they are done", ErrRecordsInFlight, n)
+	}
+	return nil
+}
+
+// ensureSoleMember checks that the consumer is the only member of its group.
+func (r *OffsetResetter) ensureSoleMember(ctx context.Context) error {
+	memberID, _ := r.client.GroupMetadata()
+	if memberID == "" {
+		return fmt.Errorf("consumer has not joined group %s yet", r.groupID)
+	}
+
+	groups, err := r.admin.DescribeGroups(ctx, r.groupID)
+	if err == nil {
+		err = groups.Error()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to describe group %s: %w", r.groupID, err)
+	}
+	group := groups[r.groupID]
+	if group.State != "Stable" {
+		return fmt.Errorf("%w: group %s is %s", ErrGroupActive, r.groupID, group.State)
+	}
+	for _, member := range group.Members {
+		if member.MemberID != memberID {
+			return fmt.Errorf("%w: group %s has %d members, stop the other replicas first",
+				ErrGroupActive, r.groupID, len(group.Members))
+		}
+	}
+	return nil
+}
+
+// targetOffsets resolves the request into an offset per partition.
+func (r *OffsetResetter) targetOffsets(ctx context.Context, req OffsetResetRequest) (map[string]map[int32]int64, error) {
+	if req.To == ResetToOffsets {
+		return r.explicitOffsets(ctx, req.Offsets)
+	}
+
+	topics, err := r.resetTopics(req.Topics)
+	if err != nil {
+		return nil, err
+	}
+
+	var listed kadm.ListedOffsets
+	switch req.To {
+	case ResetToEarliest:
+		listed, err = r.admin.ListStartOffsets(ctx, topics...)
+	case ResetToLatest:
+		listed, err = r.admin.ListEndOffsets(ctx, topics...)
+	case ResetToTimestamp:
+		if req.Timestamp.IsZero() {
+			return nil, fmt.Errorf("%w: timestamp is required", ErrInvalidOffsetReset)
+		}
+		listed, err = r.offsetsAfter(ctx, req.Timestamp, topics)
+	default:
+		return nil, fmt.Errorf("%w: unknown target %q, use %s, %s, %s or %s", ErrInvalidOffsetReset,
+			req.To, ResetToEarliest, ResetToLatest, ResetToTimestamp, ResetToOffsets)
+	}
+	if err == nil {
+		err = listed.Error()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets of %v: %w", topics, err)
+	}
+
+	targets := make(map[string]map[int32]int64)
+	listed.Each(func(o kadm.ListedOffset) {
+		if targets[o.Topic] == nil {
+			targets[o.Topic] = make(map[int32]int64)
+		}
+		targets[o.Topic][o.Partition] = o.Offset
+	})
+	return targets, nil
+}
+
+// offsetsAfter returns the offset of the first record at or after ts, or the end offset for
+// partitions without such a record.
+func (r *OffsetResetter) offsetsAfter(ctx context.Context, ts time.Time, topics []string) (kadm.ListedOffsets, error) {
+	after, err := r.admin.ListOffsetsAfterMilli(ctx, ts.UnixMilli(), topics...)
+	if err != nil {
+		return nil, err
+	}
+	ends, err := r.admin.ListEndOffsets(ctx, topics...)
+	if err != nil {
+		return nil, err
+	}
+	for topic, partitions := range after {
+		for partition, o := range partitions {
+			if o.Err != nil || o.Offset >= 0 {
+				continue
+			}
+			if end, ok := ends.Lookup(topic, partition); ok {
+				partitions[partition] = end
+			}
+		}
+	}
+	return after, nil
+}
+
+// explicitOffsets validates explicit offsets against the consumed topics and the partition bounds.
+func (r *OffsetResetter) explicitOffsets(ctx context.Context, offsets map[string]map[int32]int64) (map[string]map[int32]int64, error) {
+	if len(offsets) == 0 {
+		return nil, fmt.Errorf("%w: offsets are required", ErrInvalidOffsetReset)
+	}
+	topics := make([]string, 0, len(offsets))
+	for topic := range offsets {
+		topics = append(topics, topic)
+	}
+	if _, err := r.resetTopics(topics); err != nil {
+		return nil, err
+	}
+
+	starts, err := r.admin.ListStartOffsets(ctx, topics...)
+	if err == nil {
+		err = starts.Error()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list start offsets of %v: %w", topics, err)
+	}
+	ends, err := r.admin.ListEndOffsets(ctx, topics...)
+	if err == nil {
+		err = ends.Error()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list end offsets of %v: %w", topics, err)
+	}
+
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			start, okStart := starts.Lookup(topic, partition)
+			end, okEnd := ends.Lookup(topic, partition)
+			if !okStart || !okEnd {
+				return nil, fmt.Errorf("%w: partition %d of %s does not exist", ErrInvalidOffsetReset, partition, topic)
+			}
+			if offset < start.Offset || offset > end.Offset {
+				return nil, fmt.Errorf("%w: offset %d of %s/%d is outside [%d, %d]", ErrInvalidOffsetReset,
+					offset, topic, partition, start.Offset, end.Offset)
+			}
+		}
+	}
+	return offsets, nil
+}
+
+// resetTopics returns the topics to reset, which must be consumed by the client.
+func (r *OffsetResetter) resetTopics(requested []string) ([]string, error) {
+	consumed := r.client.GetConsumeTopics()
+	if len(requested) == 0 {
+		if len(consumed) == 0 {
+			return nil, fmt.Errorf("%w: the consumer has no topics yet", ErrInvalidOffsetReset)
+		}
+		return consumed, nil
+	}
+	for _, topic := range requested {
+		if !slices.Contains(consumed, topic) {
+			return nil, fmt.Errorf("%w: topic %s is not consumed by group %s", ErrInvalidOffsetReset, topic, r.groupID)
+		}
+	}
+	return requested, nil
+}
+
+// commit commits targets as the consumer's group member and moves its fetch positions to them.
+func (r *OffsetResetter) commit(ctx context.Context, targets map[string]map[int32]int64) error {
+	offsets := make(map[string]map[int32]kgo.EpochOffset, len(targets))
+	for topic, partitions := range targets {
+		offsets[topic] = make(map[int32]kgo.EpochOffset, len(partitions))
+		for partition, offset := range partitions {
+			offsets[topic][partition] = kgo.EpochOffset{Epoch: -1, Offset: offset}
+		}
+	}
+
+	var commitErr error
+	r.client.CommitOffsetsSync(ctx, offsets, func(cl *kgo.Client, _ *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error) {
+		if err != nil {
+			commitErr = err
+			return
+		}
+		for _, topic := range resp.Topics {
+			for _, partition := range topic.Partitions {
+				if codeErr := kerr.ErrorForCode(partition.ErrorCode); codeErr != nil {
+					commitErr = errors.Join(commitErr, fmt.Errorf("%s/%d: %w", topic.Topic, partition.Partition, codeErr))
+				}
+			}
+		}
+		if commitErr == nil {
+			// SetOffsets must not run concurrently with commits: autocommits and other commits
+			// are blocked until this callback returns.
+			cl.SetOffsets(offsets)
+		}
+	})
+	if commitErr != nil {
+		return fmt.Errorf("failed to commit offsets of group %s: %w", r.groupID, commitErr)
+	}
+	return nil
+}
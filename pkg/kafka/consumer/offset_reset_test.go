@@ -0,0 +1,90 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOffsetResetter_RequiresPause(t *testing.T) {
+	client := newTestClient(t, "orders")
+	c := newTestController(t, client, newMockStateReporter())
+	r := NewOffsetResetter(client, c, NewInFlightCounter(), createTestConsumerConfig(), zap.NewNop())
+
+	_, err := r.Reset(context.Background(), OffsetResetRequest{To: ResetToEarliest})
+	assert.ErrorIs(t, err, ErrNotPaused)
+}
+
+func TestOffsetResetter_RequiresDrain(t *testing.T) {
+	client := newTestClient(t, "orders")
+	c := newTestController(t, client, newMockStateReporter())
+	inFlight := NewInFlightCounter()
+	r := NewOffsetResetter(client, c, inFlight, createTestConsumerConfig(), zap.NewNop())
+	c.Pause()
+
+	inFlight.add()
+	_, err := r.Reset(context.Background(), OffsetResetRequest{To: ResetToEarliest})
+	assert.ErrorIs(t, err, ErrRecordsInFlight)
+
+	inFlight.done()
+	assert.NoError(t, r.ensureDrained())
+}
+
+func TestOffsetResetter_DropsParkedMessages(t *testing.T) {
+	client := newTestClient(t, "orders")
+	c := newTestController(t, client, newMockStateReporter())
+	inFlight := NewInFlightCounter()
+	r := NewOffsetResetter(client, c, inFlight, createTestConsumerConfig(), zap.NewNop())
+
+	handler := &mockHandler{processFunc: func(context.Context, proto.Message) error {
+		return fmt.Errorf("poison message: %w", ErrPauseConsumer)
+	}}
+	marker := &mockOffsetMarker{}
+	rh := &ResultHandler{log: zap.NewNop(), dlqHandler: &mockDLQHandler{}, offsetMarker: marker, inFlight: inFlight}
+	p := NewProcessor(make(chan *MessageEnvelope), handler, zap.NewNop(), rh, newMockTracer(), nil, c, nil, createTestConsumerConfig())
+
+	inFlight.add()
+	done := make(chan error, 1)
+	go func() { done <- p.processMessage(context.Background(), newTestEnvelope(0, 1)) }()
+
+	// The handler paused the consumer on the poison message, which does not hold back the reset.
+	require.Eventually(t, func() bool { return inFlight.active() == 0 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, r.ensureDrained())
+
+	// Once the reset committed, the message is dropped without being marked or processed again.
+	inFlight.dropParked()
+	require.NoError(t, <-done)
+	c.Resume()
+
+	assert.Empty(t, marker.markedRecords)
+	assert.Equal(t, int32(1), handler.callCount.Load())
+	assert.Zero(t, inFlight.count())
+}
+
+func TestOffsetResetter_ResetTopics(t *testing.T) {
+	client := newTestClient(t, "orders", "payments")
+	r := NewOffsetResetter(client, newTestController(t, client, newMockStateReporter()), nil, createTestConsumerConfig(), zap.NewNop())
+
+	t.Run("defaults to the consumed topics", func(t *testing.T) {
+		topics, err := r.resetTopics(nil)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"orders", "payments"}, topics)
+	})
+
+	t.Run("accepts a subset", func(t *testing.T) {
+		topics, err := r.resetTopics([]string{"orders"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"orders"}, topics)
+	})
+
+	t.Run("rejects topics not consumed", func(t *testing.T) {
+		_, err := r.resetTopics([]string{"inventory"})
+		assert.ErrorIs(t, err, ErrInvalidOffsetReset)
+	})
+}
@@ -0,0 +1,82 @@
+package consumer
+
+import (
+	"sync"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// offsetTracker marks offsets of concurrently processed records only up to the lowest
+// contiguous completed offset per partition, so a crash never skips a message that is
+// still in flight on another worker.
+type offsetTracker struct {
+	marker offsetMarker
+
+	mu         sync.Mutex
+	partitions map[topicPartition]*partitionOffsets
+}
+
+// partitionOffsets holds the records of a partition that were dispatched but not marked yet.
+type partitionOffsets struct {
+	inFlight  []*kgo.Record // in offset order
+	completed map[int64]bool
+}
+
+func newOffsetTracker(marker offsetMarker) *offsetTracker {
+	return &offsetTracker{
+		marker:     marker,
+		partitions: make(map[topicPartition]*partitionOffsets),
+	}
+}
+
+// track registers a record before it is handed to a worker. Records of a partition
+// must be tracked in offset order.
+func (t *offsetTracker) track(record *kgo.Record) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tp := topicPartition{topic: record.Topic, partition: record.Partition}
+	p, ok := t.partitions[tp]
+	// A record at or below the last tracked offset means the partition was reassigned and
+	// is consumed again from the committed offset; the old in-flight records are stale.
+	if !ok || (len(p.inFlight) > 0 && record.Offset <= p.inFlight[len(p.inFlight)-1].Offset) {
+		p = &partitionOffsets{completed: make(map[int64]bool)}
+		t.partitions[tp] = p
+	}
+	p.inFlight = append(p.inFlight, record)
+}
+
+// MarkCommitRecords implements offsetMarker. It records the completion and marks the
+// highest record below which all tracked records are completed. Untracked records are
+// marked right away.
+func (t *offsetTracker) MarkCommitRecords(records ...*kgo.Record) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, record := range records {
+		p, ok := t.partitions[topicPartition{topic: record.Topic, partition: record.Partition}]
+		if !ok || len(p.inFlight) == 0 || record.Offset < p.inFlight[0].Offset {
+			t.marker.MarkCommitRecords(record)
+			continue
+		}
+		p.completed[record.Offset] = true
+
+		var contiguous *kgo.Record
+		for len(p.inFlight) > 0 && p.completed[p.inFlight[0].Offset] {
+			contiguous = p.inFlight[0]
+			delete(p.completed, contiguous.Offset)
+			p.inFlight = p.inFlight[1:]
+		}
+		if contiguous != nil {
+			t.marker.MarkCommitRecords(contiguous)
+		}
+	}
+}
+
+// discard forgets the records of the partition of record, which was dropped by an offset reset.
+func (t *offsetTracker) discard(record *kgo.Record) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.partitions, topicPartition{topic: record.Topic, partition: record.Partition})
+}
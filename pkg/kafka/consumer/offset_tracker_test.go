@@ -0,0 +1,87 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func newTrackedRecord(partition int32, offset int64) *kgo.Record {
+	return &kgo.Record{Topic: "test-topic", Partition: partition, Offset: offset}
+}
+
+func markedOffsets(marker *mockOffsetMarker) []int64 {
+	offsets := make([]int64, 0, len(marker.markedRecords))
+	for _, r := range marker.markedRecords {
+		offsets = append(offsets, r.Offset)
+	}
+	return offsets
+}
+
+func TestOffsetTracker(t *testing.T) {
+	t.Run("marks only the lowest contiguous completed offset", func(t *testing.T) {
+		marker := &mockOffsetMarker{}
+		tracker := newOffsetTracker(marker)
+		records := []*kgo.Record{newTrackedRecord(0, 10), newTrackedRecord(0, 11), newTrackedRecord(0, 12)}
+		for _, r := range records {
+			tracker.track(r)
+		}
+
+		tracker.MarkCommitRecords(records[2])
+		tracker.MarkCommitRecords(records[1])
+		assert.Empty(t, marker.markedRecords, "offset 10 is still in flight")
+
+		tracker.MarkCommitRecords(records[0])
+		assert.Equal(t, []int64{12}, markedOffsets(marker))
+	})
+
+	t.Run("tracks partitions independently", func(t *testing.T) {
+		marker := &mockOffsetMarker{}
+		tracker := newOffsetTracker(marker)
+		slow, fast := newTrackedRecord(0, 5), newTrackedRecord(1, 7)
+		tracker.track(slow)
+		tracker.track(fast)
+
+		tracker.MarkCommitRecords(fast)
+
+		assert.Equal(t, []int64{7}, markedOffsets(marker))
+	})
+
+	t.Run("starts over when a partition is consumed again", func(t *testing.T) {
+		marker := &mockOffsetMarker{}
+		tracker := newOffsetTracker(marker)
+		tracker.track(newTrackedRecord(0, 20))
+		tracker.track(newTrackedRecord(0, 21))
+
+		// Reassigned partition is fetched again from the committed offset.
+		again := newTrackedRecord(0, 20)
+		tracker.track(again)
+		tracker.MarkCommitRecords(again)
+
+		assert.Equal(t, []int64{20}, markedOffsets(marker))
+	})
+
+	t.Run("marks untracked records right away", func(t *testing.T) {
+		marker := &mockOffsetMarker{}
+		tracker := newOffsetTracker(marker)
+
+		tracker.MarkCommitRecords(newTrackedRecord(3, 1))
+
+		assert.Equal(t, []int64{1}, markedOffsets(marker))
+	})
+
+	t.Run("discards the records completed behind a dropped record", func(t *testing.T) {
+		marker := &mockOffsetMarker{}
+		tracker := newOffsetTracker(marker)
+		dropped, completed := newTrackedRecord(0, 10), newTrackedRecord(0, 11)
+		tracker.track(dropped)
+		tracker.track(completed)
+		tracker.MarkCommitRecords(completed)
+
+		tracker.discard(dropped)
+		tracker.track(newTrackedRecord(0, 50))
+
+		assert.Empty(t, marker.markedRecords)
+	})
+}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
 	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
 	"github.com/cenkalti/backoff/v4"
+	"go.opentelemetry.io/otel/codes"
 	"go.uber.org/zap"
-	"google.golang.org/protobuf/proto"
 )
 
 // PanicError represents an error that occurred due to panic.
@@ -24,12 +27,33 @@ func (e *panicError) Error() string {
 	return fmt.Sprintf("panic: %v", e.Panic)
 }
 
+// Processor hands envelopes to the Handler. With a concurrency above one, envelopes are
+// sharded by record key or partition across that many workers, which keeps the order
+// per shard, and offsets are marked only once all earlier records of the partition completed.
+//
+// Handler errors are classified by the ErrorClassifier of the handler. On ErrPauseConsumer the
+// processor pauses the consumer and processes the message again once resumed; on ErrCrashConsumer
+// Run returns the error without marking the message.
 type Processor struct {
 	envelopeChan  <-chan *MessageEnvelope
 	handler       Handler
+	classifier    ErrorClassifier
 	log           *zap.Logger
 	resultHandler *ResultHandler
 	tracer        MessageTracer
+	inbox         Inbox
+	controller    *Controller
+	metrics       *Metrics
+	consumerName  string
+
+	// Concurrency configuration
+	concurrency      int
+	shardByPartition bool
+	shardBufferSize  int
+	offsets          *offsetTracker
+
+	// waitForRetryDelay makes the processor wait for the retry.not_before header; set for retry tiers.
+	waitForRetryDelay bool
 
 	// Retry configuration
 	maxRetries        uint64
@@ -44,52 +68,188 @@ func NewProcessor(
 	log *zap.Logger,
 	resultHandler *ResultHandler,
 	tracer MessageTracer,
+	inbox Inbox,
+	controller *Controller,
+	metrics *Metrics,
 	consumerConf config.ConsumerConfig,
 ) *Processor {
-	return &Processor{
+	p := &Processor{
 		envelopeChan:      envelopeChan,
 		handler:           handler,
+		classifier:        errorClassifierFor(handler),
 		log:               log,
 		resultHandler:     resultHandler,
 		tracer:            tracer,
+		inbox:             inbox,
+		controller:        controller,
+		metrics:           metrics,
+		consumerName:      consumerConf.Name,
+		concurrency:       max(consumerConf.Concurrency, 1),
+		shardByPartition:  consumerConf.ShardBy == config.ShardByPartition,
+		shardBufferSize:   consumerConf.ChannelBufferSize,
 		maxRetries:        uint64(*consumerConf.MaxRetries),
 		initialBackoff:    consumerConf.InitialBackoff,
 		maxBackoff:        consumerConf.MaxBackoff,
 		processingTimeout: consumerConf.ProcessingTimeout,
 	}
+	if consumerConf.RetryTopics.Enabled {
+		// Failed messages are retried through retry topics instead of in-process backoff.
+		p.maxRetries = 0
+	}
+	if p.concurrency > 1 {
+		p.offsets = newOffsetTracker(resultHandler.offsetMarker)
+		p.resultHandler = resultHandler.withOffsetMarker(p.offsets)
+	}
+	return p
 }
 
 func (p *Processor) Run(ctx context.Context) error {
+	if p.concurrency > 1 {
+		return p.runSharded(ctx)
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case envelope := <-p.envelopeChan:
-			p.processMessage(ctx, envelope)
+			if err := p.processMessage(ctx, envelope); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// runSharded dispatches envelopes to concurrency workers and waits for them on shutdown.
+// A worker crashing the consumer stops all workers.
+func (p *Processor) runSharded(parent context.Context) error {
+	ctx, cancel := context.WithCancelCause(parent)
+	defer cancel(nil)
+
+	shards := make([]chan *MessageEnvelope, p.concurrency)
+	var wg sync.WaitGroup
+	for i := range shards {
+		shards[i] = make(chan *MessageEnvelope, p.shardBufferSize)
+		wg.Add(1)
+		go func(shard <-chan *MessageEnvelope) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case envelope := <-shard:
+					if err := p.processMessage(ctx, envelope); err != nil {
+						cancel(err)
+						return
+					}
+				}
+			}
+		}(shards[i])
+	}
+
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case envelope := <-p.envelopeChan:
+			p.offsets.track(envelope.Record)
+			select {
+			case <-ctx.Done():
+			case shards[p.shardOf(envelope)] <- envelope:
+			}
 		}
 	}
+	wg.Wait()
+
+	if err := context.Cause(ctx); errors.Is(err, ErrCrashConsumer) {
+		return err
+	}
+	return nil
 }
 
-func (p *Processor) processMessage(ctx context.Context, envelope *MessageEnvelope) {
+// shardOf returns the worker index for an envelope. Records without a key are sharded by partition.
+func (p *Processor) shardOf(envelope *MessageEnvelope) int {
+	record := envelope.Record
+	if p.shardByPartition || len(record.Key) == 0 {
+		return int(record.Partition) % p.concurrency
+	}
+	h := fnv.New32a()
+	_, _ = h.Write(record.Key)
+	return int(h.Sum32() % uint32(p.concurrency)) //nolint:gosec // concurrency is validated to be at most 256
+}
+
+// processMessage processes an envelope and returns an error only when the consumer must crash.
+func (p *Processor) processMessage(ctx context.Context, envelope *MessageEnvelope) error {
+	if p.waitForRetryDelay && !waitUntilDue(ctx, envelope.Record) {
+		// Shutting down; the record is not marked and is consumed again after restart.
+		return nil
+	}
+	if !p.controller.acquire(ctx) {
+		return nil
+	}
+	start := time.Now()
+
 	// Витягуємо trace context з Kafka headers
 	ctx = p.tracer.ExtractContext(ctx, envelope.Record)
 
 	// Витягуємо tenant context з Kafka headers
 	ctx = tenant.ContextFromKafkaHeaders(ctx, envelope.Record.Headers)
+	ctx = contextWithRecord(ctx, envelope.Record)
 
 	// Створюємо span для обробки повідомлення
 	ctx, span := p.tracer.StartConsumerSpan(ctx, envelope.Record)
 	defer span.End()
 
-	// Обробляємо повідомлення з retry логікою
-	err := p.executeWithRetry(ctx, envelope.Event)
+	// Пропускаємо вже оброблені події, потім обробляємо повідомлення з retry логікою
+	err := p.skipProcessed(ctx, envelope)
+	if err == nil {
+		err = p.executeWithRetry(ctx, envelope)
+	}
+
+	// Призупиняємо consumer і повторюємо повідомлення після відновлення
+	for p.controller != nil && errors.Is(err, ErrPauseConsumer) {
+		p.log.Error("pausing consumer on message processing error", p.resultHandler.recordFieldsWithError(envelope.Record, err)...)
+		span.RecordError(err)
+		p.controller.Pause()
+		if !p.resultHandler.park(ctx, p.controller, envelope.Record) {
+			// Shutting down, or the offsets were reset past the record; it is not marked.
+			return nil
+		}
+		err = p.executeWithRetry(ctx, envelope)
+	}
+
+	if errors.Is(err, ErrCrashConsumer) {
+		// The record is not marked and is consumed again after restart.
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "message processing failed - crashing consumer")
+		return fmt.Errorf("consumer %s crashed on %s/%d@%d: %w", p.consumerName,
+			envelope.Record.Topic, envelope.Record.Partition, envelope.Record.Offset, err)
+	}
 
 	// Класифікуємо результат та застосовуємо відповідну стратегію
-	p.resultHandler.handle(ctx, err, envelope.Record, span)
+	outcome := p.resultHandler.handle(ctx, err, envelope.Record, span)
+	p.metrics.recordProcessed(ctx, envelope.Record.Topic, outcome, time.Since(start))
+	return nil
+}
+
+// skipProcessed returns an error wrapping ErrSkipMessage if the inbox already holds the event.
+// An unavailable inbox is logged and the message is processed.
+func (p *Processor) skipProcessed(ctx context.Context, envelope *MessageEnvelope) error {
+	eventID := headerValue(envelope.Record, HeaderEventID)
+	if p.inbox == nil || eventID == "" {
+		return nil
+	}
+	seen, err := p.inbox.Seen(ctx, p.consumerName, eventID)
+	if err != nil {
+		p.log.Warn("failed to check inbox, processing message", zap.String("event_id", eventID), zap.Error(err))
+		return nil
+	}
+	if seen {
+		return fmt.Errorf("event %s already processed: %w", eventID, ErrSkipMessage)
+	}
+	return nil
 }
 
 // executeWithRetry executes the handler with exponential backoff retry logic.
-func (p *Processor) executeWithRetry(ctx context.Context, event proto.Message) error {
+func (p *Processor) executeWithRetry(ctx context.Context, envelope *MessageEnvelope) error {
 	expBackoff := backoff.NewExponentialBackOff(
 		backoff.WithInitialInterval(p.initialBackoff),
 		backoff.WithMaxInterval(p.maxBackoff),
@@ -104,14 +264,17 @@ func (p *Processor) executeWithRetry(ctx context.Context, event proto.Message) e
 	var attempt uint64
 	return backoff.Retry(func() error {
 		attempt++
-		err := p.process(ctx, event)
+		if attempt > 1 {
+			p.metrics.recordRetry(ctx, envelope.Record.Topic)
+		}
+		err := classifyError(p.classifier, p.process(ctx, envelope))
 
 		if err == nil {
 			return nil
 		}
 
-		// Skip and permanent errors should not be retried
-		if errors.Is(err, ErrSkipMessage) || errors.Is(err, ErrPermanent) {
+		// Skip, permanent, pause and crash errors should not be retried
+		if isControlError(err) {
 			return backoff.Permanent(err)
 		}
 
@@ -133,7 +296,7 @@ func (p *Processor) executeWithRetry(ctx context.Context, event proto.Message) e
 }
 
 // process executes the handler with panic recovery.
-func (p *Processor) process(ctx context.Context, event proto.Message) (err error) {
+func (p *Processor) process(ctx context.Context, envelope *MessageEnvelope) (err error) {
 	// Apply processing timeout to prevent hanging
 	ctx, cancel := context.WithTimeout(ctx, p.processingTimeout)
 	defer cancel()
@@ -152,5 +315,37 @@ func (p *Processor) process(ctx context.Context, event proto.Message) (err error
 		}
 	}()
 
-	return p.handler.Process(ctx, event)
+	return p.handle(ctx, envelope)
+}
+
+// handle calls the handler and records the event in the inbox once it succeeded, in the same
+// transaction for a TransactionalHandler.
+func (p *Processor) handle(ctx context.Context, envelope *MessageEnvelope) error {
+	eventID := headerValue(envelope.Record, HeaderEventID)
+	if p.inbox == nil || eventID == "" {
+		return p.handler.Process(ctx, envelope.Event)
+	}
+
+	var txManager mongo.TxManager
+	if txHandler, ok := p.handler.(TransactionalHandler); ok {
+		txManager = txHandler.TxManager()
+	}
+	if txManager == nil {
+		if err := p.handler.Process(ctx, envelope.Event); err != nil {
+			return err
+		}
+		if err := p.inbox.Record(ctx, p.consumerName, eventID, envelope.Record.Topic); err != nil && !errors.Is(err, ErrSkipMessage) {
+			// The event was applied; at worst a redelivery is processed again.
+			p.log.Warn("failed to record event in inbox", zap.String("event_id", eventID), zap.Error(err))
+		}
+		return nil
+	}
+
+	_, err := txManager.WithTransaction(ctx, func(txCtx context.Context) (any, error) {
+		if err := p.handler.Process(txCtx, envelope.Event); err != nil {
+			return nil, err
+		}
+		return nil, p.inbox.Record(txCtx, p.consumerName, eventID, envelope.Record.Topic)
+	})
+	return err
 }
@@ -3,11 +3,14 @@ package consumer
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/twmb/franz-go/pkg/kgo"
@@ -16,6 +19,7 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 // mockHandler is a test implementation of Handler
@@ -37,6 +41,7 @@ type mockTracer struct {
 	extractContextFunc    func(ctx context.Context, record *kgo.Record) context.Context
 	startConsumerSpanFunc func(ctx context.Context, record *kgo.Record) (context.Context, trace.Span)
 	startDLQSpanFunc      func(ctx context.Context, record *kgo.Record, dlqTopic string) (context.Context, trace.Span)
+	startRetrySpanFunc    func(ctx context.Context, record *kgo.Record, retryTopic string) (context.Context, trace.Span)
 	injectContextFunc     func(ctx context.Context, record *kgo.Record)
 }
 
@@ -53,6 +58,10 @@ func newMockTracer() *mockTracer {
 			_, span := noop.NewTracerProvider().Tracer("test").Start(ctx, "dlq")
 			return ctx, span
 		},
+		startRetrySpanFunc: func(ctx context.Context, record *kgo.Record, retryTopic string) (context.Context, trace.Span) {
+			_, span := noop.NewTracerProvider().Tracer("test").Start(ctx, "retry")
+			return ctx, span
+		},
 		injectContextFunc: func(ctx context.Context, record *kgo.Record) {},
 	}
 }
@@ -69,6 +78,10 @@ func (m *mockTracer) StartDLQSpan(ctx context.Context, record *kgo.Record, dlqTo
 	return m.startDLQSpanFunc(ctx, record, dlqTopic)
 }
 
+func (m *mockTracer) StartRetrySpan(ctx context.Context, record *kgo.Record, retryTopic string) (context.Context, trace.Span) {
+	return m.startRetrySpanFunc(ctx, record, retryTopic)
+}
+
 func (m *mockTracer) InjectContext(ctx context.Context, record *kgo.Record) {
 	m.injectContextFunc(ctx, record)
 }
@@ -111,7 +124,7 @@ func TestNewProcessor(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(envelopeChan, handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(envelopeChan, handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
 		assert.NotNil(t, p)
 		assert.Equal(t, uint64(4), p.maxRetries) // maxRetries = MaxRetries directly
@@ -119,6 +132,16 @@ func TestNewProcessor(t *testing.T) {
 		assert.Equal(t, 50*time.Millisecond, p.maxBackoff)
 		assert.Equal(t, 1*time.Second, p.processingTimeout)
 	})
+
+	t.Run("disables in-process retries when retry topics are enabled", func(t *testing.T) {
+		conf := createTestConsumerConfig()
+		conf.RetryTopics = config.RetryTopicsConfig{Enabled: true, Delays: []time.Duration{time.Minute}}
+
+		p := NewProcessor(make(chan *MessageEnvelope), &mockHandler{}, zap.NewNop(), &ResultHandler{log: zap.NewNop()}, newMockTracer(), nil, nil, nil, conf)
+
+		assert.Equal(t, uint64(0), p.maxRetries)
+		assert.False(t, p.waitForRetryDelay)
+	})
 }
 
 func TestProcessor_Run(t *testing.T) {
@@ -131,7 +154,7 @@ func TestProcessor_Run(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(envelopeChan, handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(envelopeChan, handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
 		ctx, cancel := context.WithCancel(context.Background())
 
@@ -151,6 +174,89 @@ func TestProcessor_Run(t *testing.T) {
 	})
 }
 
+// syncOffsetMarker is a concurrency-safe offsetMarker for tests with multiple workers
+type syncOffsetMarker struct {
+	mu      sync.Mutex
+	offsets []int64
+}
+
+func (m *syncOffsetMarker) MarkCommitRecords(records ...*kgo.Record) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range records {
+		m.offsets = append(m.offsets, r.Offset)
+	}
+}
+
+func (m *syncOffsetMarker) marked() []int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]int64(nil), m.offsets...)
+}
+
+func TestProcessor_RunSharded(t *testing.T) {
+	newShardedProcessor := func(handler Handler, envelopeChan chan *MessageEnvelope, marker offsetMarker) *Processor {
+		log := zap.NewNop()
+		conf := createTestConsumerConfig()
+		conf.Concurrency = 4
+		conf.ShardBy = config.ShardByKey
+		conf.ChannelBufferSize = 10
+		resultHandler := &ResultHandler{log: log, dlqHandler: &mockDLQHandler{}, offsetMarker: marker}
+		return NewProcessor(envelopeChan, handler, log, resultHandler, newMockTracer(), nil, nil, nil, conf)
+	}
+
+	newKeyedEnvelope := func(key string, offset int64) *MessageEnvelope {
+		return &MessageEnvelope{
+			Event:  wrapperspb.String(key),
+			Record: &kgo.Record{Topic: "test-topic", Partition: 0, Offset: offset, Key: []byte(key)},
+		}
+	}
+
+	t.Run("same key goes to the same worker", func(t *testing.T) {
+		p := newShardedProcessor(&mockHandler{}, make(chan *MessageEnvelope), &syncOffsetMarker{})
+
+		assert.Equal(t, p.shardOf(newKeyedEnvelope("order-1", 1)), p.shardOf(newKeyedEnvelope("order-1", 2)))
+		assert.NotEqual(t, p.shardOf(newKeyedEnvelope("slow", 1)), p.shardOf(newKeyedEnvelope("fast", 2)))
+	})
+
+	t.Run("slow key does not block other keys but holds back the offset", func(t *testing.T) {
+		release := make(chan struct{})
+		var completed atomic.Int32
+		handler := &mockHandler{
+			processFunc: func(ctx context.Context, event proto.Message) error {
+				if v, ok := event.(*wrapperspb.StringValue); ok && v.GetValue() == "slow" {
+					<-release
+				}
+				completed.Add(1)
+				return nil
+			},
+		}
+		envelopeChan := make(chan *MessageEnvelope, 3)
+		marker := &syncOffsetMarker{}
+		p := newShardedProcessor(handler, envelopeChan, marker)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- p.Run(ctx)
+		}()
+
+		envelopeChan <- newKeyedEnvelope("fast", 1)
+		envelopeChan <- newKeyedEnvelope("slow", 2)
+		envelopeChan <- newKeyedEnvelope("fast", 3)
+
+		require.Eventually(t, func() bool { return completed.Load() == 2 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, []int64{1}, marker.marked(), "offset 3 must wait for offset 2")
+
+		close(release)
+		require.Eventually(t, func() bool { return len(marker.marked()) == 2 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, []int64{1, 3}, marker.marked())
+
+		cancel()
+		assert.NoError(t, <-done)
+	})
+}
+
 func TestProcessor_ExecuteWithRetry(t *testing.T) {
 	t.Run("succeeds on first attempt", func(t *testing.T) {
 		handler := &mockHandler{
@@ -164,9 +270,9 @@ func TestProcessor_ExecuteWithRetry(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
-		err := p.executeWithRetry(context.Background(), &emptypb.Empty{})
+		err := p.executeWithRetry(context.Background(), newTestEnvelope(0, 1))
 
 		assert.NoError(t, err)
 		assert.Equal(t, int32(1), handler.callCount.Load())
@@ -189,9 +295,9 @@ func TestProcessor_ExecuteWithRetry(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
-		err := p.executeWithRetry(context.Background(), &emptypb.Empty{})
+		err := p.executeWithRetry(context.Background(), newTestEnvelope(0, 1))
 
 		assert.NoError(t, err)
 		assert.Equal(t, int32(3), attempts.Load())
@@ -210,9 +316,9 @@ func TestProcessor_ExecuteWithRetry(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
-		err := p.executeWithRetry(context.Background(), &emptypb.Empty{})
+		err := p.executeWithRetry(context.Background(), newTestEnvelope(0, 1))
 
 		assert.Error(t, err)
 		assert.Equal(t, int32(3), handler.callCount.Load()) // 1 attempt + 2 retries = 3 total
@@ -230,9 +336,9 @@ func TestProcessor_ExecuteWithRetry(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
-		err := p.executeWithRetry(context.Background(), &emptypb.Empty{})
+		err := p.executeWithRetry(context.Background(), newTestEnvelope(0, 1))
 
 		assert.ErrorIs(t, err, ErrSkipMessage)
 		assert.Equal(t, int32(1), handler.callCount.Load())
@@ -250,9 +356,9 @@ func TestProcessor_ExecuteWithRetry(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
-		err := p.executeWithRetry(context.Background(), &emptypb.Empty{})
+		err := p.executeWithRetry(context.Background(), newTestEnvelope(0, 1))
 
 		assert.ErrorIs(t, err, ErrPermanent)
 		assert.Equal(t, int32(1), handler.callCount.Load())
@@ -271,12 +377,12 @@ func TestProcessor_ExecuteWithRetry(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel() // Cancel immediately
 
-		err := p.executeWithRetry(ctx, &emptypb.Empty{})
+		err := p.executeWithRetry(ctx, newTestEnvelope(0, 1))
 
 		// Should return quickly with context error
 		assert.Error(t, err)
@@ -296,9 +402,9 @@ func TestProcessor_Process(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
-		err := p.process(context.Background(), &emptypb.Empty{})
+		err := p.process(context.Background(), newTestEnvelope(0, 1))
 
 		assert.NoError(t, err)
 	})
@@ -316,9 +422,9 @@ func TestProcessor_Process(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
-		err := p.process(context.Background(), &emptypb.Empty{})
+		err := p.process(context.Background(), newTestEnvelope(0, 1))
 
 		assert.ErrorIs(t, err, expectedErr)
 	})
@@ -335,9 +441,9 @@ func TestProcessor_Process(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
-		err := p.process(context.Background(), &emptypb.Empty{})
+		err := p.process(context.Background(), newTestEnvelope(0, 1))
 
 		require.Error(t, err)
 		assert.ErrorIs(t, err, ErrPermanent)
@@ -358,10 +464,10 @@ func TestProcessor_Process(t *testing.T) {
 
 		resultHandler := &ResultHandler{log: log}
 
-		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, conf)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, log, resultHandler, tracer, nil, nil, nil, conf)
 
 		start := time.Now()
-		err := p.process(context.Background(), &emptypb.Empty{})
+		err := p.process(context.Background(), newTestEnvelope(0, 1))
 		elapsed := time.Since(start)
 
 		assert.Error(t, err)
@@ -406,10 +512,10 @@ func TestProcessor_ProcessMessage(t *testing.T) {
 		// Create processor
 		envelopeChan := make(chan *MessageEnvelope, 1)
 		rh := &ResultHandler{log: log}
-		p := NewProcessor(envelopeChan, handler, log, rh, tracer, conf)
+		p := NewProcessor(envelopeChan, handler, log, rh, tracer, nil, nil, nil, conf)
 
 		// Just test handler was called via executeWithRetry
-		err := p.executeWithRetry(context.Background(), &emptypb.Empty{})
+		err := p.executeWithRetry(context.Background(), newTestEnvelope(0, 1))
 
 		assert.True(t, handlerCalled.Load())
 		assert.NoError(t, err)
@@ -427,10 +533,185 @@ func TestProcessor_ProcessMessage(t *testing.T) {
 		conf := createTestConsumerConfig()
 
 		rh := &ResultHandler{log: log}
-		p := NewProcessor(make(chan *MessageEnvelope), handler, log, rh, tracer, conf)
+		p := NewProcessor(make(chan *MessageEnvelope), handler, log, rh, tracer, nil, nil, nil, conf)
 
-		err := p.executeWithRetry(context.Background(), &emptypb.Empty{})
+		err := p.executeWithRetry(context.Background(), newTestEnvelope(0, 1))
 
 		assert.ErrorIs(t, err, ErrPermanent)
 	})
+
+	t.Run("does not process message while rate limited on shutdown", func(t *testing.T) {
+		handler := &mockHandler{}
+		conf := createTestConsumerConfig()
+		controller := NewController(newTestClient(t, "test-topic"), newMockStateReporter(), conf, zap.NewNop())
+		require.NoError(t, controller.SetRateLimit(0.001, 1))
+		require.True(t, controller.acquire(context.Background()))
+
+		p := NewProcessor(make(chan *MessageEnvelope), handler, zap.NewNop(), &ResultHandler{log: zap.NewNop()}, newMockTracer(), nil, controller, nil, conf)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.NoError(t, p.processMessage(ctx, newTestEnvelope(0, 1)))
+
+		assert.Equal(t, int32(0), handler.callCount.Load())
+	})
+}
+
+// mockInbox is a test implementation of Inbox
+type mockInbox struct {
+	seen      map[string]bool
+	seenErr   error
+	recordErr error
+	recorded  []string
+	recordCtx context.Context
+}
+
+func (m *mockInbox) Seen(ctx context.Context, consumerName, eventID string) (bool, error) {
+	return m.seen[consumerName+"/"+eventID], m.seenErr
+}
+
+func (m *mockInbox) Record(ctx context.Context, consumerName, eventID, topic string) error {
+	m.recordCtx = ctx
+	if m.recordErr != nil {
+		return m.recordErr
+	}
+	m.recorded = append(m.recorded, consumerName+"/"+eventID)
+	return nil
+}
+
+type txCtxKey struct{}
+
+// mockTxManager runs the function with a context marked as transactional
+type mockTxManager struct {
+	calls int
+}
+
+func (m *mockTxManager) WithTransaction(ctx context.Context, fn func(txCtx context.Context) (any, error)) (any, error) {
+	m.calls++
+	return fn(context.WithValue(ctx, txCtxKey{}, true))
+}
+
+// mockTransactionalHandler is a test implementation of TransactionalHandler
+type mockTransactionalHandler struct {
+	mockHandler
+	txManager *mockTxManager
+}
+
+func (m *mockTransactionalHandler) TxManager() mongo.TxManager {
+	return m.txManager
+}
+
+func newEventEnvelope(eventID string) *MessageEnvelope {
+	envelope := newTestEnvelope(0, 1)
+	envelope.Record.Headers = []kgo.RecordHeader{{Key: HeaderEventID, Value: []byte(eventID)}}
+	return envelope
+}
+
+func TestProcessor_Inbox(t *testing.T) {
+	newInboxProcessor := func(handler Handler, inbox Inbox) *Processor {
+		return NewProcessor(make(chan *MessageEnvelope), handler, zap.NewNop(), &ResultHandler{log: zap.NewNop()}, newMockTracer(), inbox, nil, nil, createTestConsumerConfig())
+	}
+
+	t.Run("skips event already processed by the consumer", func(t *testing.T) {
+		inbox := &mockInbox{seen: map[string]bool{"test-consumer/evt-1": true}}
+		p := newInboxProcessor(&mockHandler{}, inbox)
+
+		assert.ErrorIs(t, p.skipProcessed(context.Background(), newEventEnvelope("evt-1")), ErrSkipMessage)
+		assert.NoError(t, p.skipProcessed(context.Background(), newEventEnvelope("evt-2")))
+	})
+
+	t.Run("processes message when inbox is unavailable", func(t *testing.T) {
+		p := newInboxProcessor(&mockHandler{}, &mockInbox{seenErr: errors.New("mongo down")})
+
+		assert.NoError(t, p.skipProcessed(context.Background(), newEventEnvelope("evt-1")))
+	})
+
+	t.Run("records event after handler succeeded", func(t *testing.T) {
+		inbox := &mockInbox{}
+		handler := &mockHandler{}
+		p := newInboxProcessor(handler, inbox)
+
+		require.NoError(t, p.executeWithRetry(context.Background(), newEventEnvelope("evt-1")))
+
+		assert.Equal(t, int32(1), handler.callCount.Load())
+		assert.Equal(t, []string{"test-consumer/evt-1"}, inbox.recorded)
+	})
+
+	t.Run("does not record failed event", func(t *testing.T) {
+		inbox := &mockInbox{}
+		handler := &mockHandler{processFunc: func(ctx context.Context, event proto.Message) error {
+			return fmt.Errorf("invalid order: %w", ErrPermanent)
+		}}
+		p := newInboxProcessor(handler, inbox)
+
+		assert.ErrorIs(t, p.executeWithRetry(context.Background(), newEventEnvelope("evt-1")), ErrPermanent)
+		assert.Empty(t, inbox.recorded)
+	})
+
+	t.Run("does not use inbox for messages without event id", func(t *testing.T) {
+		inbox := &mockInbox{seen: map[string]bool{"test-consumer/": true}}
+		p := newInboxProcessor(&mockHandler{}, inbox)
+
+		assert.NoError(t, p.skipProcessed(context.Background(), newTestEnvelope(0, 1)))
+		require.NoError(t, p.executeWithRetry(context.Background(), newTestEnvelope(0, 1)))
+		assert.Empty(t, inbox.recorded)
+	})
+
+	t.Run("records event in the transaction of a transactional handler", func(t *testing.T) {
+		inbox := &mockInbox{}
+		var handlerInTx bool
+		handler := &mockTransactionalHandler{txManager: &mockTxManager{}}
+		handler.processFunc = func(ctx context.Context, event proto.Message) error {
+			handlerInTx = ctx.Value(txCtxKey{}) != nil
+			return nil
+		}
+		p := newInboxProcessor(handler, inbox)
+
+		require.NoError(t, p.executeWithRetry(context.Background(), newEventEnvelope("evt-1")))
+
+		assert.Equal(t, 1, handler.txManager.calls)
+		assert.True(t, handlerInTx)
+		assert.NotNil(t, inbox.recordCtx.Value(txCtxKey{}), "inbox record must use the transaction context")
+		assert.Equal(t, []string{"test-consumer/evt-1"}, inbox.recorded)
+	})
+
+	t.Run("records event in the transaction of a router with a tx manager", func(t *testing.T) {
+		inbox := &mockInbox{}
+		txManager := &mockTxManager{}
+		var handlerInTx bool
+		router := NewRouter(zap.NewNop())
+		Register(router, func(ctx context.Context, _ *emptypb.Empty) error {
+			handlerInTx = ctx.Value(txCtxKey{}) != nil
+			return nil
+		})
+		router.SetTxManager(txManager)
+		p := newInboxProcessor(router, inbox)
+
+		require.NoError(t, p.executeWithRetry(context.Background(), newEventEnvelope("evt-1")))
+
+		assert.Equal(t, 1, txManager.calls)
+		assert.True(t, handlerInTx)
+		assert.NotNil(t, inbox.recordCtx.Value(txCtxKey{}), "inbox record must use the transaction context")
+	})
+
+	t.Run("router without a tx manager records event after the handler", func(t *testing.T) {
+		inbox := &mockInbox{}
+		router := NewRouter(zap.NewNop())
+		Register(router, func(context.Context, *emptypb.Empty) error { return nil })
+		p := newInboxProcessor(router, inbox)
+
+		require.NoError(t, p.executeWithRetry(context.Background(), newEventEnvelope("evt-1")))
+
+		assert.Nil(t, inbox.recordCtx.Value(txCtxKey{}))
+		assert.Equal(t, []string{"test-consumer/evt-1"}, inbox.recorded)
+	})
+
+	t.Run("concurrently recorded event rolls back and is skipped", func(t *testing.T) {
+		inbox := &mockInbox{recordErr: fmt.Errorf("duplicate event: %w", ErrSkipMessage)}
+		handler := &mockTransactionalHandler{txManager: &mockTxManager{}}
+		p := newInboxProcessor(handler, inbox)
+
+		assert.ErrorIs(t, p.executeWithRetry(context.Background(), newEventEnvelope("evt-1")), ErrSkipMessage)
+		assert.Equal(t, int32(1), handler.callCount.Load())
+	})
 }
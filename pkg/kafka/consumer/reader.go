@@ -16,13 +16,16 @@ type Reader struct {
 	client         *kgo.Client
 	messagesChan   chan<- *kgo.Record
 	maxPollRecords int
+	inFlight       *InFlightCounter
 	log            *zap.Logger
 	throttler      *logger.LogThrottler
 }
 
+// NewReader creates a Reader that counts the records it passes on in inFlight, which may be nil.
 func NewReader(
 	client *kgo.Client,
 	messagesChan chan *kgo.Record,
+	inFlight *InFlightCounter,
 	consumerConf config.ConsumerConfig,
 	log *zap.Logger,
 ) *Reader {
@@ -30,6 +33,7 @@ func NewReader(
 		client:         client,
 		messagesChan:   messagesChan,
 		maxPollRecords: consumerConf.MaxPollRecords,
+		inFlight:       inFlight,
 		log:            log,
 		throttler:      logger.NewLogThrottler(log, 0),
 	}
@@ -68,8 +72,10 @@ func (r *Reader) Run(ctx context.Context) error {
 		}
 
 		fetches.EachRecord(func(record *kgo.Record) {
+			r.inFlight.add()
 			select {
 			case <-ctx.Done():
+				r.inFlight.done()
 				return
 			case r.messagesChan <- record:
 			}
@@ -17,7 +17,7 @@ func TestNewReader(t *testing.T) {
 		consumerConf := config.ConsumerConfig{MaxPollRecords: 500}
 
 		// newReader takes *kgo.Client which requires real brokers for full testing
-		r := NewReader(nil, messagesChan, consumerConf, log)
+		r := NewReader(nil, messagesChan, nil, consumerConf, log)
 
 		assert.NotNil(t, r)
 		assert.Equal(t, log, r.log)
@@ -0,0 +1,327 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/producer"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+// HeaderRedriveCount is the number of times a message was redriven from the DLQ.
+const HeaderRedriveCount = "redrive.count"
+
+const (
+	redrivePollRecords = 500
+	// redriveIdleTimeout ends a one-shot redrive when the DLQ yields no records for this long.
+	redriveIdleTimeout = 10 * time.Second
+
+	// Backoff of Run between attempts to republish a record.
+	redriveInitialBackoff = time.Second
+	redriveMaxBackoff     = time.Minute
+)
+
+// RedriveResult summarizes a redrive run.
+type RedriveResult struct {
+	Scanned  int // DLQ records read
+	Matched  int // Records selected by the filter
+	Redriven int // Records republished to their original topic, zero on a dry run
+	Parked   int // Matching records left in the DLQ because they reached the max redrive count
+}
+
+// Redriver republishes DLQ records selected by config.RedriveConfig to their original topic.
+// The dlq.* and retry.* headers are stripped and the redrive.count header is incremented,
+// so a redriven message gets a fresh round of retries. Records that were already redriven
+// MaxRedrives times stay in the DLQ, so a message that keeps failing is not redriven forever.
+type Redriver struct {
+	client    *kgo.Client
+	producer  producer.Producer
+	dlqTopics []string
+	conf      config.RedriveConfig
+	log       *zap.Logger
+}
+
+// NewRedriver creates a Redriver that reads dlqTopics with client and republishes through producer.
+// A *kgo.Client is a producer.Producer as well, so one client may serve both.
+func NewRedriver(
+	client *kgo.Client,
+	producer producer.Producer,
+	dlqTopics []string,
+	conf config.RedriveConfig,
+	log *zap.Logger,
+) *Redriver {
+	return &Redriver{
+		client:    client,
+		producer:  producer,
+		dlqTopics: dlqTopics,
+		conf:      conf,
+		log:       log.With(zap.Strings("dlq_topics", dlqTopics), zap.Bool("dry_run", conf.DryRun)),
+	}
+}
+
+// NewRedriveClient creates a client that reads dlqTopics from the start without a consumer group,
+// for one-shot redrives with RedriveOnce.
+func NewRedriveClient(conf config.Config, dlqTopics ...string) (*kgo.Client, error) {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(strings.Split(conf.Brokers, ",")...),
+		kgo.ConsumeTopics(dlqTopics...),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka redrive client, topics: %v: %w", dlqTopics, err)
+	}
+	return client, nil
+}
+
+// Run redrives matching records until ctx is cancelled. It is meant for a client consuming the DLQ
+// in a consumer group: redriven records are marked for commit, except on a dry run so that the
+// records are read again once the dry run is switched off. A record that cannot be republished
+// is retried with backoff and neither it nor a later record is marked meanwhile, so it stays in
+// the DLQ until it is republished.
+func (r *Redriver) Run(ctx context.Context) error {
+	for {
+		fetches := r.client.PollRecords(ctx, redrivePollRecords)
+		if ctx.Err() != nil {
+			return nil //nolint:nilerr // context cancellation is a graceful shutdown, not an error
+		}
+		r.logFetchErrors(fetches)
+
+		var result RedriveResult
+		for _, record := range fetches.Records() {
+			if err := r.handleWithBackoff(ctx, record, &result); err != nil {
+				return nil //nolint:nilerr // records are retried until the context is cancelled, a graceful shutdown
+			}
+			if !r.conf.DryRun {
+				r.client.MarkCommitRecords(record)
+			}
+		}
+	}
+}
+
+// handleWithBackoff handles record until it succeeds; it only fails once ctx is cancelled.
+func (r *Redriver) handleWithBackoff(ctx context.Context, record *kgo.Record, result *RedriveResult) error {
+	b := backoff.WithContext(backoff.NewExponentialBackOff(
+		backoff.WithInitialInterval(redriveInitialBackoff),
+		backoff.WithMaxInterval(redriveMaxBackoff),
+		backoff.WithMaxElapsedTime(0),
+	), ctx)
+
+	return backoff.RetryNotify(func() error {
+		return r.handle(ctx, record, result)
+	}, b, func(err error, next time.Duration) {
+		r.log.Error("failed to redrive DLQ record, retrying",
+			zap.Int32("partition", record.Partition),
+			zap.Int64("offset", record.Offset),
+			zap.Duration("backoff", next),
+			zap.Error(err))
+	})
+}
+
+// RedriveOnce redrives the matching records that are in the DLQ when it is called and returns.
+// It stops at the first record that cannot be republished.
+func (r *Redriver) RedriveOnce(ctx context.Context) (RedriveResult, error) {
+	var result RedriveResult
+
+	remaining, err := r.pendingOffsets(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	for len(remaining) > 0 {
+		pollCtx, cancel := context.WithTimeout(ctx, redriveIdleTimeout)
+		fetches := r.client.PollRecords(pollCtx, redrivePollRecords)
+		cancel()
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		r.logFetchErrors(fetches)
+
+		records := fetches.Records()
+		if len(records) == 0 {
+			r.log.Warn("no more DLQ records before the end offsets, stopping redrive",
+				zap.Int("pending_partitions", len(remaining)))
+			break
+		}
+
+		for _, record := range records {
+			tp := topicPartition{topic: record.Topic, partition: record.Partition}
+			end, ok := remaining[tp]
+			if !ok || record.Offset >= end {
+				// Written after the redrive started.
+				continue
+			}
+			if err := r.handle(ctx, record, &result); err != nil {
+				return result, err
+			}
+			if record.Offset+1 >= end {
+				delete(remaining, tp)
+			}
+		}
+	}
+
+	r.log.Info("DLQ redrive finished",
+		zap.Int("scanned", result.Scanned),
+		zap.Int("matched", result.Matched),
+		zap.Int("redriven", result.Redriven),
+		zap.Int("parked", result.Parked))
+	return result, nil
+}
+
+// pendingOffsets returns the end offset of every non-empty DLQ partition.
+func (r *Redriver) pendingOffsets(ctx context.Context) (map[topicPartition]int64, error) {
+	admin := kadm.NewClient(r.client)
+	starts, err := admin.ListStartOffsets(ctx, r.dlqTopics...)
+	if err == nil {
+		err = starts.Error()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list start offsets of %v: %w", r.dlqTopics, err)
+	}
+	ends, err := admin.ListEndOffsets(ctx, r.dlqTopics...)
+	if err == nil {
+		err = ends.Error()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list end offsets of %v: %w", r.dlqTopics, err)
+	}
+
+	remaining := make(map[topicPartition]int64)
+	ends.Each(func(end kadm.ListedOffset) {
+		if start, ok := starts.Lookup(end.Topic, end.Partition); ok && start.Offset < end.Offset {
+			remaining[topicPartition{topic: end.Topic, partition: end.Partition}] = end.Offset
+		}
+	})
+	return remaining, nil
+}
+
+func (r *Redriver) logFetchErrors(fetches kgo.Fetches) {
+	for _, fe := range fetches.Errors() {
+		if errors.Is(fe.Err, context.Canceled) || errors.Is(fe.Err, context.DeadlineExceeded) {
+			continue
+		}
+		r.log.Error("kafka fetch error",
+			zap.String("topic", fe.Topic),
+			zap.Int32("partition", fe.Partition),
+			zap.Error(fe.Err))
+	}
+}
+
+// handle redrives a single DLQ record if it matches the filter.
+func (r *Redriver) handle(ctx context.Context, record *kgo.Record, result *RedriveResult) error {
+	result.Scanned++
+	if !r.matches(record) {
+		return nil
+	}
+
+	target := redriveTarget(record)
+	if target == "" {
+		r.log.Warn("DLQ record has no original topic, skipping",
+			zap.Int32("partition", record.Partition),
+			zap.Int64("offset", record.Offset))
+		return nil
+	}
+	result.Matched++
+
+	fields := []zap.Field{
+		zap.String("target_topic", target),
+		zap.String("dlq_topic", record.Topic),
+		zap.String("key", string(record.Key)),
+		zap.Int32("partition", record.Partition),
+		zap.Int64("offset", record.Offset),
+	}
+	if count := redriveCount(record); r.conf.MaxRedrives > 0 && count >= r.conf.MaxRedrives {
+		result.Parked++
+		r.log.Warn("DLQ record reached the max redrive count, leaving it in the DLQ",
+			append(fields, zap.Int("redrive_count", count))...)
+		return nil
+	}
+	if r.conf.DryRun {
+		r.log.Info("DLQ record would be redriven", fields...)
+		return nil
+	}
+
+	errCh := make(chan error, 1)
+	r.producer.Produce(ctx, newRedriveRecord(record, target), func(_ *kgo.Record, err error) {
+		errCh <- err
+	})
+	if err := <-errCh; err != nil {
+		return fmt.Errorf("failed to republish DLQ record to %s: %w", target, err)
+	}
+
+	result.Redriven++
+	r.log.Info("DLQ record redriven", fields...)
+	return nil
+}
+
+// matches reports whether the record passes the redrive filter.
+func (r *Redriver) matches(record *kgo.Record) bool {
+	failedAt := dlqFailedAt(record)
+	if !r.conf.FailedAfter.IsZero() && failedAt.Before(r.conf.FailedAfter) {
+		return false
+	}
+	if !r.conf.FailedBefore.IsZero() && !failedAt.Before(r.conf.FailedBefore) {
+		return false
+	}
+	if r.conf.ErrorContains != "" && !strings.Contains(headerValue(record, HeaderDLQError), r.conf.ErrorContains) {
+		return false
+	}
+	if r.conf.EventType != "" && headerValue(record, "event_type") != r.conf.EventType {
+		return false
+	}
+	return true
+}
+
+// dlqFailedAt returns when the record was moved to the DLQ, falling back to the record timestamp.
+func dlqFailedAt(record *kgo.Record) time.Time {
+	if failedAt, err := time.Parse(time.RFC3339, headerValue(record, HeaderDLQTimestamp)); err == nil {
+		return failedAt
+	}
+	return record.Timestamp
+}
+
+// redriveTarget returns the topic the message was first consumed from. Messages that reached the
+// DLQ from a retry tier go back to the main topic rather than the tier.
+func redriveTarget(record *kgo.Record) string {
+	if topic := headerValue(record, HeaderRetryOriginalTopic); topic != "" {
+		return topic
+	}
+	return headerValue(record, HeaderDLQOriginalTopic)
+}
+
+// redriveCount returns the number of times the message of record was redriven before.
+func redriveCount(record *kgo.Record) int {
+	count, err := strconv.Atoi(headerValue(record, HeaderRedriveCount))
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// newRedriveRecord copies a DLQ record for target without the dlq.* and retry.* headers.
+func newRedriveRecord(record *kgo.Record, target string) *kgo.Record {
+	count := redriveCount(record)
+
+	headers := make([]kgo.RecordHeader, 0, len(record.Headers))
+	for _, h := range record.Headers {
+		if strings.HasPrefix(h.Key, "dlq.") || strings.HasPrefix(h.Key, "retry.") || h.Key == HeaderRedriveCount {
+			continue
+		}
+		headers = append(headers, h)
+	}
+	headers = append(headers, kgo.RecordHeader{Key: HeaderRedriveCount, Value: []byte(strconv.Itoa(count + 1))})
+
+	return &kgo.Record{
+		Topic:   target,
+		Key:     record.Key,
+		Value:   record.Value,
+		Headers: headers,
+	}
+}
@@ -0,0 +1,176 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+func createTestDLQRecord(failedAt time.Time, errMsg string) *kgo.Record {
+	return &kgo.Record{
+		Topic:     "test-topic.dlq",
+		Partition: 1,
+		Offset:    7,
+		Key:       []byte("test-key"),
+		Value:     []byte("test-value"),
+		Timestamp: failedAt,
+		Headers: []kgo.RecordHeader{
+			{Key: "event_type", Value: []byte("orders.v1.OrderCreated")},
+			{Key: "traceparent", Value: []byte("00-trace")},
+			{Key: HeaderDLQOriginalTopic, Value: []byte("test-topic")},
+			{Key: HeaderDLQOriginalPartition, Value: []byte("0")},
+			{Key: HeaderDLQOriginalOffset, Value: []byte("100")},
+			{Key: HeaderDLQError, Value: []byte(errMsg)},
+			{Key: HeaderDLQTimestamp, Value: []byte(failedAt.UTC().Format(time.RFC3339))},
+		},
+	}
+}
+
+func newTestRedriver(producer *mockProducer, conf config.RedriveConfig) *Redriver {
+	return NewRedriver(nil, producer, []string{"test-topic.dlq"}, conf, zap.NewNop())
+}
+
+func TestRedriver_Handle(t *testing.T) {
+	failedAt := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("republishes record to original topic", func(t *testing.T) {
+		producer := &mockProducer{}
+		r := newTestRedriver(producer, config.RedriveConfig{})
+		var result RedriveResult
+
+		require.NoError(t, r.handle(context.Background(), createTestDLQRecord(failedAt, "db down"), &result))
+
+		assert.Equal(t, RedriveResult{Scanned: 1, Matched: 1, Redriven: 1}, result)
+		require.Len(t, producer.records, 1)
+		sent := producer.records[0]
+		assert.Equal(t, "test-topic", sent.Topic)
+		assert.Equal(t, []byte("test-key"), sent.Key)
+		assert.Equal(t, []byte("test-value"), sent.Value)
+		assert.Equal(t, []kgo.RecordHeader{
+			{Key: "event_type", Value: []byte("orders.v1.OrderCreated")},
+			{Key: "traceparent", Value: []byte("00-trace")},
+			{Key: HeaderRedriveCount, Value: []byte("1")},
+		}, sent.Headers)
+	})
+
+	t.Run("increments redrive count and returns retried messages to main topic", func(t *testing.T) {
+		producer := &mockProducer{}
+		r := newTestRedriver(producer, config.RedriveConfig{})
+		record := createTestDLQRecord(failedAt, "db down")
+		record.Headers = append(record.Headers,
+			kgo.RecordHeader{Key: HeaderRedriveCount, Value: []byte("2")},
+			kgo.RecordHeader{Key: HeaderRetryAttempt, Value: []byte("2")},
+			kgo.RecordHeader{Key: HeaderRetryOriginalTopic, Value: []byte("test-topic")},
+		)
+		record.Headers[2].Value = []byte("test-topic.retry.10m")
+
+		require.NoError(t, r.handle(context.Background(), record, &RedriveResult{}))
+
+		sent := producer.records[0]
+		assert.Equal(t, "test-topic", sent.Topic)
+		assert.Equal(t, "3", headerValue(sent, HeaderRedriveCount))
+		assert.Empty(t, headerValue(sent, HeaderRetryAttempt))
+	})
+
+	t.Run("dry run only counts matches", func(t *testing.T) {
+		producer := &mockProducer{}
+		r := newTestRedriver(producer, config.RedriveConfig{DryRun: true})
+		var result RedriveResult
+
+		require.NoError(t, r.handle(context.Background(), createTestDLQRecord(failedAt, "db down"), &result))
+
+		assert.Equal(t, RedriveResult{Scanned: 1, Matched: 1}, result)
+		assert.Empty(t, producer.records)
+	})
+
+	t.Run("skips record without original topic", func(t *testing.T) {
+		producer := &mockProducer{}
+		r := newTestRedriver(producer, config.RedriveConfig{})
+		var result RedriveResult
+
+		require.NoError(t, r.handle(context.Background(), createTestMessage(), &result))
+
+		assert.Equal(t, RedriveResult{Scanned: 1}, result)
+		assert.Empty(t, producer.records)
+	})
+
+	t.Run("returns produce error", func(t *testing.T) {
+		producer := &mockProducer{err: errors.New("broker unavailable")}
+		r := newTestRedriver(producer, config.RedriveConfig{})
+		var result RedriveResult
+
+		err := r.handle(context.Background(), createTestDLQRecord(failedAt, "db down"), &result)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "broker unavailable")
+		assert.Equal(t, 0, result.Redriven)
+	})
+
+	t.Run("parks record at max redrive count", func(t *testing.T) {
+		producer := &mockProducer{}
+		r := newTestRedriver(producer, config.RedriveConfig{MaxRedrives: 3})
+		record := createTestDLQRecord(failedAt, "db down")
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: HeaderRedriveCount, Value: []byte("3")})
+		var result RedriveResult
+
+		require.NoError(t, r.handle(context.Background(), record, &result))
+
+		assert.Equal(t, RedriveResult{Scanned: 1, Matched: 1, Parked: 1}, result)
+		assert.Empty(t, producer.records)
+	})
+}
+
+func TestRedriver_HandleWithBackoff(t *testing.T) {
+	t.Run("retries a failed record until the context is cancelled", func(t *testing.T) {
+		producer := &mockProducer{err: errors.New("broker unavailable")}
+		r := newTestRedriver(producer, config.RedriveConfig{})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := r.handleWithBackoff(ctx, createTestDLQRecord(time.Now(), "db down"), &RedriveResult{})
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Len(t, producer.records, 1)
+	})
+}
+
+func TestRedriver_Matches(t *testing.T) {
+	failedAt := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	record := createTestDLQRecord(failedAt, "failed to save order: connection refused")
+
+	tests := []struct {
+		name    string
+		conf    config.RedriveConfig
+		matches bool
+	}{
+		{"empty filter", config.RedriveConfig{}, true},
+		{"inside time range", config.RedriveConfig{FailedAfter: failedAt, FailedBefore: failedAt.Add(time.Hour)}, true},
+		{"before time range", config.RedriveConfig{FailedAfter: failedAt.Add(time.Second)}, false},
+		{"upper bound is exclusive", config.RedriveConfig{FailedBefore: failedAt}, false},
+		{"error substring", config.RedriveConfig{ErrorContains: "connection refused"}, true},
+		{"other error", config.RedriveConfig{ErrorContains: "timeout"}, false},
+		{"event type", config.RedriveConfig{EventType: "orders.v1.OrderCreated"}, true},
+		{"other event type", config.RedriveConfig{EventType: "orders.v1.OrderCancelled"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRedriver(&mockProducer{}, tt.conf)
+			assert.Equal(t, tt.matches, r.matches(record))
+		})
+	}
+
+	t.Run("falls back to record timestamp", func(t *testing.T) {
+		r := newTestRedriver(&mockProducer{}, config.RedriveConfig{FailedAfter: failedAt})
+		noHeader := &kgo.Record{Timestamp: failedAt.Add(-time.Minute)}
+
+		assert.False(t, r.matches(noHeader))
+	})
+}
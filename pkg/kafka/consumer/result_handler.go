@@ -17,34 +17,87 @@ type offsetMarker interface {
 
 // ResultHandler handles message processing results.
 type ResultHandler struct {
-	log          *zap.Logger
-	dlqHandler   DLQHandler
-	offsetMarker offsetMarker
+	log            *zap.Logger
+	dlqHandler     DLQHandler
+	retryPublisher RetryPublisher
+	offsetMarker   offsetMarker
+	metrics        *Metrics
+	inFlight       *InFlightCounter
 }
 
+// NewResultHandler creates a ResultHandler that marks records of client and finishes them in
+// inFlight, which may be nil.
 func NewResultHandler(
 	log *zap.Logger,
 	dlqHandler DLQHandler,
+	retryPublisher RetryPublisher,
 	client *kgo.Client,
+	metrics *Metrics,
+	inFlight *InFlightCounter,
 ) *ResultHandler {
 	return &ResultHandler{
-		log:          log,
-		dlqHandler:   dlqHandler,
-		offsetMarker: client,
+		log:            log,
+		dlqHandler:     dlqHandler,
+		retryPublisher: retryPublisher,
+		offsetMarker:   client,
+		metrics:        metrics,
+		inFlight:       inFlight,
 	}
 }
 
-// handle processes the result of message handling and takes appropriate action.
-func (h *ResultHandler) handle(ctx context.Context, err error, record *kgo.Record, span trace.Span) {
+// withOffsetMarker returns a copy of the handler that marks processed records with marker.
+func (h *ResultHandler) withOffsetMarker(marker offsetMarker) *ResultHandler {
+	clone := *h
+	clone.offsetMarker = marker
+	return &clone
+}
+
+// handle processes the result of message handling, takes appropriate action and returns the outcome.
+func (h *ResultHandler) handle(ctx context.Context, err error, record *kgo.Record, span trace.Span) string {
+	defer h.inFlight.done()
 	defer h.offsetMarker.MarkCommitRecords(record)
 
+	outcome := h.apply(ctx, err, record, span)
+	h.metrics.recordOutcome(ctx, record.Topic, outcome)
+	return outcome
+}
+
+// park holds records whose handler paused the consumer until it is resumed. Parked records don't
+// hold back an offset reset, which drops them unmarked. It returns false if the records must not
+// be processed again: on shutdown, or when they were dropped.
+func (h *ResultHandler) park(ctx context.Context, controller *Controller, records ...*kgo.Record) bool {
+	dropped := h.inFlight.park(len(records))
+	err := controller.waitRunning(ctx, dropped)
+	h.inFlight.unpark(len(records))
+
+	if errors.Is(err, errParkedDropped) {
+		for _, record := range records {
+			h.log.Warn("dropping message of paused consumer, its offsets were reset", h.recordFields(record)...)
+			h.drop(record)
+		}
+	}
+	return err == nil
+}
+
+// drop finishes a record without marking it. The records completed behind it on the partition
+// are not marked either, since they precede the reset offsets.
+func (h *ResultHandler) drop(record *kgo.Record) {
+	if tracker, ok := h.offsetMarker.(*offsetTracker); ok {
+		tracker.discard(record)
+	}
+	h.inFlight.done()
+}
+
+func (h *ResultHandler) apply(ctx context.Context, err error, record *kgo.Record, span trace.Span) string {
 	switch {
 	case err == nil:
 		span.SetStatus(codes.Ok, "message processed successfully")
+		return OutcomeSuccess
 
 	case errors.Is(err, ErrSkipMessage):
 		span.SetStatus(codes.Ok, "message skipped")
 		h.log.Info("skipping message", h.recordFields(record)...)
+		return OutcomeSkipped
 
 	case errors.Is(err, ErrPermanent):
 		span.SetStatus(codes.Error, "permanent error - sending to DLQ")
@@ -55,13 +108,21 @@ func (h *ResultHandler) handle(ctx context.Context, err error, record *kgo.Recor
 		}
 		h.log.Error("permanent error - sending message to DLQ", fields...)
 		h.dlqHandler.SendToDLQ(ctx, record, err)
+		return OutcomePermanent
 
 	default:
-		// Retry exhausted or context cancelled
 		span.RecordError(err)
+		if h.retryPublisher != nil && h.retryPublisher.PublishRetry(ctx, record, err) {
+			span.SetStatus(codes.Error, "message processing failed - sent to retry topic")
+			h.log.Warn("message processing failed - sent to retry topic", h.recordFieldsWithError(record, err)...)
+			return OutcomeRetryTopic
+		}
+
+		// Retry exhausted or context cancelled
 		span.SetStatus(codes.Error, "message processing failed - sending to DLQ")
 		h.log.Error("message processing failed after retries - sending to DLQ", h.recordFieldsWithError(record, err)...)
 		h.dlqHandler.SendToDLQ(ctx, record, err)
+		return OutcomeFailed
 	}
 }
 
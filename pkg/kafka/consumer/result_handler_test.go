@@ -157,6 +157,34 @@ func TestResultHandler_Handle(t *testing.T) {
 			t.Fatal("record was not sent to DLQ")
 		}
 	})
+
+	t.Run("sends failed message to retry topic instead of DLQ", func(t *testing.T) {
+		dlqHandler := &mockDLQHandler{}
+		retryPublisher := &mockRetryPublisher{published: true}
+		marker := &mockOffsetMarker{}
+		rh := &ResultHandler{log: zap.NewNop(), dlqHandler: dlqHandler, retryPublisher: retryPublisher, offsetMarker: marker}
+
+		span := newMockSpan()
+		record := createTestMessage()
+
+		rh.handle(context.Background(), assert.AnError, record, span)
+
+		assert.Equal(t, "message processing failed - sent to retry topic", span.statusMessage)
+		assert.Equal(t, []*kgo.Record{record}, retryPublisher.records)
+		assert.Len(t, marker.markedRecords, 1)
+		assert.Equal(t, int32(0), dlqHandler.callCount.Load())
+	})
+
+	t.Run("sends message to DLQ when retry tiers are exhausted", func(t *testing.T) {
+		dlqHandler := &mockDLQHandler{}
+		retryPublisher := &mockRetryPublisher{published: false}
+		rh := &ResultHandler{log: zap.NewNop(), dlqHandler: dlqHandler, retryPublisher: retryPublisher, offsetMarker: &mockOffsetMarker{}}
+
+		rh.handle(context.Background(), assert.AnError, createTestMessage(), newMockSpan())
+
+		assert.Len(t, retryPublisher.records, 1)
+		assert.Equal(t, int32(1), dlqHandler.callCount.Load())
+	})
 }
 
 func TestResultHandler_RecordFields(t *testing.T) {
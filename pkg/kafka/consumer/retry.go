@@ -0,0 +1,209 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/producer"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+// Headers written on messages republished to a retry topic.
+const (
+	// HeaderRetryAttempt is the number of the retry tier the message was sent to, starting at 1.
+	HeaderRetryAttempt = "retry.attempt"
+	// HeaderRetryOriginalTopic is the topic the message was first consumed from.
+	HeaderRetryOriginalTopic = "retry.original.topic"
+	// HeaderRetryNotBefore is the Unix time in milliseconds before which the message is not reprocessed.
+	HeaderRetryNotBefore = "retry.not_before"
+	// HeaderRetryError is the error of the last failed attempt.
+	HeaderRetryError = "retry.error"
+)
+
+// RetryTier is a retry delay with the name of its topics.
+type RetryTier struct {
+	Name  string // Formatted delay used as topic suffix, e.g. 1m
+	Delay time.Duration
+}
+
+// Topic returns the retry topic of the tier for messages consumed from sourceTopic,
+// named {source}.retry.{delay}, e.g. orders.retry.1m.
+func (t RetryTier) Topic(sourceTopic string) string {
+	return sourceTopic + ".retry." + t.Name
+}
+
+// RetryTiers returns the retry tiers of a consumer, empty if retry topics are disabled.
+func RetryTiers(consumerConf config.ConsumerConfig) []RetryTier {
+	if !consumerConf.RetryTopics.Enabled {
+		return nil
+	}
+	tiers := make([]RetryTier, 0, len(consumerConf.RetryTopics.Delays))
+	for _, delay := range consumerConf.RetryTopics.Delays {
+		tiers = append(tiers, RetryTier{
+			Name:  formatRetryDelay(delay),
+			Delay: delay,
+		})
+	}
+	return tiers
+}
+
+// formatRetryDelay formats a delay in the largest whole unit, e.g. 1m, 90s or 2h.
+func formatRetryDelay(delay time.Duration) string {
+	switch {
+	case delay%time.Hour == 0:
+		return fmt.Sprintf("%dh", delay/time.Hour)
+	case delay%time.Minute == 0:
+		return fmt.Sprintf("%dm", delay/time.Minute)
+	case delay%time.Second == 0:
+		return fmt.Sprintf("%ds", delay/time.Second)
+	default:
+		return fmt.Sprintf("%dms", delay/time.Millisecond)
+	}
+}
+
+// RetryPublisher republishes messages that failed with a retriable error to the next retry tier.
+type RetryPublisher interface {
+	// PublishRetry sends the record to the next retry topic. It returns false if the record
+	// already went through all tiers or could not be published; the caller then sends it to the DLQ.
+	PublishRetry(ctx context.Context, record *kgo.Record, processingErr error) bool
+}
+
+type retryPublisher struct {
+	producer producer.Producer
+	tiers    []RetryTier
+	tracer   MessageTracer
+	log      *zap.Logger
+}
+
+func NewRetryPublisher(
+	producer producer.Producer,
+	tiers []RetryTier,
+	tracer MessageTracer,
+	log *zap.Logger,
+) RetryPublisher {
+	return &retryPublisher{
+		producer: producer,
+		tiers:    tiers,
+		tracer:   tracer,
+		log:      log,
+	}
+}
+
+func (p *retryPublisher) PublishRetry(ctx context.Context, record *kgo.Record, processingErr error) bool {
+	attempt := retryAttempt(record)
+	if attempt >= len(p.tiers) {
+		return false
+	}
+	tier := p.tiers[attempt]
+
+	originalTopic := headerValue(record, HeaderRetryOriginalTopic)
+	if originalTopic == "" {
+		originalTopic = record.Topic
+	}
+	retryTopic := tier.Topic(originalTopic)
+
+	ctx, span := p.tracer.StartRetrySpan(ctx, record, retryTopic)
+	defer span.End()
+
+	headers := make([]kgo.RecordHeader, 0, len(record.Headers)+4)
+	for _, h := range record.Headers {
+		if !strings.HasPrefix(h.Key, "retry.") {
+			headers = append(headers, h)
+		}
+	}
+	headers = append(headers,
+		kgo.RecordHeader{Key: HeaderRetryAttempt, Value: []byte(strconv.Itoa(attempt + 1))},
+		kgo.RecordHeader{Key: HeaderRetryOriginalTopic, Value: []byte(originalTopic)},
+		kgo.RecordHeader{Key: HeaderRetryNotBefore, Value: []byte(strconv.FormatInt(time.Now().Add(tier.Delay).UnixMilli(), 10))},
+		kgo.RecordHeader{Key: HeaderRetryError, Value: []byte(processingErr.Error())},
+	)
+
+	retryRecord := &kgo.Record{
+		Topic:   retryTopic,
+		Key:     record.Key,
+		Value:   record.Value,
+		Headers: headers,
+	}
+	p.tracer.InjectContext(ctx, retryRecord)
+
+	errCh := make(chan error, 1)
+	p.producer.Produce(ctx, retryRecord, func(r *kgo.Record, err error) {
+		errCh <- err
+	})
+
+	if err := <-errCh; err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to deliver message to retry topic")
+		p.log.Error("failed to deliver message to retry topic",
+			zap.String("retry_topic", retryTopic),
+			zap.String("key", string(record.Key)),
+			zap.Error(err))
+		return false
+	}
+
+	span.SetStatus(codes.Ok, "message sent to retry topic")
+	p.log.Info("message sent to retry topic",
+		zap.String("retry_topic", retryTopic),
+		zap.String("key", string(record.Key)),
+		zap.Int("attempt", attempt+1))
+	return true
+}
+
+func NewNoopRetryPublisher() RetryPublisher {
+	return noopRetryPublisher{}
+}
+
+// noopRetryPublisher is used when retry topics are disabled; failed messages go straight to the DLQ.
+type noopRetryPublisher struct{}
+
+func (noopRetryPublisher) PublishRetry(context.Context, *kgo.Record, error) bool {
+	return false
+}
+
+// retryAttempt returns the retry tier a record was consumed from, 0 for the main topic.
+func retryAttempt(record *kgo.Record) int {
+	attempt, err := strconv.Atoi(headerValue(record, HeaderRetryAttempt))
+	if err != nil || attempt < 0 {
+		return 0
+	}
+	return attempt
+}
+
+// waitUntilDue blocks until the retry.not_before time of the record has passed.
+// It returns false if ctx is done first.
+func waitUntilDue(ctx context.Context, record *kgo.Record) bool {
+	notBefore, err := strconv.ParseInt(headerValue(record, HeaderRetryNotBefore), 10, 64)
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	wait := time.Until(time.UnixMilli(notBefore))
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// headerValue returns the last value of a record header, empty if missing.
+func headerValue(record *kgo.Record, key string) string {
+	var value string
+	for _, h := range record.Headers {
+		if h.Key == key {
+			value = string(h.Value)
+		}
+	}
+	return value
+}
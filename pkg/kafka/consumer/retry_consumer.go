@@ -0,0 +1,80 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/core/worker"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/kafkaproto"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+// RetryConsumer reprocesses messages from the retry topics of a consumer once their delay passed.
+// Every tier is consumed by its own client, so a long delay of one tier does not hold back another.
+// Messages that fail again move on to the next tier or to the DLQ.
+type RetryConsumer struct {
+	workers []worker.Runnable
+}
+
+// NewRetryConsumer creates a RetryConsumer with one pipeline per client; clients[i] must
+// consume the topic of tier i. The clients are paused and rate limited with the main consumer
+// through controller.
+func NewRetryConsumer(
+	clients []*kgo.Client,
+	handler Handler,
+	deserializer kafkaproto.Deserializer,
+	tracer MessageTracer,
+	dlqHandler DLQHandler,
+	retryPublisher RetryPublisher,
+	inbox Inbox,
+	controller *Controller,
+	metrics *Metrics,
+	consumerConf config.ConsumerConfig,
+	log *zap.Logger,
+) *RetryConsumer {
+	c := &RetryConsumer{}
+	for _, client := range clients {
+		controller.AddClient(client)
+		messagesChan := make(chan *kgo.Record, consumerConf.ChannelBufferSize)
+		envelopeChan := make(chan *MessageEnvelope, consumerConf.ChannelBufferSize)
+		resultHandler := NewResultHandler(log, dlqHandler, retryPublisher, client, metrics, nil)
+
+		processor := NewProcessor(envelopeChan, handler, log, resultHandler, tracer, inbox, controller, metrics, consumerConf)
+		processor.waitForRetryDelay = true
+
+		c.workers = append(c.workers,
+			NewReader(client, messagesChan, nil, consumerConf, log),
+			NewMessageDeserializer(messagesChan, envelopeChan, deserializer, log, tracer, dlqHandler, metrics, nil),
+			processor,
+		)
+	}
+	return c
+}
+
+// Run runs the pipelines of all tiers until ctx is cancelled. The first worker failing, such as
+// a tier crashing the consumer, stops all tiers and its error is returned.
+func (c *RetryConsumer) Run(parent context.Context) error {
+	ctx, cancel := context.WithCancelCause(parent)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for _, w := range c.workers {
+		wg.Add(1)
+		go func(w worker.Runnable) {
+			defer wg.Done()
+			if err := w.Run(ctx); err != nil {
+				cancel(err)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// Without a failed worker the cause is the one of parent, i.e. a shutdown.
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Cause(parent)) {
+		return err
+	}
+	return nil
+}
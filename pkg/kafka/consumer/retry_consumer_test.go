@@ -0,0 +1,54 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/core/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runnableFunc adapts a function to worker.Runnable.
+type runnableFunc func(ctx context.Context) error
+
+func (f runnableFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+// untilDone is a worker that runs until ctx is cancelled.
+func untilDone(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func TestRetryConsumer_Run(t *testing.T) {
+	t.Run("crashing tier stops all tiers", func(t *testing.T) {
+		crash := fmt.Errorf("tier crashed: %w", ErrCrashConsumer)
+		c := &RetryConsumer{workers: []worker.Runnable{
+			runnableFunc(untilDone),
+			runnableFunc(func(context.Context) error { return crash }),
+			runnableFunc(untilDone),
+		}}
+
+		done := make(chan error, 1)
+		go func() { done <- c.Run(context.Background()) }()
+
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, ErrCrashConsumer)
+		case <-time.After(time.Second):
+			t.Fatal("Run did not return after a tier crashed")
+		}
+	})
+
+	t.Run("returns nil on shutdown", func(t *testing.T) {
+		c := &RetryConsumer{workers: []worker.Runnable{runnableFunc(untilDone), runnableFunc(untilDone)}}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		require.NoError(t, c.Run(ctx))
+	})
+}
@@ -0,0 +1,180 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+// mockRetryPublisher is a test implementation of RetryPublisher
+type mockRetryPublisher struct {
+	published bool
+	records   []*kgo.Record
+}
+
+func (m *mockRetryPublisher) PublishRetry(ctx context.Context, record *kgo.Record, processingErr error) bool {
+	m.records = append(m.records, record)
+	return m.published
+}
+
+// mockProducer is a test implementation of producer.Producer
+type mockProducer struct {
+	err     error
+	records []*kgo.Record
+}
+
+func (m *mockProducer) Produce(ctx context.Context, record *kgo.Record, promise func(*kgo.Record, error)) {
+	m.records = append(m.records, record)
+	promise(record, m.err)
+}
+
+func testRetryTiers() []RetryTier {
+	return []RetryTier{
+		{Name: "1m", Delay: time.Minute},
+		{Name: "10m", Delay: 10 * time.Minute},
+	}
+}
+
+func TestRetryTiers(t *testing.T) {
+	t.Run("returns no tiers when disabled", func(t *testing.T) {
+		conf := createTestConsumerConfig()
+		conf.RetryTopics.Delays = []time.Duration{time.Minute}
+
+		assert.Empty(t, RetryTiers(conf))
+	})
+
+	t.Run("names tier topics after their delay", func(t *testing.T) {
+		conf := createTestConsumerConfig()
+		conf.RetryTopics = config.RetryTopicsConfig{
+			Enabled: true,
+			Delays:  []time.Duration{1500 * time.Millisecond, 90 * time.Second, 10 * time.Minute, 2 * time.Hour},
+		}
+
+		tiers := RetryTiers(conf)
+
+		require.Len(t, tiers, 4)
+		assert.Equal(t, "test-topic.retry.1500ms", tiers[0].Topic("test-topic"))
+		assert.Equal(t, "test-topic.retry.90s", tiers[1].Topic("test-topic"))
+		assert.Equal(t, "test-topic.retry.10m", tiers[2].Topic("test-topic"))
+		assert.Equal(t, "payments.retry.2h", tiers[3].Topic("payments"))
+		assert.Equal(t, 2*time.Hour, tiers[3].Delay)
+	})
+}
+
+func TestRetryPublisher_PublishRetry(t *testing.T) {
+	t.Run("publishes message to first tier with retry headers", func(t *testing.T) {
+		producer := &mockProducer{}
+		publisher := NewRetryPublisher(producer, testRetryTiers(), newMockTracer(), zap.NewNop())
+		record := createTestMessage()
+		record.Headers = []kgo.RecordHeader{{Key: "event_type", Value: []byte("OrderCreated")}}
+
+		before := time.Now()
+		ok := publisher.PublishRetry(context.Background(), record, errors.New("database unavailable"))
+
+		require.True(t, ok)
+		require.Len(t, producer.records, 1)
+		sent := producer.records[0]
+		assert.Equal(t, "test-topic.retry.1m", sent.Topic)
+		assert.Equal(t, record.Key, sent.Key)
+		assert.Equal(t, record.Value, sent.Value)
+		assert.Equal(t, "OrderCreated", headerValue(sent, "event_type"))
+		assert.Equal(t, "1", headerValue(sent, HeaderRetryAttempt))
+		assert.Equal(t, "test-topic", headerValue(sent, HeaderRetryOriginalTopic))
+		assert.Equal(t, "database unavailable", headerValue(sent, HeaderRetryError))
+
+		notBefore, err := strconv.ParseInt(headerValue(sent, HeaderRetryNotBefore), 10, 64)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, notBefore, before.Add(time.Minute).UnixMilli())
+	})
+
+	t.Run("moves message from a tier to the next one", func(t *testing.T) {
+		producer := &mockProducer{}
+		publisher := NewRetryPublisher(producer, testRetryTiers(), newMockTracer(), zap.NewNop())
+		record := createTestMessage()
+		record.Topic = "test-topic.retry.1m"
+		record.Headers = []kgo.RecordHeader{
+			{Key: HeaderRetryAttempt, Value: []byte("1")},
+			{Key: HeaderRetryOriginalTopic, Value: []byte("test-topic")},
+			{Key: HeaderRetryError, Value: []byte("first failure")},
+		}
+
+		require.True(t, publisher.PublishRetry(context.Background(), record, errors.New("second failure")))
+
+		sent := producer.records[0]
+		assert.Equal(t, "test-topic.retry.10m", sent.Topic)
+		assert.Equal(t, "2", headerValue(sent, HeaderRetryAttempt))
+		assert.Equal(t, "test-topic", headerValue(sent, HeaderRetryOriginalTopic))
+		assert.Equal(t, "second failure", headerValue(sent, HeaderRetryError))
+		assert.Len(t, sent.Headers, 4, "previous retry headers must be replaced")
+	})
+
+	t.Run("returns false when all tiers are exhausted", func(t *testing.T) {
+		producer := &mockProducer{}
+		publisher := NewRetryPublisher(producer, testRetryTiers(), newMockTracer(), zap.NewNop())
+		record := createTestMessage()
+		record.Headers = []kgo.RecordHeader{{Key: HeaderRetryAttempt, Value: []byte("2")}}
+
+		assert.False(t, publisher.PublishRetry(context.Background(), record, assert.AnError))
+		assert.Empty(t, producer.records)
+	})
+
+	t.Run("returns false when produce fails", func(t *testing.T) {
+		producer := &mockProducer{err: errors.New("broker unavailable")}
+		publisher := NewRetryPublisher(producer, testRetryTiers(), newMockTracer(), zap.NewNop())
+
+		assert.False(t, publisher.PublishRetry(context.Background(), createTestMessage(), assert.AnError))
+	})
+
+	t.Run("keeps tiers of each source topic apart", func(t *testing.T) {
+		producer := &mockProducer{}
+		publisher := NewRetryPublisher(producer, testRetryTiers(), newMockTracer(), zap.NewNop())
+		record := createTestMessage()
+		record.Topic = "payments"
+
+		require.True(t, publisher.PublishRetry(context.Background(), record, assert.AnError))
+
+		assert.Equal(t, "payments.retry.1m", producer.records[0].Topic)
+	})
+
+	t.Run("noop publisher never publishes", func(t *testing.T) {
+		assert.False(t, NewNoopRetryPublisher().PublishRetry(context.Background(), createTestMessage(), assert.AnError))
+	})
+}
+
+func TestWaitUntilDue(t *testing.T) {
+	withNotBefore := func(at time.Time) *kgo.Record {
+		record := createTestMessage()
+		record.Headers = []kgo.RecordHeader{{Key: HeaderRetryNotBefore, Value: []byte(strconv.FormatInt(at.UnixMilli(), 10))}}
+		return record
+	}
+
+	t.Run("returns immediately without header", func(t *testing.T) {
+		assert.True(t, waitUntilDue(context.Background(), createTestMessage()))
+	})
+
+	t.Run("returns immediately when due", func(t *testing.T) {
+		assert.True(t, waitUntilDue(context.Background(), withNotBefore(time.Now().Add(-time.Second))))
+	})
+
+	t.Run("waits until not_before", func(t *testing.T) {
+		notBefore := time.Now().Add(50 * time.Millisecond)
+
+		assert.True(t, waitUntilDue(context.Background(), withNotBefore(notBefore)))
+		assert.False(t, time.Now().Before(notBefore.Truncate(time.Millisecond)))
+	})
+
+	t.Run("returns false when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		assert.False(t, waitUntilDue(ctx, withNotBefore(time.Now().Add(time.Hour))))
+	})
+}
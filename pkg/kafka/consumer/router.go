@@ -2,46 +2,194 @@ package consumer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
+// EventHandlerFunc handles an event dispatched by Router.
+type EventHandlerFunc func(ctx context.Context, event proto.Message) error
+
+// Middleware wraps every dispatch of a Router, including events without a handler.
+type Middleware func(next EventHandlerFunc) EventHandlerFunc
+
+// prefixRoute is a handler for events whose proto full name starts with prefix.
+type prefixRoute struct {
+	prefix  string
+	handler EventHandlerFunc
+}
+
 // Router dispatches events to registered typed handler functions.
-// It implements the Handler interface.
+// It implements the Handler interface. Events are dispatched by type regardless of the topic
+// they were consumed from, so one Router serves a consumer of several topics.
+//
+// An event goes to the handler of its Go type, else of its proto full name, else of the longest
+// matching full name prefix, else to the fallback handler. Without any of them it is skipped.
 type Router struct {
-	handlers map[reflect.Type]func(ctx context.Context, event any) error
-	log      *zap.Logger
+	handlers   map[reflect.Type]EventHandlerFunc
+	typeNames  map[protoreflect.FullName]struct{} // Full names of the types in handlers
+	names      map[protoreflect.FullName]EventHandlerFunc
+	prefixes   []prefixRoute // Longest prefix first
+	fallback   EventHandlerFunc
+	middleware []Middleware
+	chain      EventHandlerFunc // dispatch wrapped in middleware, rebuilt by Use
+	classifier ErrorClassifier
+	txManager  mongo.TxManager
+	log        *zap.Logger
 }
 
 // NewRouter creates a new Router instance.
 func NewRouter(log *zap.Logger) *Router {
-	return &Router{
-		handlers: make(map[reflect.Type]func(ctx context.Context, event any) error),
-		log:      log,
+	r := &Router{
+		handlers:  make(map[reflect.Type]EventHandlerFunc),
+		typeNames: make(map[protoreflect.FullName]struct{}),
+		names:     make(map[protoreflect.FullName]EventHandlerFunc),
+		log:       log,
 	}
+	r.chain = r.dispatch
+	return r
 }
 
 // Register adds a typed handler function for event type E.
 // The handler will be called when an event of type *E is dispatched.
 func Register[E any](r *Router, fn func(context.Context, *E) error) {
 	eventType := reflect.TypeOf((*E)(nil))
-	r.handlers[eventType] = func(ctx context.Context, event any) error {
-		return fn(ctx, event.(*E)) //nolint:errcheck // type guaranteed by reflect lookup in Process
+	r.handlers[eventType] = func(ctx context.Context, event proto.Message) error {
+		return fn(ctx, any(event).(*E)) //nolint:errcheck // type guaranteed by reflect lookup in Process
+	}
+	if m, ok := any(new(E)).(proto.Message); ok {
+		r.typeNames[m.ProtoReflect().Descriptor().FullName()] = struct{}{}
+	}
+}
+
+// HandleName adds a handler for events with the proto full name, e.g. "tenant.v1.TenantCreatedEvent".
+func (r *Router) HandleName(fullName string, fn EventHandlerFunc) {
+	r.names[protoreflect.FullName(fullName)] = fn
+}
+
+// HandlePrefix adds a handler for events whose proto full name starts with prefix,
+// e.g. "tenant.v1." for every event of the package.
+func (r *Router) HandlePrefix(prefix string, fn EventHandlerFunc) {
+	r.prefixes = slices.DeleteFunc(r.prefixes, func(p prefixRoute) bool { return p.prefix == prefix })
+	r.prefixes = append(r.prefixes, prefixRoute{prefix: prefix, handler: fn})
+	slices.SortStableFunc(r.prefixes, func(a, b prefixRoute) int { return len(b.prefix) - len(a.prefix) })
+}
+
+// HandleFallback sets the handler of events no other handler matches, instead of skipping them.
+func (r *Router) HandleFallback(fn EventHandlerFunc) {
+	r.fallback = fn
+}
+
+// Use appends middleware. The first middleware is the outermost one.
+// Like the handlers, middleware must be added before the Router processes events.
+func (r *Router) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+
+	chain := r.dispatch
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		chain = r.middleware[i](chain)
 	}
+	r.chain = chain
+}
+
+// SetErrorClassifier classifies the errors of the handlers with classifier, e.g.
+// DefaultErrorClassifier. Without one the errors are not classified.
+func (r *Router) SetErrorClassifier(classifier ErrorClassifier) {
+	r.classifier = classifier
+}
+
+// ClassifyError implements ErrorClassifier with the classifier set by SetErrorClassifier.
+func (r *Router) ClassifyError(err error) ErrorDecision {
+	if r.classifier == nil {
+		return DecisionDefault
+	}
+	return r.classifier.ClassifyError(err)
+}
+
+// SetTxManager processes each event in a transaction of txManager together with its inbox
+// record, see TransactionalHandler. The handlers must write through the context they receive.
+func (r *Router) SetTxManager(txManager mongo.TxManager) {
+	r.txManager = txManager
 }
 
-// Process implements Handler. It dispatches the event to the registered handler
-// based on its type. Returns ErrSkipMessage if no handler is registered.
+// TxManager implements TransactionalHandler with the manager set by SetTxManager, nil if none.
+func (r *Router) TxManager() mongo.TxManager {
+	return r.txManager
+}
+
+// Handles reports whether an event with the proto full name has a handler other than the fallback.
+func (r *Router) Handles(fullName string) bool {
+	name := protoreflect.FullName(fullName)
+	if _, ok := r.typeNames[name]; ok {
+		return true
+	}
+	if _, ok := r.names[name]; ok {
+		return true
+	}
+	return r.prefixHandler(fullName) != nil
+}
+
+// Process implements Handler. It dispatches the event through the middleware to the handler
+// registered for it. Returns ErrSkipMessage if no handler is registered.
 func (r *Router) Process(ctx context.Context, event proto.Message) error {
-	eventType := reflect.TypeOf(event)
-	handler, ok := r.handlers[eventType]
+	return r.chain(ctx, event)
+}
+
+func (r *Router) dispatch(ctx context.Context, event proto.Message) error {
+	if handler, ok := r.handlers[reflect.TypeOf(event)]; ok {
+		return handler(ctx, event)
+	}
+	name := event.ProtoReflect().Descriptor().FullName()
+	if handler, ok := r.names[name]; ok {
+		return handler(ctx, event)
+	}
+	if handler := r.prefixHandler(string(name)); handler != nil {
+		return handler(ctx, event)
+	}
+	if r.fallback != nil {
+		return r.fallback(ctx, event)
+	}
+	r.log.Warn("no handler registered for event type, skipping",
+		zap.String("type", fmt.Sprintf("%T", event)))
+	return fmt.Errorf("no handler for event type %T: %w", event, ErrSkipMessage)
+}
+
+func (r *Router) prefixHandler(fullName string) EventHandlerFunc {
+	for _, p := range r.prefixes {
+		if strings.HasPrefix(fullName, p.prefix) {
+			return p.handler
+		}
+	}
+	return nil
+}
+
+// eventTypeHandler is a Handler that reports the event types it routes, such as Router.
+type eventTypeHandler interface {
+	Handles(fullName string) bool
+}
+
+// CheckEventTypes returns an error listing the event types handler routes to no handler.
+// Handlers that don't report their event types, unlike Router, are not checked.
+func CheckEventTypes(handler Handler, eventTypes []string) error {
+	router, ok := handler.(eventTypeHandler)
 	if !ok {
-		r.log.Warn("no handler registered for event type, skipping",
-			zap.String("type", fmt.Sprintf("%T", event)))
-		return fmt.Errorf("no handler for event type %T: %w", event, ErrSkipMessage)
+		return nil
+	}
+	var missing []string
+	for _, eventType := range eventTypes {
+		if !router.Handles(eventType) {
+			missing = append(missing, eventType)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.New("no handler registered for event types: " + strings.Join(missing, ", "))
 	}
-	return handler(ctx, event)
+	return nil
 }
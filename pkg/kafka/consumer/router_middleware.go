@@ -0,0 +1,130 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// recordKey is the context key for storing the consumed record.
+type recordKey struct{}
+
+// contextWithRecord returns a new context with the record the event was consumed from.
+func contextWithRecord(ctx context.Context, record *kgo.Record) context.Context {
+	return context.WithValue(ctx, recordKey{}, record)
+}
+
+// RecordFromContext retrieves the record an event was consumed from, e.g. for its headers.
+// It is set for Handler calls by the consumer.
+func RecordFromContext(ctx context.Context) (*kgo.Record, bool) {
+	record, ok := ctx.Value(recordKey{}).(*kgo.Record)
+	return record, ok && record != nil
+}
+
+func eventTypeOf(event proto.Message) string {
+	return string(event.ProtoReflect().Descriptor().FullName())
+}
+
+// LoggingMiddleware logs every dispatched event at debug level with its duration and error.
+func LoggingMiddleware(log *zap.Logger) Middleware {
+	return func(next EventHandlerFunc) EventHandlerFunc {
+		return func(ctx context.Context, event proto.Message) error {
+			start := time.Now()
+			err := next(ctx, event)
+			log.Debug("event handled",
+				zap.String("event_type", eventTypeOf(event)),
+				zap.Duration("duration", time.Since(start)),
+				zap.Error(err))
+			return err
+		}
+	}
+}
+
+// MetricsMiddleware records the handling duration of every dispatched event on the
+// "kafka.consumer" meter, by consumer, event type and outcome. The outcome is success,
+// skipped or error; handler errors are classified after the middleware.
+func MetricsMiddleware(meterProvider metric.MeterProvider, consumerName string) (Middleware, error) {
+	duration, err := meterProvider.Meter("kafka.consumer").Float64Histogram(MetricEventDuration,
+		metric.WithDescription("Time to handle a routed event."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s histogram: %w", MetricEventDuration, err)
+	}
+	consumer := attribute.String(MetricAttributeConsumer, consumerName)
+
+	return func(next EventHandlerFunc) EventHandlerFunc {
+		return func(ctx context.Context, event proto.Message) error {
+			start := time.Now()
+			err := next(ctx, event)
+
+			outcome := OutcomeSuccess
+			switch {
+			case errors.Is(err, ErrSkipMessage):
+				outcome = OutcomeSkipped
+			case err != nil:
+				outcome = OutcomeError
+			}
+			duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(consumer,
+				attribute.String(MetricAttributeEventType, eventTypeOf(event)),
+				attribute.String(MetricAttributeOutcome, outcome)))
+			return err
+		}
+	}, nil
+}
+
+// TenantMiddleware sends events without a tenant in their headers to the DLQ,
+// for consumers whose handlers require a tenant.
+func TenantMiddleware() Middleware {
+	return func(next EventHandlerFunc) EventHandlerFunc {
+		return func(ctx context.Context, event proto.Message) error {
+			if _, ok := tenant.SlugFromContext(ctx); !ok {
+				return fmt.Errorf("event %s has no tenant: %w", eventTypeOf(event), ErrPermanent)
+			}
+			return next(ctx, event)
+		}
+	}
+}
+
+// IdempotencyMiddleware skips events the consumer already handled according to inbox, and
+// records events once handled. Events are identified by the event_id header; events without
+// it are always handled. Unlike the inbox module, which covers every event of a consumer, the
+// middleware can be applied to the events of a single Router.
+func IdempotencyMiddleware(inbox Inbox, consumerName string, log *zap.Logger) Middleware {
+	return func(next EventHandlerFunc) EventHandlerFunc {
+		return func(ctx context.Context, event proto.Message) error {
+			record, ok := RecordFromContext(ctx)
+			if !ok {
+				return next(ctx, event)
+			}
+			eventID := headerValue(record, HeaderEventID)
+			if eventID == "" {
+				return next(ctx, event)
+			}
+
+			seen, err := inbox.Seen(ctx, consumerName, eventID)
+			if err != nil {
+				log.Warn("failed to check inbox, handling event", zap.String("event_id", eventID), zap.Error(err))
+			} else if seen {
+				return fmt.Errorf("event %s already processed: %w", eventID, ErrSkipMessage)
+			}
+
+			if err = next(ctx, event); err != nil {
+				return err
+			}
+			if err = inbox.Record(ctx, consumerName, eventID, record.Topic); err != nil && !errors.Is(err, ErrSkipMessage) {
+				// The event was handled; at worst a redelivery is handled again.
+				log.Warn("failed to record event in inbox", zap.String("event_id", eventID), zap.Error(err))
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	handle := TenantMiddleware()(func(context.Context, proto.Message) error { return nil })
+
+	assert.ErrorIs(t, handle(context.Background(), &emptypb.Empty{}), ErrPermanent)
+	assert.NoError(t, handle(tenant.ContextWithSlug(context.Background(), "acme"), &emptypb.Empty{}))
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	inbox := &mockInbox{seen: map[string]bool{"test-consumer/evt-1": true}}
+	var calls int
+	handle := IdempotencyMiddleware(inbox, "test-consumer", zap.NewNop())(func(context.Context, proto.Message) error {
+		calls++
+		return nil
+	})
+	ctxWithEvent := func(eventID string) context.Context {
+		record := &kgo.Record{Topic: "test-topic", Headers: []kgo.RecordHeader{{Key: HeaderEventID, Value: []byte(eventID)}}}
+		return contextWithRecord(context.Background(), record)
+	}
+
+	t.Run("skips handled events", func(t *testing.T) {
+		assert.ErrorIs(t, handle(ctxWithEvent("evt-1"), &emptypb.Empty{}), ErrSkipMessage)
+		assert.Zero(t, calls)
+	})
+
+	t.Run("records new events", func(t *testing.T) {
+		require.NoError(t, handle(ctxWithEvent("evt-2"), &emptypb.Empty{}))
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, []string{"test-consumer/evt-2"}, inbox.recorded)
+	})
+
+	t.Run("handles events without id", func(t *testing.T) {
+		require.NoError(t, handle(context.Background(), &emptypb.Empty{}))
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mw, err := MetricsMiddleware(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "test-consumer")
+	require.NoError(t, err)
+
+	results := []error{nil, ErrSkipMessage, errors.New("boom")}
+	for _, result := range results {
+		_ = mw(func(context.Context, proto.Message) error { return result })(context.Background(), &emptypb.Empty{}) //nolint:errcheck // only the recorded metric is checked
+	}
+
+	hist, ok := collectMetric(t, reader, MetricEventDuration).(metricdata.Histogram[float64])
+	require.True(t, ok)
+	outcomes := make(map[string]uint64)
+	for _, dp := range hist.DataPoints {
+		assert.Equal(t, "google.protobuf.Empty", attributeOf(dp.Attributes, MetricAttributeEventType))
+		outcomes[attributeOf(dp.Attributes, MetricAttributeOutcome)] = dp.Count
+	}
+	assert.Equal(t, map[string]uint64{OutcomeSuccess: 1, OutcomeSkipped: 1, OutcomeError: 1}, outcomes)
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
@@ -88,3 +90,105 @@ func TestRouter_Process(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestRouter_Routes(t *testing.T) {
+	var handled []string
+	handledBy := func(name string) EventHandlerFunc {
+		return func(context.Context, proto.Message) error {
+			handled = append(handled, name)
+			return nil
+		}
+	}
+
+	r := NewRouter(zap.NewNop())
+	Register(r, func(context.Context, *emptypb.Empty) error {
+		handled = append(handled, "typed")
+		return nil
+	})
+	r.HandleName("google.protobuf.StringValue", handledBy("name"))
+	r.HandlePrefix("google.", handledBy("short prefix"))
+	r.HandlePrefix("google.protobuf.", handledBy("long prefix"))
+
+	t.Run("prefers type, then name, then longest prefix", func(t *testing.T) {
+		handled = nil
+		require.NoError(t, r.Process(context.Background(), &emptypb.Empty{}))
+		require.NoError(t, r.Process(context.Background(), &wrapperspb.StringValue{}))
+		require.NoError(t, r.Process(context.Background(), &durationpb.Duration{}))
+		assert.Equal(t, []string{"typed", "name", "long prefix"}, handled)
+	})
+
+	t.Run("sends unmatched events to the fallback", func(t *testing.T) {
+		r := NewRouter(zap.NewNop())
+		r.HandleFallback(handledBy("fallback"))
+
+		handled = nil
+		require.NoError(t, r.Process(context.Background(), &durationpb.Duration{}))
+		assert.Equal(t, []string{"fallback"}, handled)
+	})
+
+	t.Run("reports handled event types", func(t *testing.T) {
+		assert.True(t, r.Handles("google.protobuf.Empty"))
+		assert.True(t, r.Handles("google.protobuf.StringValue"))
+		assert.True(t, r.Handles("google.protobuf.Int64Value"))
+		assert.False(t, r.Handles("tenant.v1.TenantCreatedEvent"))
+	})
+}
+
+func TestRouter_Middleware(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next EventHandlerFunc) EventHandlerFunc {
+			return func(ctx context.Context, event proto.Message) error {
+				calls = append(calls, name+" before")
+				err := next(ctx, event)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+
+	r := NewRouter(zap.NewNop())
+	r.Use(trace("outer"), trace("inner"))
+	Register(r, func(context.Context, *emptypb.Empty) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+
+	require.NoError(t, r.Process(context.Background(), &emptypb.Empty{}))
+	assert.Equal(t, []string{"outer before", "inner before", "handler", "inner after", "outer after"}, calls)
+
+	t.Run("wraps events without handler", func(t *testing.T) {
+		calls = nil
+		err := r.Process(context.Background(), &wrapperspb.StringValue{})
+		assert.ErrorIs(t, err, ErrSkipMessage)
+		assert.Equal(t, []string{"outer before", "inner before", "inner after", "outer after"}, calls)
+	})
+
+	t.Run("builds the chain once", func(t *testing.T) {
+		var built int
+		r := NewRouter(zap.NewNop())
+		r.Use(func(next EventHandlerFunc) EventHandlerFunc {
+			built++
+			return next
+		})
+		Register(r, func(context.Context, *emptypb.Empty) error { return nil })
+
+		require.NoError(t, r.Process(context.Background(), &emptypb.Empty{}))
+		require.NoError(t, r.Process(context.Background(), &emptypb.Empty{}))
+		assert.Equal(t, 1, built)
+	})
+}
+
+func TestCheckEventTypes(t *testing.T) {
+	r := NewRouter(zap.NewNop())
+	Register(r, func(context.Context, *emptypb.Empty) error { return nil })
+	r.HandleFallback(func(context.Context, proto.Message) error { return nil })
+
+	require.NoError(t, CheckEventTypes(r, []string{"google.protobuf.Empty"}))
+
+	err := CheckEventTypes(r, []string{"google.protobuf.Empty", "tenant.v1.TenantCreatedEvent"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tenant.v1.TenantCreatedEvent")
+
+	assert.NoError(t, CheckEventTypes(&mockHandler{}, []string{"tenant.v1.TenantCreatedEvent"}), "other handlers are not checked")
+}
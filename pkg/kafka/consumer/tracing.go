@@ -21,6 +21,9 @@ type MessageTracer interface {
 	// StartDLQSpan створює span для відправки в DLQ
 	StartDLQSpan(ctx context.Context, record *kgo.Record, dlqTopic string) (context.Context, trace.Span)
 
+	// StartRetrySpan створює span для відправки в retry topic
+	StartRetrySpan(ctx context.Context, record *kgo.Record, retryTopic string) (context.Context, trace.Span)
+
 	// InjectContext додає trace context в Kafka headers
 	InjectContext(ctx context.Context, record *kgo.Record)
 }
@@ -78,6 +81,20 @@ func (t *messageTracer) StartDLQSpan(ctx context.Context, record *kgo.Record, dl
 	)
 }
 
+func (t *messageTracer) StartRetrySpan(ctx context.Context, record *kgo.Record, retryTopic string) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "kafka.send_to_retry",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination", retryTopic),
+			attribute.String("messaging.source.topic", record.Topic),
+			attribute.Int("messaging.source.partition", int(record.Partition)),
+			attribute.Int64("messaging.source.offset", record.Offset),
+			attribute.String("messaging.message.key", string(record.Key)),
+		),
+	)
+}
+
 func (t *messageTracer) InjectContext(ctx context.Context, record *kgo.Record) {
 	// Конвертуємо існуючі headers в map
 	headersMap := make(map[string]string)
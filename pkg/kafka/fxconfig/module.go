@@ -2,6 +2,7 @@ package fxconfig
 
 import (
 	fx_kafka_config "github.com/Sokol111/ecommerce-commons/pkg/kafka/config/fxconfig"
+	fx_consumer "github.com/Sokol111/ecommerce-commons/pkg/kafka/consumer/fxconfig"
 	fx_kafkaproto "github.com/Sokol111/ecommerce-commons/pkg/kafka/kafkaproto/fxconfig"
 	fx_outbox "github.com/Sokol111/ecommerce-commons/pkg/kafka/outbox/fxconfig"
 	fx_producer "github.com/Sokol111/ecommerce-commons/pkg/kafka/producer/fxconfig"
@@ -15,5 +16,6 @@ func NewKafkaModule() fx.Option {
 		fx_producer.NewProducerModule(),
 		fx_kafkaproto.NewProtoModule(),
 		fx_outbox.NewOutboxModule(),
+		fx.Provide(fx.Annotate(fx_consumer.MetricViews, fx.ResultTags(`group:"metric_views,flatten"`))),
 	)
 }
@@ -0,0 +1,39 @@
+package inbox
+
+import (
+	"fmt"
+	"time"
+)
+
+// minRetention keeps events long enough to catch redeliveries after a consumer group rebalance.
+const minRetention = time.Minute
+
+// Config holds the inbox configuration.
+type Config struct {
+	// Retention is how long processed events are remembered. Redeliveries of older events
+	// are processed again, so it should exceed the longest expected redelivery delay.
+	// Default: 7 days
+	Retention time.Duration `koanf:"retention"`
+
+	// Collection is the MongoDB collection of processed events.
+	// Default: "inbox"
+	Collection string `koanf:"collection"`
+}
+
+// ApplyDefaults sets default values for unset configuration fields.
+func (c *Config) ApplyDefaults() {
+	if c.Retention <= 0 {
+		c.Retention = 7 * 24 * time.Hour
+	}
+	if c.Collection == "" {
+		c.Collection = defaultCollectionName
+	}
+}
+
+// Validate validates the inbox configuration.
+func (c *Config) Validate() error {
+	if c.Retention < minRetention {
+		return fmt.Errorf("inbox retention must be at least %s, got: %s", minRetention, c.Retention)
+	}
+	return nil
+}
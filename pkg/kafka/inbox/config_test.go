@@ -0,0 +1,26 @@
+package inbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("applies defaults", func(t *testing.T) {
+		cfg := Config{}
+		cfg.ApplyDefaults()
+
+		assert.Equal(t, 7*24*time.Hour, cfg.Retention)
+		assert.Equal(t, "inbox", cfg.Collection)
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("rejects short retention", func(t *testing.T) {
+		cfg := Config{Retention: time.Second}
+		cfg.ApplyDefaults()
+
+		assert.Error(t, cfg.Validate())
+	})
+}
@@ -0,0 +1,53 @@
+package fxconfig
+
+import (
+	"context"
+
+	coreconfig "github.com/Sokol111/ecommerce-commons/pkg/core/config"
+	"github.com/Sokol111/ecommerce-commons/pkg/core/health"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/inbox"
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// NewInboxModule provides a consumer.Inbox, which makes the consumers registered with
+// RegisterHandlerAndConsumer skip events they already processed.
+func NewInboxModule() fx.Option {
+	return fx.Module("inbox",
+		fx.Decorate(
+			func(log *zap.Logger) *zap.Logger {
+				return log.With(zap.String("component", "inbox"))
+			},
+		),
+		fx.Provide(
+			provideConfig,
+			fx.Private,
+		),
+		fx.Provide(
+			inbox.NewInbox,
+		),
+		fx.Invoke(
+			ensureSchema,
+		),
+	)
+}
+
+func provideConfig(loader *coreconfig.Loader) (inbox.Config, error) {
+	return coreconfig.Load[inbox.Config](loader, "inbox", nil)
+}
+
+func ensureSchema(lc fx.Lifecycle, log *zap.Logger, cfg inbox.Config, database *mongodriver.Database, readiness health.ComponentManager) {
+	markReady := readiness.AddComponent("inbox-schema")
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			log.Info("ensuring inbox indexes")
+			if err := inbox.EnsureIndexes(ctx, database, cfg); err != nil {
+				return err
+			}
+			log.Info("inbox indexes ready")
+			markReady()
+			return nil
+		},
+	})
+}
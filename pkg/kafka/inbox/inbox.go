@@ -0,0 +1,111 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/consumer"
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultCollectionName = "inbox"
+
+	// Index names.
+	idxProcessedAtTTL = "inbox_processedAt_ttl"
+)
+
+// processedEvent is the inbox document of an event processed by a consumer.
+type processedEvent struct {
+	ID          string    `bson:"_id"`
+	Consumer    string    `bson:"consumer"`
+	EventID     string    `bson:"eventId"`
+	Topic       string    `bson:"topic"`
+	ProcessedAt time.Time `bson:"processedAt"`
+}
+
+type mongoInbox struct {
+	coll *mongodriver.Collection
+}
+
+// NewInbox creates a consumer.Inbox backed by a MongoDB collection of database. Records expire
+// after the configured retention through a TTL index created by EnsureIndexes.
+func NewInbox(database *mongodriver.Database, cfg Config) consumer.Inbox {
+	return &mongoInbox{coll: database.Collection(cfg.Collection)}
+}
+
+// documentID scopes the event id to the consumer, so consumers of the same event do not skip each other.
+func documentID(consumerName, eventID string) string {
+	return consumerName + "/" + eventID
+}
+
+func (i *mongoInbox) Seen(ctx context.Context, consumerName, eventID string) (bool, error) {
+	err := i.coll.FindOne(ctx, bson.M{"_id": documentID(consumerName, eventID)},
+		options.FindOne().SetProjection(bson.M{"_id": 1})).Err()
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to find inbox event %s: %w", eventID, err)
+	}
+	return true, nil
+}
+
+func (i *mongoInbox) Record(ctx context.Context, consumerName, eventID, topic string) error {
+	_, err := i.coll.InsertOne(ctx, processedEvent{
+		ID:          documentID(consumerName, eventID),
+		Consumer:    consumerName,
+		EventID:     eventID,
+		Topic:       topic,
+		ProcessedAt: time.Now().UTC(),
+	})
+	if mongodriver.IsDuplicateKeyError(err) {
+		return fmt.Errorf("event %s already recorded in inbox: %w", eventID, consumer.ErrSkipMessage)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert inbox event %s: %w", eventID, err)
+	}
+	return nil
+}
+
+// EnsureIndexes creates the TTL index that expires processed events after the retention.
+// It is idempotent; a changed retention replaces the index.
+func EnsureIndexes(ctx context.Context, database *mongodriver.Database, cfg Config) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	coll := database.Collection(cfg.Collection)
+	seconds := int32(cfg.Retention / time.Second) //nolint:gosec // retention periods are far below the int32 range
+
+	specs, err := coll.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list indexes: %w", err)
+	}
+	for _, spec := range specs {
+		if spec.Name != idxProcessedAtTTL {
+			continue
+		}
+		if spec.ExpireAfterSeconds != nil && *spec.ExpireAfterSeconds == seconds {
+			return nil
+		}
+		if err = mongo.DropIndexIfExists(ctx, coll, idxProcessedAtTTL); err != nil {
+			return err
+		}
+	}
+
+	_, err = coll.Indexes().CreateOne(ctx, mongodriver.IndexModel{
+		Keys: bson.D{{Key: "processedAt", Value: 1}},
+		Options: options.Index().
+			SetName(idxProcessedAtTTL).
+			SetExpireAfterSeconds(seconds),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create index %s: %w", idxProcessedAtTTL, err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+//go:build integration
+
+package inbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/core/logger"
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/consumer"
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
+	"github.com/Sokol111/ecommerce-commons/pkg/testutil/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+var testMongo *container.MongoDBContainer
+
+func TestMain(m *testing.M) {
+	testMongo = container.StartDefaultMongoDBContainer()
+	code := m.Run()
+	_ = testMongo.Terminate()
+	os.Exit(code)
+}
+
+func testConfig() Config {
+	cfg := Config{}
+	cfg.ApplyDefaults()
+	return cfg
+}
+
+func TestInbox(t *testing.T) {
+	ctx := context.Background()
+	db := testMongo.Database("inbox_test")
+	cfg := testConfig()
+	require.NoError(t, EnsureIndexes(ctx, db, cfg))
+
+	inbox := NewInbox(db, cfg)
+
+	t.Run("records and finds processed events", func(t *testing.T) {
+		seen, err := inbox.Seen(ctx, "orders-consumer", "event-1")
+		require.NoError(t, err)
+		assert.False(t, seen)
+
+		require.NoError(t, inbox.Record(ctx, "orders-consumer", "event-1", "orders"))
+
+		seen, err = inbox.Seen(ctx, "orders-consumer", "event-1")
+		require.NoError(t, err)
+		assert.True(t, seen)
+	})
+
+	t.Run("rejects duplicates as skipped messages", func(t *testing.T) {
+		err := inbox.Record(ctx, "orders-consumer", "event-1", "orders")
+		assert.ErrorIs(t, err, consumer.ErrSkipMessage)
+	})
+
+	t.Run("scopes events to the consumer", func(t *testing.T) {
+		seen, err := inbox.Seen(ctx, "billing-consumer", "event-1")
+		require.NoError(t, err)
+		assert.False(t, seen)
+
+		require.NoError(t, inbox.Record(ctx, "billing-consumer", "event-1", "orders"))
+	})
+}
+
+func TestInbox_Transaction(t *testing.T) {
+	ctx := logger.With(context.Background(), zap.NewNop())
+	db := testMongo.Database("inbox_tx_test")
+	cfg := testConfig()
+	require.NoError(t, EnsureIndexes(ctx, db, cfg))
+
+	inbox := NewInbox(db, cfg)
+	txManager := mongo.NewTxManager(testMongo.Client, zap.NewNop())
+	orders := db.Collection("orders")
+
+	t.Run("rolls back the record with a failed handler", func(t *testing.T) {
+		_, err := txManager.WithTransaction(ctx, func(txCtx context.Context) (any, error) {
+			if _, insertErr := orders.InsertOne(txCtx, bson.M{"_id": "order-1"}); insertErr != nil {
+				return nil, insertErr
+			}
+			if recordErr := inbox.Record(txCtx, "orders-consumer", "event-1", "orders"); recordErr != nil {
+				return nil, recordErr
+			}
+			return nil, errors.New("handler failed")
+		})
+		require.Error(t, err)
+
+		seen, err := inbox.Seen(ctx, "orders-consumer", "event-1")
+		require.NoError(t, err)
+		assert.False(t, seen)
+	})
+
+	t.Run("rolls back the handler write with a duplicate", func(t *testing.T) {
+		require.NoError(t, inbox.Record(ctx, "orders-consumer", "event-2", "orders"))
+
+		_, err := txManager.WithTransaction(ctx, func(txCtx context.Context) (any, error) {
+			if _, insertErr := orders.InsertOne(txCtx, bson.M{"_id": "order-2"}); insertErr != nil {
+				return nil, insertErr
+			}
+			return nil, inbox.Record(txCtx, "orders-consumer", "event-2", "orders")
+		})
+		assert.ErrorIs(t, err, consumer.ErrSkipMessage)
+
+		count, err := orders.CountDocuments(ctx, bson.M{"_id": "order-2"})
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+}
+
+func TestEnsureIndexes_RetentionChange(t *testing.T) {
+	ctx := context.Background()
+	db := testMongo.Database("inbox_schema_test")
+	cfg := testConfig()
+	require.NoError(t, EnsureIndexes(ctx, db, cfg))
+	require.NoError(t, EnsureIndexes(ctx, db, cfg), "must be idempotent")
+
+	cfg.Retention = time.Hour
+	require.NoError(t, EnsureIndexes(ctx, db, cfg))
+
+	specs, err := db.Collection(cfg.Collection).Indexes().ListSpecifications(ctx)
+	require.NoError(t, err)
+	for _, spec := range specs {
+		if spec.Name == idxProcessedAtTTL {
+			require.NotNil(t, spec.ExpireAfterSeconds)
+			assert.Equal(t, int32(3600), *spec.ExpireAfterSeconds)
+			return
+		}
+	}
+	t.Fatal("TTL index not found")
+}
@@ -14,6 +14,10 @@ type HeaderPopulator interface {
 	// into the provided headers map. Returns the generated event_id.
 	// Trace context is propagated separately via W3C traceparent header.
 	PopulateHeaders(event proto.Message, headers map[string]string) string
+
+	// PopulateMetadata is PopulateHeaders for payloads that are not proto messages.
+	// eventType is written as event_type as is; an empty eventType leaves the header unset.
+	PopulateMetadata(eventType string, headers map[string]string) string
 }
 
 type headerPopulator struct {
@@ -26,9 +30,15 @@ func NewHeaderPopulator(source string) HeaderPopulator {
 }
 
 func (p *headerPopulator) PopulateHeaders(event proto.Message, headers map[string]string) string {
+	return p.PopulateMetadata(string(event.ProtoReflect().Descriptor().FullName()), headers)
+}
+
+func (p *headerPopulator) PopulateMetadata(eventType string, headers map[string]string) string {
 	eventID := uuid.New().String()
 	headers["event_id"] = eventID
-	headers["event_type"] = string(event.ProtoReflect().Descriptor().FullName())
+	if eventType != "" {
+		headers["event_type"] = eventType
+	}
 	headers["source"] = p.source
 	headers["timestamp"] = strconv.FormatInt(time.Now().UTC().UnixMilli(), 10)
 
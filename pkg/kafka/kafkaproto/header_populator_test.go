@@ -54,4 +54,25 @@ func TestHeaderPopulator_PopulateHeaders(t *testing.T) {
 
 		assert.Equal(t, "google.protobuf.Empty", headers["event_type"])
 	})
+
+	t.Run("populates metadata for non-proto payloads", func(t *testing.T) {
+		populator := NewHeaderPopulator("svc")
+		headers := make(map[string]string)
+
+		eventID := populator.PopulateMetadata("com.example.order.created", headers)
+
+		assert.Equal(t, eventID, headers["event_id"])
+		assert.Equal(t, "com.example.order.created", headers["event_type"])
+		assert.Equal(t, "svc", headers["source"])
+		assert.NotEmpty(t, headers["timestamp"])
+	})
+
+	t.Run("leaves event_type unset for empty type", func(t *testing.T) {
+		populator := NewHeaderPopulator("svc")
+		headers := make(map[string]string)
+
+		populator.PopulateMetadata("", headers)
+
+		assert.NotContains(t, headers, "event_type")
+	})
 }
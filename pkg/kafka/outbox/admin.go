@@ -0,0 +1,182 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/kafkaproto"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// ErrMessageNotFound is returned by Admin when the requested outbox message does not exist
+// or is not in a status that allows the operation.
+var ErrMessageNotFound = errors.New("outbox message not found")
+
+// ListFilter narrows down the messages returned by Admin.List.
+// Zero values are ignored.
+type ListFilter struct {
+	Status        string
+	Topic         string
+	Key           string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// MinAttempts selects messages with at least this many delivery attempts.
+	MinAttempts int32
+	// Limit defaults to 50 and is capped at 500.
+	Limit int64
+	Skip  int64
+}
+
+func (f ListFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	if f.Topic != "" {
+		filter["topic"] = f.Topic
+	}
+	if f.Key != "" {
+		filter["key"] = f.Key
+	}
+	created := bson.M{}
+	if !f.CreatedAfter.IsZero() {
+		created["$gte"] = f.CreatedAfter.UTC()
+	}
+	if !f.CreatedBefore.IsZero() {
+		created["$lt"] = f.CreatedBefore.UTC()
+	}
+	if len(created) > 0 {
+		filter["createdAt"] = created
+	}
+	if f.MinAttempts > 0 {
+		filter["attemptsToSend"] = bson.M{"$gte": f.MinAttempts}
+	}
+	return filter
+}
+
+func (f *ListFilter) applyDefaults() {
+	if f.Limit <= 0 {
+		f.Limit = defaultListLimit
+	}
+	if f.Limit > maxListLimit {
+		f.Limit = maxListLimit
+	}
+	if f.Skip < 0 {
+		f.Skip = 0
+	}
+}
+
+// MessageDetails is a single outbox message with its payload decoded for inspection.
+type MessageDetails struct {
+	*OutboxEntity
+	// Event is the payload rendered as protojson, or the payload itself for JSON content types;
+	// nil if it could not be decoded.
+	Event json.RawMessage
+	// DecodeError explains why Event is empty.
+	DecodeError string
+}
+
+// Admin provides operational access to the outbox collection.
+type Admin interface {
+	// List returns messages matching the filter, newest first.
+	List(ctx context.Context, filter ListFilter) ([]*OutboxEntity, error)
+
+	// Get returns a single message with its decoded payload.
+	Get(ctx context.Context, id string) (*MessageDetails, error)
+
+	// Requeue resets the delivery schedule and attempts counter of a PROCESSING or FAILED message
+	// so the fetcher picks it up right away.
+	Requeue(ctx context.Context, id string) error
+
+	// Purge permanently deletes a message.
+	Purge(ctx context.Context, id string) error
+
+	// Replay re-enqueues SENT messages, see Replayer.
+	Replay(ctx context.Context, req ReplayRequest) (ReplayResult, error)
+}
+
+type admin struct {
+	outboxRepository Repository
+	deserializer     kafkaproto.Deserializer
+	replayer         Replayer
+}
+
+// NewAdmin creates an Admin backed by the outbox repository.
+func NewAdmin(outboxRepository Repository, deserializer kafkaproto.Deserializer) Admin {
+	return &admin{
+		outboxRepository: outboxRepository,
+		deserializer:     deserializer,
+		replayer:         NewReplayer(outboxRepository),
+	}
+}
+
+func (a *admin) List(ctx context.Context, filter ListFilter) ([]*OutboxEntity, error) {
+	filter.applyDefaults()
+	return a.outboxRepository.Find(ctx, filter)
+}
+
+func (a *admin) Get(ctx context.Context, id string) (*MessageDetails, error) {
+	entity, err := a.outboxRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, translateNotFound(err)
+	}
+
+	details := &MessageDetails{OutboxEntity: entity}
+
+	if contentType := entity.Headers[HeaderContentType]; contentType != "" && contentType != ContentTypeProtobuf {
+		if json.Valid(entity.Payload) {
+			details.Event = entity.Payload
+		} else {
+			details.DecodeError = fmt.Sprintf("payload with content type %q cannot be rendered", contentType)
+		}
+		return details, nil
+	}
+
+	headers := make(map[string][]byte, len(entity.Headers))
+	for k, v := range entity.Headers {
+		headers[k] = []byte(v)
+	}
+
+	event, err := a.deserializer.Deserialize(entity.Payload, headers)
+	if err != nil {
+		details.DecodeError = err.Error()
+		return details, nil
+	}
+
+	encoded, err := protojson.Marshal(event)
+	if err != nil {
+		details.DecodeError = fmt.Sprintf("protojson marshal failed: %v", err)
+		return details, nil
+	}
+	details.Event = encoded
+
+	return details, nil
+}
+
+func (a *admin) Requeue(ctx context.Context, id string) error {
+	return translateNotFound(a.outboxRepository.Requeue(ctx, id))
+}
+
+func (a *admin) Purge(ctx context.Context, id string) error {
+	return translateNotFound(a.outboxRepository.Delete(ctx, id))
+}
+
+func (a *admin) Replay(ctx context.Context, req ReplayRequest) (ReplayResult, error) {
+	return a.replayer.Replay(ctx, req)
+}
+
+func translateNotFound(err error) error {
+	if errors.Is(err, errEntityNotFound) {
+		return fmt.Errorf("%w: %w", ErrMessageNotFound, err)
+	}
+	return err
+}
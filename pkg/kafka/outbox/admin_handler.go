@@ -0,0 +1,249 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AdminHandler exposes Admin over HTTP for on-call operations.
+type AdminHandler struct {
+	admin  Admin
+	logger *zap.Logger
+}
+
+func NewAdminHandler(admin Admin, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		admin:  admin,
+		logger: logger,
+	}
+}
+
+// messageView is the JSON representation of an outbox message.
+type messageView struct {
+	ID               string            `json:"id"`
+	Topic            string            `json:"topic"`
+	Key              string            `json:"key"`
+	Status           string            `json:"status"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	AttemptsToSend   int32             `json:"attempts_to_send"`
+	LastError        string            `json:"last_error,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	SentAt           *time.Time        `json:"sent_at,omitempty"`
+	FailedAt         *time.Time        `json:"failed_at,omitempty"`
+	NextAttemptAfter *time.Time        `json:"next_attempt_after,omitempty"`
+	LockExpiresAt    *time.Time        `json:"lock_expires_at,omitempty"`
+	LockOwner        string            `json:"lock_owner,omitempty"`
+	Event            json.RawMessage   `json:"event,omitempty"`
+	DecodeError      string            `json:"decode_error,omitempty"`
+}
+
+func newMessageView(e *OutboxEntity) messageView {
+	return messageView{
+		ID:               e.ID,
+		Topic:            e.Topic,
+		Key:              e.Key,
+		Status:           e.Status,
+		Headers:          e.Headers,
+		AttemptsToSend:   e.AttemptsToSend,
+		LastError:        e.LastError,
+		CreatedAt:        e.CreatedAt,
+		SentAt:           optionalTime(e.SentAt),
+		FailedAt:         optionalTime(e.FailedAt),
+		NextAttemptAfter: optionalTime(e.NextAttemptAfter),
+		LockExpiresAt:    optionalTime(e.LockExpiresAt),
+		LockOwner:        e.LockOwner,
+	}
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// List handles GET {prefix}/messages.
+// Supported query parameters: status, topic, key, created_after, created_before (RFC 3339),
+// min_attempts, limit, skip.
+//
+// All endpoints accept a tenant query parameter, which is required when the outbox is tenant-scoped.
+func (h *AdminHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	entities, err := h.admin.List(requestContext(r), filter)
+	if err != nil {
+		h.writeAdminError(w, err)
+		return
+	}
+
+	views := make([]messageView, 0, len(entities))
+	for _, e := range entities {
+		views = append(views, newMessageView(e))
+	}
+	h.writeJSON(w, http.StatusOK, views)
+}
+
+// Get handles GET {prefix}/messages/{id}.
+func (h *AdminHandler) Get(w http.ResponseWriter, r *http.Request) {
+	details, err := h.admin.Get(requestContext(r), r.PathValue("id"))
+	if err != nil {
+		h.writeAdminError(w, err)
+		return
+	}
+
+	view := newMessageView(details.OutboxEntity)
+	view.Event = details.Event
+	view.DecodeError = details.DecodeError
+	h.writeJSON(w, http.StatusOK, view)
+}
+
+// Requeue handles POST {prefix}/messages/{id}/requeue.
+func (h *AdminHandler) Requeue(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.admin.Requeue(requestContext(r), id); err != nil {
+		h.writeAdminError(w, err)
+		return
+	}
+	h.logger.Info("outbox message requeued via admin API", zap.String("id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Purge handles DELETE {prefix}/messages/{id}.
+func (h *AdminHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.admin.Purge(requestContext(r), id); err != nil {
+		h.writeAdminError(w, err)
+		return
+	}
+	h.logger.Info("outbox message purged via admin API", zap.String("id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// replayRequest is the JSON body of the replay endpoint.
+type replayRequest struct {
+	Topic         string    `json:"topic"`
+	Key           string    `json:"key,omitempty"`
+	CreatedAfter  time.Time `json:"created_after"`
+	CreatedBefore time.Time `json:"created_before"`
+	TargetTopic   string    `json:"target_topic,omitempty"`
+	DryRun        bool      `json:"dry_run"`
+}
+
+// replayView is the JSON response of the replay endpoint.
+type replayView struct {
+	Matched  int64 `json:"matched"`
+	Enqueued int64 `json:"enqueued"`
+	DryRun   bool  `json:"dry_run"`
+}
+
+// Replay handles POST {prefix}/replay.
+// The body selects SENT messages by topic (required), key and created_after/created_before (RFC 3339),
+// and optionally sets target_topic and dry_run.
+func (h *AdminHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	var body replayRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.writeError(w, http.StatusBadRequest, errors.New("request body must be a JSON replay request"))
+		return
+	}
+
+	result, err := h.admin.Replay(requestContext(r), ReplayRequest(body))
+	if err != nil {
+		h.writeAdminError(w, err)
+		return
+	}
+	if !body.DryRun {
+		h.logger.Info("outbox messages replayed via admin API",
+			zap.String("topic", body.Topic),
+			zap.String("target_topic", body.TargetTopic),
+			zap.Int64("enqueued", result.Enqueued))
+	}
+	h.writeJSON(w, http.StatusOK, replayView{Matched: result.Matched, Enqueued: result.Enqueued, DryRun: body.DryRun})
+}
+
+// requestContext scopes the request context to the tenant given in the tenant query parameter.
+func requestContext(r *http.Request) context.Context {
+	return withTenant(r.Context(), r.URL.Query().Get("tenant"))
+}
+
+func parseListFilter(r *http.Request) (ListFilter, error) {
+	q := r.URL.Query()
+	filter := ListFilter{
+		Status: q.Get("status"),
+		Topic:  q.Get("topic"),
+		Key:    q.Get("key"),
+	}
+
+	var err error
+	if v := q.Get("created_after"); v != "" {
+		if filter.CreatedAfter, err = time.Parse(time.RFC3339, v); err != nil {
+			return ListFilter{}, errors.New("created_after must be an RFC 3339 timestamp")
+		}
+	}
+	if v := q.Get("created_before"); v != "" {
+		if filter.CreatedBefore, err = time.Parse(time.RFC3339, v); err != nil {
+			return ListFilter{}, errors.New("created_before must be an RFC 3339 timestamp")
+		}
+	}
+	if v := q.Get("min_attempts"); v != "" {
+		n, parseErr := strconv.ParseInt(v, 10, 32)
+		if parseErr != nil {
+			return ListFilter{}, errors.New("min_attempts must be an integer")
+		}
+		filter.MinAttempts = int32(n) //nolint:gosec // bounded by ParseInt bitSize
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return ListFilter{}, errors.New("limit must be an integer")
+		}
+	}
+	if v := q.Get("skip"); v != "" {
+		if filter.Skip, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return ListFilter{}, errors.New("skip must be an integer")
+		}
+	}
+	return filter, nil
+}
+
+func (h *AdminHandler) writeAdminError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrMessageNotFound) {
+		h.writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, ErrTenantRequired) || errors.Is(err, ErrInvalidReplay) {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.writeError(w, http.StatusInternalServerError, err)
+}
+
+func (h *AdminHandler) writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		h.logger.Error("outbox admin request failed", zap.Error(err))
+	}
+	h.writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (h *AdminHandler) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body) //nolint:errcheck // HTTP handler, error logged by net/http
+}
+
+// RegisterAdminRoutes registers the outbox admin endpoints under the given prefix (e.g. "/admin/outbox").
+func RegisterAdminRoutes(mux *http.ServeMux, prefix string, h *AdminHandler) {
+	mux.HandleFunc("GET "+prefix+"/messages", h.List)
+	mux.HandleFunc("GET "+prefix+"/messages/{id}", h.Get)
+	mux.HandleFunc("POST "+prefix+"/messages/{id}/requeue", h.Requeue)
+	mux.HandleFunc("DELETE "+prefix+"/messages/{id}", h.Purge)
+	mux.HandleFunc("POST "+prefix+"/replay", h.Replay)
+}
@@ -0,0 +1,268 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/kafkaproto"
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestListFilter_toBSON(t *testing.T) {
+	t.Run("empty filter matches everything", func(t *testing.T) {
+		assert.Equal(t, bson.M{}, ListFilter{}.toBSON())
+	})
+
+	t.Run("all fields are translated", func(t *testing.T) {
+		from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := from.Add(24 * time.Hour)
+
+		filter := ListFilter{
+			Status:        StatusFailed,
+			Topic:         "orders",
+			Key:           "order-1",
+			CreatedAfter:  from,
+			CreatedBefore: to,
+			MinAttempts:   3,
+		}.toBSON()
+
+		assert.Equal(t, bson.M{
+			"status":         StatusFailed,
+			"topic":          "orders",
+			"key":            "order-1",
+			"createdAt":      bson.M{"$gte": from, "$lt": to},
+			"attemptsToSend": bson.M{"$gte": int32(3)},
+		}, filter)
+	})
+}
+
+func TestAdmin_List(t *testing.T) {
+	t.Run("applies default limit", func(t *testing.T) {
+		repo := newMockRepository()
+		a := NewAdmin(repo, kafkaproto.NewDeserializer())
+
+		_, err := a.List(context.Background(), ListFilter{Topic: "orders"})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(defaultListLimit), repo.findFilter.Limit)
+		assert.Equal(t, "orders", repo.findFilter.Topic)
+	})
+
+	t.Run("caps limit", func(t *testing.T) {
+		repo := newMockRepository()
+		a := NewAdmin(repo, kafkaproto.NewDeserializer())
+
+		_, err := a.List(context.Background(), ListFilter{Limit: 10000})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(maxListLimit), repo.findFilter.Limit)
+	})
+}
+
+func TestAdmin_Get(t *testing.T) {
+	t.Run("decodes payload using event_type header", func(t *testing.T) {
+		payload, err := proto.Marshal(wrapperspb.String("hello"))
+		require.NoError(t, err)
+
+		repo := newMockRepository()
+		repo.byID = map[string]*OutboxEntity{
+			"id-1": {ID: "id-1", Payload: payload, Headers: map[string]string{"event_type": "google.protobuf.StringValue"}},
+		}
+		a := NewAdmin(repo, kafkaproto.NewDeserializer())
+
+		details, err := a.Get(context.Background(), "id-1")
+
+		require.NoError(t, err)
+		assert.Equal(t, "id-1", details.ID)
+		assert.JSONEq(t, `"hello"`, string(details.Event))
+		assert.Empty(t, details.DecodeError)
+	})
+
+	t.Run("renders JSON payload as is", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.byID = map[string]*OutboxEntity{
+			"id-1": {ID: "id-1", Payload: []byte(`{"id":"1"}`), Headers: map[string]string{HeaderContentType: ContentTypeCloudEventsJSON}},
+		}
+		a := NewAdmin(repo, kafkaproto.NewDeserializer())
+
+		details, err := a.Get(context.Background(), "id-1")
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"1"}`, string(details.Event))
+		assert.Empty(t, details.DecodeError)
+	})
+
+	t.Run("reports decode error instead of failing", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.byID = map[string]*OutboxEntity{"id-1": {ID: "id-1"}}
+		a := NewAdmin(repo, kafkaproto.NewDeserializer())
+
+		details, err := a.Get(context.Background(), "id-1")
+
+		require.NoError(t, err)
+		assert.Nil(t, details.Event)
+		assert.Contains(t, details.DecodeError, "event_type")
+	})
+
+	t.Run("returns ErrMessageNotFound", func(t *testing.T) {
+		a := NewAdmin(newMockRepository(), kafkaproto.NewDeserializer())
+
+		_, err := a.Get(context.Background(), "missing")
+
+		assert.ErrorIs(t, err, ErrMessageNotFound)
+	})
+}
+
+func newTestAdminMux(repo *mockRepository) *http.ServeMux {
+	mux := http.NewServeMux()
+	h := NewAdminHandler(NewAdmin(repo, kafkaproto.NewDeserializer()), zap.NewNop())
+	RegisterAdminRoutes(mux, "/admin/outbox", h)
+	return mux
+}
+
+func TestAdminHandler(t *testing.T) {
+	t.Run("lists messages with query filters", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.findResult = []*OutboxEntity{{ID: "id-1", Topic: "orders", Status: StatusFailed, Payload: []byte("secret")}}
+		mux := newTestAdminMux(repo)
+
+		req := httptest.NewRequest(http.MethodGet,
+			"/admin/outbox/messages?status=FAILED&topic=orders&min_attempts=2&created_after=2026-01-01T00:00:00Z&limit=10", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, StatusFailed, repo.findFilter.Status)
+		assert.Equal(t, "orders", repo.findFilter.Topic)
+		assert.Equal(t, int32(2), repo.findFilter.MinAttempts)
+		assert.Equal(t, int64(10), repo.findFilter.Limit)
+		assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), repo.findFilter.CreatedAfter)
+
+		var views []map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &views))
+		require.Len(t, views, 1)
+		assert.Equal(t, "id-1", views[0]["id"])
+		assert.NotContains(t, views[0], "payload")
+	})
+
+	t.Run("rejects invalid filter", func(t *testing.T) {
+		mux := newTestAdminMux(newMockRepository())
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/outbox/messages?created_after=yesterday", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("returns 404 for unknown message", func(t *testing.T) {
+		mux := newTestAdminMux(newMockRepository())
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/outbox/messages/missing", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("requeues message", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.byID = map[string]*OutboxEntity{"id-1": {ID: "id-1", Status: StatusFailed}}
+		mux := newTestAdminMux(repo)
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/outbox/messages/id-1/requeue", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, []string{"id-1"}, repo.requeuedIDs)
+	})
+
+	t.Run("returns 400 when tenant is required", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.findErr = ErrTenantRequired
+		mux := newTestAdminMux(repo)
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/outbox/messages", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("scopes request to tenant query parameter", func(t *testing.T) {
+		var gotTenant string
+		mux := http.NewServeMux()
+		h := NewAdminHandler(&tenantRecordingAdmin{tenant: &gotTenant}, zap.NewNop())
+		RegisterAdminRoutes(mux, "/admin/outbox", h)
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/outbox/messages?tenant=acme", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "acme", gotTenant)
+	})
+
+	t.Run("purges message", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.byID = map[string]*OutboxEntity{"id-1": {ID: "id-1"}}
+		mux := newTestAdminMux(repo)
+
+		req := httptest.NewRequest(http.MethodDelete, "/admin/outbox/messages/id-1", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, []string{"id-1"}, repo.deletedIDs)
+	})
+
+	t.Run("replays messages", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.replayable = []*OutboxEntity{{ID: "id-1", Topic: "orders", Status: StatusSent}}
+		mux := newTestAdminMux(repo)
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/outbox/replay",
+			strings.NewReader(`{"topic":"orders","created_after":"2026-01-01T00:00:00Z","target_topic":"orders-rebuild"}`))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"matched":1,"enqueued":1,"dry_run":false}`, rec.Body.String())
+		require.Len(t, repo.enqueued, 1)
+		assert.Equal(t, "orders-rebuild", repo.enqueued[0].Topic)
+		assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), repo.replayFilters[0].CreatedAfter)
+	})
+
+	t.Run("rejects replay without topic", func(t *testing.T) {
+		mux := newTestAdminMux(newMockRepository())
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/outbox/replay", strings.NewReader(`{"dry_run":true}`))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+// tenantRecordingAdmin records the tenant slug of the List call context.
+type tenantRecordingAdmin struct {
+	Admin
+	tenant *string
+}
+
+func (a *tenantRecordingAdmin) List(ctx context.Context, filter ListFilter) ([]*OutboxEntity, error) {
+	*a.tenant, _ = tenant.SlugFromContext(ctx)
+	return nil, nil
+}
@@ -0,0 +1,114 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ArchiveCollectionName is the collection ArchiveTargetCollection copies messages to.
+const ArchiveCollectionName = "outbox_archive"
+
+// ArchiveSink stores SENT messages before the Archiver deletes them.
+// Archive must be idempotent: a message may be archived again if its deletion failed.
+type ArchiveSink interface {
+	Archive(ctx context.Context, entities []*OutboxEntity) error
+}
+
+type collectionArchiveSink struct {
+	collections mongo.CollectionProvider
+}
+
+// NewCollectionArchiveSink creates an ArchiveSink that inserts messages into the collection
+// resolved by the provider, keeping their ids.
+func NewCollectionArchiveSink(collections mongo.CollectionProvider) ArchiveSink {
+	return &collectionArchiveSink{collections: collections}
+}
+
+func (s *collectionArchiveSink) Archive(ctx context.Context, entities []*OutboxEntity) error {
+	docs := make([]any, 0, len(entities))
+	for _, e := range entities {
+		docs = append(docs, e)
+	}
+	_, err := s.collections.GetCollection(ctx).InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	// Messages archived by an earlier run whose deletion failed are already there.
+	if err != nil && !mongodriver.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to insert archived outbox messages: %w", err)
+	}
+	return nil
+}
+
+// ArchivedMessage is a line of a JSONL archive file.
+type ArchivedMessage struct {
+	ID        string            `json:"id"`
+	Topic     string            `json:"topic"`
+	Key       string            `json:"key"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Payload   []byte            `json:"payload"`
+	CreatedAt time.Time         `json:"created_at"`
+	SentAt    time.Time         `json:"sent_at"`
+}
+
+type jsonlArchiveSink struct {
+	directory string
+	mu        sync.Mutex
+}
+
+// NewJSONLArchiveSink creates an ArchiveSink that appends messages to daily files
+// named outbox-archive-YYYY-MM-DD.jsonl in directory, by delivery date.
+// Payloads are base64 encoded. Files are synced before Archive returns.
+func NewJSONLArchiveSink(directory string) ArchiveSink {
+	return &jsonlArchiveSink{directory: directory}
+}
+
+func (s *jsonlArchiveSink) Archive(_ context.Context, entities []*OutboxEntity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byFile := make(map[string][]*OutboxEntity)
+	for _, e := range entities {
+		name := "outbox-archive-" + e.SentAt.UTC().Format(time.DateOnly) + ".jsonl"
+		byFile[name] = append(byFile[name], e)
+	}
+	for name, fileEntities := range byFile {
+		if err := s.appendFile(filepath.Join(s.directory, name), fileEntities); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *jsonlArchiveSink) appendFile(path string, entities []*OutboxEntity) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) //nolint:gosec // path is built from the configured archive directory
+	if err != nil {
+		return fmt.Errorf("failed to open outbox archive file: %w", err)
+	}
+	defer func() { _ = f.Close() }() //nolint:errcheck // Close after Sync only releases the descriptor
+
+	enc := json.NewEncoder(f)
+	for _, e := range entities {
+		if err = enc.Encode(ArchivedMessage{
+			ID:        e.ID,
+			Topic:     e.Topic,
+			Key:       e.Key,
+			Headers:   e.Headers,
+			Payload:   e.Payload,
+			CreatedAt: e.CreatedAt,
+			SentAt:    e.SentAt,
+		}); err != nil {
+			return fmt.Errorf("failed to write outbox archive file: %w", err)
+		}
+	}
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync outbox archive file: %w", err)
+	}
+	return nil
+}
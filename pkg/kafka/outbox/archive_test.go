@@ -0,0 +1,43 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONLArchiveSink(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewJSONLArchiveSink(dir)
+	day1 := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+
+	require.NoError(t, sink.Archive(context.Background(), []*OutboxEntity{
+		{ID: "a", Topic: "orders", Payload: []byte{0x01, 0x02}, SentAt: day1},
+		{ID: "b", Topic: "orders", Payload: []byte{0x03}, SentAt: day2},
+	}))
+	require.NoError(t, sink.Archive(context.Background(), []*OutboxEntity{
+		{ID: "c", Topic: "orders", SentAt: day1},
+	}))
+
+	first, err := os.ReadFile(filepath.Join(dir, "outbox-archive-2026-03-01.jsonl"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(first)), "\n")
+	require.Len(t, lines, 2)
+
+	var msg ArchivedMessage
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &msg))
+	assert.Equal(t, "a", msg.ID)
+	assert.Equal(t, []byte{0x01, 0x02}, msg.Payload)
+
+	second, err := os.ReadFile(filepath.Join(dir, "outbox-archive-2026-03-02.jsonl"))
+	require.NoError(t, err)
+	assert.Contains(t, string(second), `"id":"b"`)
+}
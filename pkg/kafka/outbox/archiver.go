@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
+	"go.uber.org/zap"
+)
+
+// Archiver copies SENT messages older than RetentionConfig.Sent to an ArchiveSink and then
+// deletes them from the outbox. It replaces the TTL index for SENT messages when
+// RetentionConfig.Archive is enabled.
+type Archiver struct {
+	outboxRepository Repository
+	sink             ArchiveSink
+	tenants          tenant.Repository
+	enabled          bool
+	retention        time.Duration
+	interval         time.Duration
+	batchSize        int
+	logger           *zap.Logger
+}
+
+// NewArchiver creates an Archiver. With a non-nil tenants repository every active tenant
+// outbox is archived in turn; nil means a single shared outbox collection.
+func NewArchiver(outboxRepository Repository, sink ArchiveSink, tenants tenant.Repository, config Config, logger *zap.Logger) *Archiver {
+	return &Archiver{
+		outboxRepository: outboxRepository,
+		sink:             sink,
+		tenants:          tenants,
+		enabled:          config.Retention.Archive.Enabled,
+		retention:        config.Retention.Sent,
+		interval:         config.Retention.Archive.Interval,
+		batchSize:        config.Retention.Archive.BatchSize,
+		logger:           logger,
+	}
+}
+
+func (a *Archiver) Run(ctx context.Context) error {
+	if !a.enabled {
+		return nil
+	}
+
+	for {
+		a.archiveAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(a.interval):
+		}
+	}
+}
+
+func (a *Archiver) archiveAll(ctx context.Context) {
+	slugs, err := activeTenantSlugs(ctx, a.tenants)
+	if err != nil {
+		a.logger.Error("failed to list tenants for outbox archival", zap.Error(err))
+		return
+	}
+
+	before := time.Now().Add(-a.retention)
+	for _, slug := range slugs {
+		archived, archiveErr := a.archive(withTenant(ctx, slug), before)
+		if ctx.Err() != nil {
+			return
+		}
+		if archiveErr != nil {
+			a.logger.Error("failed to archive outbox messages", zap.String("tenant", slug), zap.Error(archiveErr))
+		}
+		if archived > 0 {
+			a.logger.Info("archived outbox messages", zap.String("tenant", slug), zap.Int64("count", archived))
+		}
+	}
+}
+
+// archive moves SENT messages delivered before the given time to the sink in batches.
+// Returns the number of deleted messages.
+func (a *Archiver) archive(ctx context.Context, before time.Time) (int64, error) {
+	var archived int64
+	for ctx.Err() == nil {
+		entities, err := a.outboxRepository.FindSentBefore(ctx, before, a.batchSize)
+		if err != nil {
+			return archived, err
+		}
+		if len(entities) == 0 {
+			return archived, nil
+		}
+
+		if err = a.sink.Archive(ctx, entities); err != nil {
+			return archived, err
+		}
+
+		ids := make([]string, 0, len(entities))
+		for _, e := range entities {
+			ids = append(ids, e.ID)
+		}
+		deleted, err := a.outboxRepository.DeleteSent(ctx, ids)
+		if err != nil {
+			return archived, err
+		}
+		archived += deleted
+
+		if len(entities) < a.batchSize {
+			return archived, nil
+		}
+	}
+	return archived, nil
+}
@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// recordingArchiveSink records archived entities in memory.
+type recordingArchiveSink struct {
+	mu       sync.Mutex
+	archived []string
+	err      error
+}
+
+func (s *recordingArchiveSink) Archive(_ context.Context, entities []*OutboxEntity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	for _, e := range entities {
+		s.archived = append(s.archived, e.ID)
+	}
+	return nil
+}
+
+func testArchiverConfig() Config {
+	cfg := Config{}
+	cfg.ApplyDefaults()
+	cfg.Retention.Sent = time.Hour
+	cfg.Retention.Archive.Enabled = true
+	cfg.Retention.Archive.BatchSize = 2
+	return cfg
+}
+
+func TestArchiver_Archive(t *testing.T) {
+	sentEntities := func() []*OutboxEntity {
+		old := time.Now().Add(-2 * time.Hour)
+		entities := make([]*OutboxEntity, 0, 6)
+		for i := range 5 {
+			entities = append(entities, &OutboxEntity{ID: fmt.Sprintf("old-%d", i), Status: StatusSent, SentAt: old})
+		}
+		return append(entities, &OutboxEntity{ID: "recent", Status: StatusSent, SentAt: time.Now()})
+	}
+
+	t.Run("archives and deletes expired messages in batches", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.sentBefore = sentEntities()
+		sink := &recordingArchiveSink{}
+		a := NewArchiver(repo, sink, nil, testArchiverConfig(), zap.NewNop())
+
+		archived, err := a.archive(context.Background(), time.Now().Add(-time.Hour))
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), archived)
+		assert.Equal(t, []string{"old-0", "old-1", "old-2", "old-3", "old-4"}, sink.archived)
+		assert.Equal(t, sink.archived, repo.deletedSentIDs)
+		require.Len(t, repo.sentBefore, 1)
+		assert.Equal(t, "recent", repo.sentBefore[0].ID)
+	})
+
+	t.Run("keeps messages when archiving fails", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.sentBefore = sentEntities()
+		a := NewArchiver(repo, &recordingArchiveSink{err: errors.New("disk full")}, nil, testArchiverConfig(), zap.NewNop())
+
+		_, err := a.archive(context.Background(), time.Now().Add(-time.Hour))
+
+		assert.Error(t, err)
+		assert.Empty(t, repo.deletedSentIDs)
+		assert.Len(t, repo.sentBefore, 6)
+	})
+
+	t.Run("archives every tenant", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.sentBefore = sentEntities()
+		tenants := &mockTenantRepository{active: []tenant.Record{{Slug: "acme"}, {Slug: "globex"}}}
+		a := NewArchiver(repo, &recordingArchiveSink{}, tenants, testArchiverConfig(), zap.NewNop())
+
+		a.archiveAll(context.Background())
+
+		assert.Len(t, repo.deletedSentIDs, 5)
+	})
+}
+
+func TestArchiver_Run(t *testing.T) {
+	t.Run("returns immediately when disabled", func(t *testing.T) {
+		a := NewArchiver(newMockRepository(), &recordingArchiveSink{}, nil, Config{}, zap.NewNop())
+
+		assert.NoError(t, a.Run(context.Background()))
+	})
+}
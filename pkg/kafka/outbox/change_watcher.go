@@ -0,0 +1,206 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+const (
+	resumeTokenCollectionName = "outbox_resume_tokens"
+	resumeTokenID             = "outbox"
+
+	// resumeTokenSaveInterval limits resume token writes to one per interval under load.
+	resumeTokenSaveInterval = 10 * time.Second
+
+	// MongoDB server error codes.
+	codeChangeStreamFatalError   = 280
+	codeChangeStreamHistoryLost  = 286
+	codeChangeStreamNotSupported = 40573
+)
+
+var errChangeStreamNotSupported = errors.New("change streams are not supported by the MongoDB deployment")
+
+// ChangeWatcher watches the outbox collection for inserts and notifies the Fetcher
+// when a new message becomes due. The resume token is persisted so events that happen
+// while the service is down are replayed after a restart.
+type ChangeWatcher struct {
+	source       changeStreamSource
+	match        bson.M
+	tokens       *mongodriver.Collection
+	wakeups      chan<- time.Time
+	enabled      bool
+	errorBackoff time.Duration
+	logger       *zap.Logger
+}
+
+// changeStreamSource is implemented by *mongo.Collection and *mongo.Client.
+type changeStreamSource interface {
+	Watch(ctx context.Context, pipeline any, opts ...options.Lister[options.ChangeStreamOptions]) (*mongodriver.ChangeStream, error)
+}
+
+// NewChangeWatcher creates a ChangeWatcher for the shared outbox collection or,
+// with Config.TenantScoped, for the outbox collections of all tenant databases.
+// The tenant-scoped mode opens a cluster-wide change stream, which requires
+// the changeStream privilege on all databases.
+func NewChangeWatcher(database *mongodriver.Database, wakeups chan time.Time, config Config, logger *zap.Logger) *ChangeWatcher {
+	var (
+		source changeStreamSource = database.Collection(collectionName)
+		match                     = bson.M{"operationType": "insert"}
+	)
+	if config.TenantScoped {
+		source = database.Client()
+		match["ns.coll"] = collectionName
+		match["ns.db"] = bson.M{"$regex": "^" + regexp.QuoteMeta(database.Name()+"_")}
+	}
+
+	return &ChangeWatcher{
+		source:       source,
+		match:        match,
+		tokens:       database.Collection(resumeTokenCollectionName),
+		wakeups:      wakeups,
+		enabled:      config.ChangeStream.Enabled,
+		errorBackoff: config.ErrorBackoff,
+		logger:       logger,
+	}
+}
+
+func (w *ChangeWatcher) Run(ctx context.Context) error {
+	if !w.enabled {
+		return nil
+	}
+
+	for {
+		err := w.watch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, errChangeStreamNotSupported) {
+			w.logger.Warn("outbox change stream is not supported, falling back to polling", zap.Error(err))
+			return nil
+		}
+		w.logger.Error("outbox change stream failed, restarting", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.errorBackoff):
+		}
+	}
+}
+
+func (w *ChangeWatcher) watch(ctx context.Context) error {
+	token, err := w.loadResumeToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	pipeline := mongodriver.Pipeline{
+		{{Key: "$match", Value: w.match}},
+		{{Key: "$project", Value: bson.M{"fullDocument.nextAttemptAfter": 1}}},
+	}
+	opts := options.ChangeStream()
+	if token != nil {
+		opts.SetResumeAfter(token)
+	}
+
+	stream, err := w.source.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return w.translateError(ctx, token, fmt.Errorf("failed to open outbox change stream: %w", err))
+	}
+	defer func() { _ = stream.Close(context.Background()) }() //nolint:errcheck // Best effort cleanup
+
+	w.logger.Info("outbox change stream started", zap.Bool("resumed", token != nil))
+
+	lastSaved := time.Now()
+	defer func() {
+		// Persist the latest position on shutdown so the next start resumes from here.
+		saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		w.saveResumeToken(saveCtx, stream.ResumeToken())
+	}()
+
+	for stream.Next(ctx) {
+		var event struct {
+			FullDocument struct {
+				NextAttemptAfter time.Time `bson:"nextAttemptAfter"`
+			} `bson:"fullDocument"`
+		}
+		if err = stream.Decode(&event); err != nil {
+			return fmt.Errorf("failed to decode outbox change event: %w", err)
+		}
+		w.notify(event.FullDocument.NextAttemptAfter)
+
+		if time.Since(lastSaved) >= resumeTokenSaveInterval {
+			w.saveResumeToken(ctx, stream.ResumeToken())
+			lastSaved = time.Now()
+		}
+	}
+
+	if err = stream.Err(); err != nil {
+		return w.translateError(ctx, token, fmt.Errorf("outbox change stream interrupted: %w", err))
+	}
+	return nil
+}
+
+// notify hands the due time to the Fetcher without blocking.
+// Dropped notifications are picked up by the next poll.
+func (w *ChangeWatcher) notify(due time.Time) {
+	select {
+	case w.wakeups <- due:
+	default:
+	}
+}
+
+// translateError discards a resume token the server can no longer resume from,
+// so the next attempt starts watching from the current position.
+func (w *ChangeWatcher) translateError(ctx context.Context, token bson.Raw, err error) error {
+	var serverErr mongodriver.ServerError
+	if !errors.As(err, &serverErr) {
+		return err
+	}
+	if serverErr.HasErrorCode(codeChangeStreamNotSupported) {
+		return fmt.Errorf("%w: %w", errChangeStreamNotSupported, err)
+	}
+	if token != nil && (serverErr.HasErrorCode(codeChangeStreamHistoryLost) || serverErr.HasErrorCode(codeChangeStreamFatalError)) {
+		w.logger.Warn("outbox change stream resume token is no longer valid, discarding it", zap.Error(err))
+		if _, delErr := w.tokens.DeleteOne(ctx, bson.M{"_id": resumeTokenID}); delErr != nil {
+			w.logger.Error("failed to discard outbox resume token", zap.Error(delErr))
+		}
+	}
+	return err
+}
+
+func (w *ChangeWatcher) loadResumeToken(ctx context.Context) (bson.Raw, error) {
+	var doc struct {
+		Token bson.Raw `bson:"token"`
+	}
+	err := w.tokens.FindOne(ctx, bson.M{"_id": resumeTokenID}).Decode(&doc)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load outbox resume token: %w", err)
+	}
+	return doc.Token, nil
+}
+
+func (w *ChangeWatcher) saveResumeToken(ctx context.Context, token bson.Raw) {
+	if token == nil {
+		return
+	}
+	_, err := w.tokens.UpdateOne(ctx,
+		bson.M{"_id": resumeTokenID},
+		bson.M{"$set": bson.M{"token": token, "updatedAt": time.Now().UTC()}},
+		options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		w.logger.Error("failed to save outbox resume token", zap.Error(err))
+	}
+}
@@ -0,0 +1,88 @@
+//go:build integration
+
+package outbox
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+func TestChangeWatcher(t *testing.T) {
+	ctx := context.Background()
+	db := testMongo.Database("outbox_change_stream_test")
+	require.NoError(t, EnsureIndexes(ctx, db, testRetention()))
+
+	repo := newTestRepository(db, Config{MaxBackoff: time.Hour})
+	cfg := Config{ChangeStream: ChangeStreamConfig{Enabled: true}, ErrorBackoff: time.Second}
+
+	startWatcher := func(wakeups chan time.Time) (stop func()) {
+		w := NewChangeWatcher(db, wakeups, cfg, zap.NewNop())
+		runCtx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = w.Run(runCtx)
+		}()
+		return func() {
+			cancel()
+			wg.Wait()
+		}
+	}
+
+	waitForWakeup := func(wakeups chan time.Time) time.Time {
+		select {
+		case due := <-wakeups:
+			return due
+		case <-time.After(10 * time.Second):
+			t.Fatal("expected wakeup from change stream")
+			return time.Time{}
+		}
+	}
+
+	wakeups := make(chan time.Time, 10)
+	stop := startWatcher(wakeups)
+
+	// The stream may not be open yet; keep inserting until the first event arrives.
+	require.Eventually(t, func() bool {
+		if _, err := repo.Create(ctx, []byte("payload"), bson.NewObjectID().Hex(), "key", "orders", nil, time.Time{}); err != nil {
+			return false
+		}
+		select {
+		case due := <-wakeups:
+			return due.After(time.Now())
+		case <-time.After(200 * time.Millisecond):
+			return false
+		}
+	}, 10*time.Second, 10*time.Millisecond)
+
+	stop()
+
+	count, err := db.Collection(resumeTokenCollectionName).CountDocuments(ctx, bson.M{"_id": resumeTokenID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "resume token must be persisted on shutdown")
+
+	t.Run("replays inserts made while stopped", func(t *testing.T) {
+		missed, err := repo.Create(ctx, []byte("payload"), "inserted-while-stopped", "key", "orders", nil, time.Time{})
+		require.NoError(t, err)
+
+		wakeups := make(chan time.Time, 10)
+		stop := startWatcher(wakeups)
+		defer stop()
+
+		// Drain events for inserts that landed before the token was saved.
+		for {
+			due := waitForWakeup(wakeups)
+			if due.Equal(missed.NextAttemptAfter.Truncate(time.Millisecond)) {
+				return
+			}
+		}
+	})
+}
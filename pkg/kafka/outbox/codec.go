@@ -0,0 +1,219 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/kafkaproto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Header keys and content types written by the codecs.
+const (
+	HeaderContentType = "content-type"
+	HeaderEventType   = "event_type"
+
+	ContentTypeProtobuf        = "application/x-protobuf"
+	ContentTypeJSON            = "application/json"
+	ContentTypeCloudEventsJSON = "application/cloudevents+json"
+)
+
+// Codec names accepted by Config.Codec and TopicCodecConfig.Codec.
+const (
+	// CodecProto encodes events as binary protobuf.
+	CodecProto = "proto"
+	// CodecProtoJSON encodes events as protojson.
+	CodecProtoJSON = "protojson"
+	// CodecCloudEventsStructured wraps the protojson event in a CloudEvents JSON envelope.
+	CodecCloudEventsStructured = "cloudevents-structured"
+	// CodecCloudEventsBinary encodes the event as binary protobuf and carries
+	// the CloudEvents attributes in ce_ headers.
+	CodecCloudEventsBinary = "cloudevents-binary"
+)
+
+const cloudEventsSpecVersion = "1.0"
+
+// Codec encodes outbox events into Kafka payloads.
+type Codec interface {
+	// Encode returns the payload for event. headers already carries the event metadata
+	// (event_id, event_type, source, timestamp); the codec sets content-type and
+	// any format specific headers.
+	Encode(event proto.Message, headers map[string]string) ([]byte, error)
+}
+
+type protoCodec struct {
+	serializer kafkaproto.Serializer
+}
+
+// NewProtoCodec creates a Codec that encodes events with the proto serializer.
+func NewProtoCodec(serializer kafkaproto.Serializer) Codec {
+	return &protoCodec{serializer: serializer}
+}
+
+func (c *protoCodec) Encode(event proto.Message, headers map[string]string) ([]byte, error) {
+	data, err := c.serializer.Serialize(event)
+	if err != nil {
+		return nil, err
+	}
+	headers[HeaderContentType] = ContentTypeProtobuf
+	return data, nil
+}
+
+type protoJSONCodec struct{}
+
+// NewProtoJSONCodec creates a Codec that encodes events as protojson.
+func NewProtoJSONCodec() Codec {
+	return protoJSONCodec{}
+}
+
+func (protoJSONCodec) Encode(event proto.Message, headers map[string]string) ([]byte, error) {
+	data, err := protojson.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("protojson marshal failed: %w", err)
+	}
+	headers[HeaderContentType] = ContentTypeJSON
+	return data, nil
+}
+
+// CloudEventsMode selects the CloudEvents Kafka protocol binding content mode.
+type CloudEventsMode int
+
+const (
+	// CloudEventsStructured puts attributes and data in a single JSON envelope.
+	CloudEventsStructured CloudEventsMode = iota
+	// CloudEventsBinary keeps the data as the payload and maps attributes to ce_ headers.
+	CloudEventsBinary
+)
+
+type cloudEventsCodec struct {
+	mode CloudEventsMode
+	data Codec
+}
+
+// NewCloudEventsCodec creates a Codec that produces CloudEvents 1.0 in the given mode.
+// The event data is encoded with the data codec. The CloudEvents id, source, type and time
+// attributes are taken from the event_id, source, event_type and timestamp headers.
+func NewCloudEventsCodec(mode CloudEventsMode, data Codec) Codec {
+	return &cloudEventsCodec{mode: mode, data: data}
+}
+
+// cloudEvent is the structured mode JSON envelope.
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+func (c *cloudEventsCodec) Encode(event proto.Message, headers map[string]string) ([]byte, error) {
+	data, err := c.data.Encode(event, headers)
+	if err != nil {
+		return nil, err
+	}
+	dataContentType := headers[HeaderContentType]
+
+	if c.mode == CloudEventsBinary {
+		headers["ce_specversion"] = cloudEventsSpecVersion
+		headers["ce_id"] = headers["event_id"]
+		headers["ce_source"] = headers["source"]
+		headers["ce_type"] = headers[HeaderEventType]
+		if t := cloudEventTime(headers); t != "" {
+			headers["ce_time"] = t
+		}
+		return data, nil
+	}
+
+	envelope := cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              headers["event_id"],
+		Source:          headers["source"],
+		Type:            headers[HeaderEventType],
+		Time:            cloudEventTime(headers),
+		DataContentType: dataContentType,
+	}
+	if dataContentType == ContentTypeJSON {
+		envelope.Data = data
+	} else {
+		envelope.DataBase64 = data
+	}
+
+	encoded, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("cloudevents marshal failed: %w", err)
+	}
+	headers[HeaderContentType] = ContentTypeCloudEventsJSON
+	return encoded, nil
+}
+
+// cloudEventTime converts the millisecond timestamp header to RFC 3339, empty if missing.
+func cloudEventTime(headers map[string]string) string {
+	ms, err := strconv.ParseInt(headers["timestamp"], 10, 64)
+	if err != nil {
+		return ""
+	}
+	return time.UnixMilli(ms).UTC().Format(time.RFC3339Nano)
+}
+
+// isKnownCodec reports whether name is a valid codec name.
+func isKnownCodec(name string) bool {
+	switch name {
+	case CodecProto, CodecProtoJSON, CodecCloudEventsStructured, CodecCloudEventsBinary:
+		return true
+	default:
+		return false
+	}
+}
+
+// newCodec resolves a codec name from the configuration. Names are checked by Config.Validate;
+// an unknown name yields a codec that fails every Encode.
+func newCodec(name string, serializer kafkaproto.Serializer) Codec {
+	switch name {
+	case CodecProto, "":
+		return NewProtoCodec(serializer)
+	case CodecProtoJSON:
+		return NewProtoJSONCodec()
+	case CodecCloudEventsStructured:
+		return NewCloudEventsCodec(CloudEventsStructured, NewProtoJSONCodec())
+	case CodecCloudEventsBinary:
+		return NewCloudEventsCodec(CloudEventsBinary, NewProtoCodec(serializer))
+	default:
+		return unknownCodec(name)
+	}
+}
+
+type unknownCodec string
+
+func (c unknownCodec) Encode(proto.Message, map[string]string) ([]byte, error) {
+	return nil, fmt.Errorf("unknown outbox codec %q", string(c))
+}
+
+// codecRegistry selects the codec for a topic.
+type codecRegistry struct {
+	fallback Codec
+	byTopic  map[string]Codec
+}
+
+func newCodecRegistry(config Config, serializer kafkaproto.Serializer) *codecRegistry {
+	registry := &codecRegistry{
+		fallback: newCodec(config.Codec, serializer),
+		byTopic:  make(map[string]Codec, len(config.TopicCodecs)),
+	}
+	for _, tc := range config.TopicCodecs {
+		registry.byTopic[tc.Topic] = newCodec(tc.Codec, serializer)
+	}
+	return registry
+}
+
+func (r *codecRegistry) forTopic(topic string) Codec {
+	if codec, ok := r.byTopic[topic]; ok {
+		return codec
+	}
+	return r.fallback
+}
@@ -0,0 +1,107 @@
+package outbox
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/kafkaproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func testCodecHeaders() map[string]string {
+	return map[string]string{
+		"event_id":      "evt-1",
+		HeaderEventType: "google.protobuf.StringValue",
+		"source":        "orders-service",
+		"timestamp":     "1700000000000",
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	event := wrapperspb.String("hello")
+	protoBytes, marshalErr := proto.Marshal(event)
+	require.NoError(t, marshalErr)
+
+	t.Run("proto", func(t *testing.T) {
+		headers := testCodecHeaders()
+		data, err := NewProtoCodec(kafkaproto.NewSerializer()).Encode(event, headers)
+
+		require.NoError(t, err)
+		assert.Equal(t, protoBytes, data)
+		assert.Equal(t, ContentTypeProtobuf, headers[HeaderContentType])
+	})
+
+	t.Run("protojson", func(t *testing.T) {
+		headers := testCodecHeaders()
+		data, err := NewProtoJSONCodec().Encode(event, headers)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `"hello"`, string(data))
+		assert.Equal(t, ContentTypeJSON, headers[HeaderContentType])
+	})
+
+	t.Run("cloudevents structured with json data", func(t *testing.T) {
+		headers := testCodecHeaders()
+		data, err := newCodec(CodecCloudEventsStructured, kafkaproto.NewSerializer()).Encode(event, headers)
+
+		require.NoError(t, err)
+		assert.Equal(t, ContentTypeCloudEventsJSON, headers[HeaderContentType])
+		assert.JSONEq(t, `{
+			"specversion": "1.0",
+			"id": "evt-1",
+			"source": "orders-service",
+			"type": "google.protobuf.StringValue",
+			"time": "2023-11-14T22:13:20Z",
+			"datacontenttype": "application/json",
+			"data": "hello"
+		}`, string(data))
+	})
+
+	t.Run("cloudevents structured with binary data", func(t *testing.T) {
+		headers := testCodecHeaders()
+		data, err := NewCloudEventsCodec(CloudEventsStructured, NewProtoCodec(kafkaproto.NewSerializer())).Encode(event, headers)
+		require.NoError(t, err)
+
+		var envelope map[string]any
+		require.NoError(t, json.Unmarshal(data, &envelope))
+		assert.Equal(t, ContentTypeProtobuf, envelope["datacontenttype"])
+		assert.Equal(t, base64.StdEncoding.EncodeToString(protoBytes), envelope["data_base64"])
+		assert.NotContains(t, envelope, "data")
+	})
+
+	t.Run("cloudevents binary", func(t *testing.T) {
+		headers := testCodecHeaders()
+		data, err := newCodec(CodecCloudEventsBinary, kafkaproto.NewSerializer()).Encode(event, headers)
+
+		require.NoError(t, err)
+		assert.Equal(t, protoBytes, data)
+		assert.Equal(t, ContentTypeProtobuf, headers[HeaderContentType])
+		assert.Equal(t, "1.0", headers["ce_specversion"])
+		assert.Equal(t, "evt-1", headers["ce_id"])
+		assert.Equal(t, "orders-service", headers["ce_source"])
+		assert.Equal(t, "google.protobuf.StringValue", headers["ce_type"])
+		assert.Equal(t, "2023-11-14T22:13:20Z", headers["ce_time"])
+	})
+
+	t.Run("unknown codec fails on encode", func(t *testing.T) {
+		_, err := newCodec("avro", kafkaproto.NewSerializer()).Encode(event, testCodecHeaders())
+		assert.ErrorContains(t, err, `unknown outbox codec "avro"`)
+	})
+}
+
+func TestConfig_ValidateCodecs(t *testing.T) {
+	cfg := Config{}
+	cfg.ApplyDefaults()
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, CodecProto, cfg.Codec)
+
+	cfg.TopicCodecs = []TopicCodecConfig{{Topic: "partner.events", Codec: "avro"}}
+	assert.ErrorContains(t, cfg.Validate(), `unknown outbox codec "avro" for topic "partner.events"`)
+
+	cfg.TopicCodecs = []TopicCodecConfig{{Codec: CodecProtoJSON}}
+	assert.Error(t, cfg.Validate())
+}
@@ -1,12 +1,158 @@
 package outbox
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxBatchSize bounds Config.BatchSize so a single fetch does not lock more messages
+// than can be sent before the lock expires.
+const maxBatchSize = 1000
 
 // Config holds the outbox pattern configuration.
 type Config struct {
 	// MaxBackoff is the maximum delay between retry attempts.
 	// Default: 10 hours
 	MaxBackoff time.Duration `koanf:"max-backoff"`
+
+	// MaxAttempts is the number of delivery attempts after which a message
+	// is moved to StatusFailed and no longer retried.
+	// Default: 15
+	MaxAttempts int32 `koanf:"max-attempts"`
+
+	// DLQTopic is an optional Kafka topic that failed messages are copied to.
+	// Empty disables the copy; failed messages are still kept in the outbox collection.
+	DLQTopic string `koanf:"dlq-topic"`
+
+	// BatchSize is the maximum number of messages the fetcher locks per database round trip.
+	// Default: 100
+	BatchSize int `koanf:"batch-size"`
+
+	// PollInterval is how long the fetcher waits before polling again when no message is due.
+	// Default: 5 seconds
+	PollInterval time.Duration `koanf:"poll-interval"`
+
+	// ErrorBackoff is how long the fetcher waits after a failed poll.
+	// Default: 10 seconds
+	ErrorBackoff time.Duration `koanf:"error-backoff"`
+
+	// DrainTimeout bounds the shutdown drain: flushing records already handed to the producer
+	// and storing their delivery confirmations. Keep it below the application stop timeout.
+	// Default: 10 seconds
+	DrainTimeout time.Duration `koanf:"drain-timeout"`
+
+	// ChangeStream configures change stream driven dispatch.
+	ChangeStream ChangeStreamConfig `koanf:"change-stream"`
+
+	// StrictOrdering guarantees that messages with the same key and topic are produced
+	// in creation order even across retries: a message is not sent while an earlier one
+	// with the same key is still undelivered. FAILED messages no longer block their successors.
+	// Costs an extra lookup per fetch and disables the direct hand-off after commit
+	// while a predecessor is pending.
+	// Default: false
+	StrictOrdering bool `koanf:"strict-ordering"`
+
+	// TenantScoped stores messages in the outbox collection of each tenant database
+	// ({db}_{slug}), so tenant transactions can include the outbox insert and tenant cleanup
+	// removes pending messages. The fetcher dispatches from every active tenant.
+	// Requires multi-tenancy to be enabled.
+	// Default: false
+	TenantScoped bool `koanf:"tenant-scoped"`
+
+	// RequireTransaction makes Outbox.Create fail with ErrNoTransaction unless the context
+	// carries an active MongoDB transaction (see mongo.TxManager and PublishInTx).
+	// Default: false
+	RequireTransaction bool `koanf:"require-transaction"`
+
+	// Codec is the payload codec for events of topics without a TopicCodecs entry:
+	// proto, protojson, cloudevents-structured or cloudevents-binary.
+	// Messages with a pre-encoded Payload bypass the codec.
+	// Default: proto
+	Codec string `koanf:"codec"`
+
+	// TopicCodecs overrides Codec for individual topics.
+	TopicCodecs []TopicCodecConfig `koanf:"topic-codecs"`
+
+	// Retention configures how long delivered and failed messages are kept.
+	Retention RetentionConfig `koanf:"retention"`
+
+	// Admin configures the optional HTTP admin API.
+	Admin AdminConfig `koanf:"admin"`
+}
+
+// RetentionConfig holds the per-status retention of outbox messages.
+// PROCESSING messages are never removed automatically.
+type RetentionConfig struct {
+	// Sent is how long SENT messages are kept after delivery.
+	// Default: 120 hours (5 days)
+	Sent time.Duration `koanf:"sent"`
+
+	// Failed is how long FAILED messages are kept after they failed.
+	// Zero keeps them until they are requeued or purged via the admin API.
+	// Default: 0
+	Failed time.Duration `koanf:"failed"`
+
+	// Archive configures copying SENT messages elsewhere before they are deleted.
+	Archive ArchiveConfig `koanf:"archive"`
+}
+
+// Archive targets accepted by ArchiveConfig.Target.
+const (
+	// ArchiveTargetCollection copies messages to the outbox_archive collection
+	// in the database of the outbox collection.
+	ArchiveTargetCollection = "collection"
+	// ArchiveTargetJSONL appends messages to daily JSONL files in ArchiveConfig.Directory.
+	ArchiveTargetJSONL = "jsonl"
+)
+
+// ArchiveConfig holds the archival configuration of SENT messages.
+type ArchiveConfig struct {
+	// Enabled archives SENT messages once they are older than RetentionConfig.Sent
+	// and deletes them afterwards. Replaces the TTL index for SENT messages.
+	Enabled bool `koanf:"enabled"`
+
+	// Target is where messages are archived: collection or jsonl.
+	// Default: collection
+	Target string `koanf:"target"`
+
+	// Directory holds the JSONL files. Required for the jsonl target.
+	Directory string `koanf:"directory"`
+
+	// Interval is the time between archival runs.
+	// Default: 1 hour
+	Interval time.Duration `koanf:"interval"`
+
+	// BatchSize is the number of messages archived and deleted per round trip.
+	// Default: 500
+	BatchSize int `koanf:"batch-size"`
+}
+
+// ChangeStreamConfig holds the outbox change stream configuration.
+type ChangeStreamConfig struct {
+	// Enabled makes the fetcher watch the outbox collection for inserts and wake up
+	// as soon as a new message is due instead of waiting for the next poll.
+	// Polling stays active as a fallback. Requires a replica set; on a standalone
+	// server the watcher logs a warning and the fetcher keeps polling.
+	Enabled bool `koanf:"enabled"`
+}
+
+// TopicCodecConfig selects the payload codec of a topic.
+type TopicCodecConfig struct {
+	Topic string `koanf:"topic"`
+	Codec string `koanf:"codec"`
+}
+
+// AdminConfig holds the outbox admin API configuration.
+type AdminConfig struct {
+	// Enabled registers the admin endpoints on the shared *http.ServeMux.
+	// The endpoints are not authenticated, expose them on internal networks only.
+	Enabled bool `koanf:"enabled"`
+
+	// PathPrefix is the route prefix for the admin endpoints.
+	// Default: /admin/outbox
+	PathPrefix string `koanf:"path-prefix"`
 }
 
 // ApplyDefaults sets default values for unset configuration fields.
@@ -14,9 +160,73 @@ func (c *Config) ApplyDefaults() {
 	if c.MaxBackoff <= 0 {
 		c.MaxBackoff = 10 * time.Hour
 	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 15
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = 5 * time.Second
+	}
+	if c.ErrorBackoff <= 0 {
+		c.ErrorBackoff = 10 * time.Second
+	}
+	if c.DrainTimeout <= 0 {
+		c.DrainTimeout = 10 * time.Second
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.Codec == "" {
+		c.Codec = CodecProto
+	}
+	if c.Retention.Sent <= 0 {
+		c.Retention.Sent = 5 * 24 * time.Hour
+	}
+	if c.Retention.Archive.Target == "" {
+		c.Retention.Archive.Target = ArchiveTargetCollection
+	}
+	if c.Retention.Archive.Interval <= 0 {
+		c.Retention.Archive.Interval = time.Hour
+	}
+	if c.Retention.Archive.BatchSize <= 0 {
+		c.Retention.Archive.BatchSize = 500
+	}
+	if c.Admin.PathPrefix == "" {
+		c.Admin.PathPrefix = "/admin/outbox"
+	}
 }
 
 // Validate validates the outbox configuration.
 func (c *Config) Validate() error {
+	if c.BatchSize > maxBatchSize {
+		return fmt.Errorf("outbox batch size must not exceed %d, got: %d", maxBatchSize, c.BatchSize)
+	}
+	if !isKnownCodec(c.Codec) {
+		return fmt.Errorf("unknown outbox codec %q", c.Codec)
+	}
+	for _, tc := range c.TopicCodecs {
+		if tc.Topic == "" {
+			return errors.New("outbox topic codec must have a topic")
+		}
+		if !isKnownCodec(tc.Codec) {
+			return fmt.Errorf("unknown outbox codec %q for topic %q", tc.Codec, tc.Topic)
+		}
+	}
+	if c.Retention.Failed < 0 {
+		return fmt.Errorf("outbox failed message retention must not be negative, got: %s", c.Retention.Failed)
+	}
+	if archive := c.Retention.Archive; archive.Enabled {
+		switch archive.Target {
+		case ArchiveTargetCollection:
+		case ArchiveTargetJSONL:
+			if archive.Directory == "" {
+				return errors.New("outbox archive directory is required for the jsonl target")
+			}
+		default:
+			return fmt.Errorf("unknown outbox archive target %q", archive.Target)
+		}
+	}
+	if c.Admin.Enabled && (!strings.HasPrefix(c.Admin.PathPrefix, "/") || strings.HasSuffix(c.Admin.PathPrefix, "/")) {
+		return fmt.Errorf("outbox admin path prefix must start with '/' and must not end with '/', got: %q", c.Admin.PathPrefix)
+	}
 	return nil
 }
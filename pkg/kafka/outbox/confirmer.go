@@ -10,13 +10,20 @@ import (
 
 // ConfirmResult carries the result of a Kafka produce callback.
 type ConfirmResult struct {
-	ID  string
-	Err error
+	ID string
+	// Tenant is the tenant slug of a tenant-scoped message, empty otherwise.
+	Tenant string
+	// Topic and CreatedAt of the message, used for delivery metrics.
+	Topic     string
+	CreatedAt time.Time
+	Err       error
 }
 
 type Confirmer struct {
 	outboxRepository Repository
 	confirmChan      <-chan ConfirmResult
+	metrics          *Metrics
+	drainTimeout     time.Duration
 	logger           *zap.Logger
 	wg               sync.WaitGroup
 }
@@ -24,18 +31,25 @@ type Confirmer struct {
 func NewConfirmer(
 	outboxRepository Repository,
 	confirmChan chan ConfirmResult,
+	metrics *Metrics,
+	config Config,
 	logger *zap.Logger,
 ) *Confirmer {
 	return &Confirmer{
 		outboxRepository: outboxRepository,
 		confirmChan:      confirmChan,
+		metrics:          metrics,
+		drainTimeout:     config.DrainTimeout,
 		logger:           logger,
 	}
 }
 
+// Run stores delivery results until ctx is cancelled, then drains the results that are
+// already queued. Repository writes are not tied to ctx so that shutdown does not abort them.
 func (c *Confirmer) Run(ctx context.Context) error {
 	defer c.wg.Wait()
 
+	writeCtx := context.WithoutCancel(ctx)
 	results := make([]ConfirmResult, 0, 100)
 
 	flush := func() {
@@ -45,7 +59,7 @@ func (c *Confirmer) Run(ctx context.Context) error {
 		copySlice := make([]ConfirmResult, len(results))
 		copy(copySlice, results)
 		c.wg.Add(1)
-		go c.handleConfirmation(ctx, copySlice)
+		go c.handleConfirmation(writeCtx, copySlice)
 		results = results[:0]
 	}
 
@@ -55,14 +69,14 @@ func (c *Confirmer) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
-			flush()
+			c.drain(writeCtx, results)
 			return nil
 		default:
 		}
 
 		select {
 		case <-ctx.Done():
-			flush()
+			c.drain(writeCtx, results)
 			return nil
 		case result := <-c.confirmChan:
 			results = append(results, result)
@@ -75,18 +89,57 @@ func (c *Confirmer) Run(ctx context.Context) error {
 	}
 }
 
+// drain stores pending and queued results within the drain timeout.
+// The Sender has already been drained, so the queue holds every outstanding result.
+func (c *Confirmer) drain(ctx context.Context, pending []ConfirmResult) {
+	ctx, cancel := context.WithTimeout(ctx, c.drainTimeout)
+	defer cancel()
+
+	results := append([]ConfirmResult(nil), pending...)
+	for queued := true; queued; {
+		select {
+		case result := <-c.confirmChan:
+			results = append(results, result)
+		default:
+			queued = false
+		}
+	}
+
+	if len(results) > 0 {
+		c.wg.Add(1)
+		c.handleConfirmation(ctx, results)
+	}
+	c.logger.Info("confirmer drained", zap.Int("count", len(results)))
+}
+
 func (c *Confirmer) handleConfirmation(ctx context.Context, results []ConfirmResult) {
 	defer c.wg.Done()
 
+	byTenant := make(map[string][]ConfirmResult)
+	for _, r := range results {
+		byTenant[r.Tenant] = append(byTenant[r.Tenant], r)
+	}
+	for slug, tenantResults := range byTenant {
+		c.confirm(withTenant(ctx, slug), tenantResults)
+	}
+}
+
+func (c *Confirmer) confirm(ctx context.Context, results []ConfirmResult) {
 	ids := make([]string, 0, len(results))
+	sent := make([]ConfirmResult, 0, len(results))
 	for _, r := range results {
 		if r.Err != nil {
 			c.logger.Error("kafka delivery failed - message will be retried",
 				zap.String("message_id", r.ID),
 				zap.Error(r.Err))
+			if err := c.outboxRepository.UpdateLastError(ctx, r.ID, r.Err.Error()); err != nil {
+				c.logger.Error("failed to record delivery error", zap.String("message_id", r.ID), zap.Error(err))
+				c.metrics.recordConfirmFailures(ctx, r.Topic, 1)
+			}
 			continue
 		}
 		ids = append(ids, r.ID)
+		sent = append(sent, r)
 	}
 
 	if len(ids) == 0 {
@@ -96,8 +149,15 @@ func (c *Confirmer) handleConfirmation(ctx context.Context, results []ConfirmRes
 	err := c.outboxRepository.UpdateAsSentByIDs(ctx, ids)
 	if err != nil {
 		c.logger.Error("failed to update confirmation", zap.Error(err))
+		for _, r := range sent {
+			c.metrics.recordConfirmFailures(ctx, r.Topic, 1)
+		}
 		return
 	}
 
+	for _, r := range sent {
+		c.metrics.recordDelivered(ctx, r.Topic, r.CreatedAt)
+	}
+
 	c.logger.Debug("outbox sending confirmed", zap.Int("count", len(ids)))
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -16,7 +17,7 @@ func TestConfirmer_Run(t *testing.T) {
 	t.Run("processes confirm results and updates repository", func(t *testing.T) {
 		repo := newMockRepository()
 		confirmChan := make(chan ConfirmResult, 10)
-		c := NewConfirmer(repo, confirmChan, zap.NewNop())
+		c := NewConfirmer(repo, confirmChan, newTestMetrics(t), Config{DrainTimeout: time.Second}, zap.NewNop())
 
 		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 		defer cancel()
@@ -43,7 +44,7 @@ func TestConfirmer_Run(t *testing.T) {
 	t.Run("batches multiple confirm results", func(t *testing.T) {
 		repo := newMockRepository()
 		confirmChan := make(chan ConfirmResult, 200)
-		c := NewConfirmer(repo, confirmChan, zap.NewNop())
+		c := NewConfirmer(repo, confirmChan, newTestMetrics(t), Config{DrainTimeout: time.Second}, zap.NewNop())
 
 		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 		defer cancel()
@@ -72,7 +73,7 @@ func TestConfirmer_Run(t *testing.T) {
 	t.Run("returns nil when context is cancelled", func(t *testing.T) {
 		repo := newMockRepository()
 		confirmChan := make(chan ConfirmResult, 10)
-		c := NewConfirmer(repo, confirmChan, zap.NewNop())
+		c := NewConfirmer(repo, confirmChan, newTestMetrics(t), Config{DrainTimeout: time.Second}, zap.NewNop())
 
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel()
@@ -85,7 +86,7 @@ func TestConfirmer_Run(t *testing.T) {
 	t.Run("skips results with delivery errors", func(t *testing.T) {
 		repo := newMockRepository()
 		confirmChan := make(chan ConfirmResult, 10)
-		c := NewConfirmer(repo, confirmChan, zap.NewNop())
+		c := NewConfirmer(repo, confirmChan, newTestMetrics(t), Config{DrainTimeout: time.Second}, zap.NewNop())
 
 		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 		defer cancel()
@@ -110,13 +111,14 @@ func TestConfirmer_Run(t *testing.T) {
 		ids := repo.GetUpdateAsSentIDs()
 		assert.NotContains(t, ids, "failed-message")
 		assert.Contains(t, ids, "success-message")
+		assert.Equal(t, map[string]string{"failed-message": "kafka delivery failed"}, repo.GetLastErrors())
 	})
 
 	t.Run("handles repository error gracefully", func(t *testing.T) {
 		repo := newMockRepository()
 		repo.updateAsSentErr = errors.New("database error")
 		confirmChan := make(chan ConfirmResult, 10)
-		c := NewConfirmer(repo, confirmChan, zap.NewNop())
+		c := NewConfirmer(repo, confirmChan, newTestMetrics(t), Config{DrainTimeout: time.Second}, zap.NewNop())
 
 		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 		defer cancel()
@@ -141,7 +143,7 @@ func TestConfirmer_Run(t *testing.T) {
 	t.Run("flushes remaining results on context cancellation", func(t *testing.T) {
 		repo := newMockRepository()
 		confirmChan := make(chan ConfirmResult, 10)
-		c := NewConfirmer(repo, confirmChan, zap.NewNop())
+		c := NewConfirmer(repo, confirmChan, newTestMetrics(t), Config{DrainTimeout: time.Second}, zap.NewNop())
 
 		ctx, cancel := context.WithCancel(context.Background())
 
@@ -164,7 +166,43 @@ func TestConfirmer_Run(t *testing.T) {
 
 		// Results should be flushed on cancellation
 		ids := repo.GetUpdateAsSentIDs()
-		assert.GreaterOrEqual(t, len(ids), 0) // At least attempted to flush
+		assert.Len(t, ids, 5)
+	})
+
+	t.Run("drains queued results after cancellation", func(t *testing.T) {
+		repo := newMockRepository()
+		confirmChan := make(chan ConfirmResult, 10)
+		c := NewConfirmer(repo, confirmChan, newTestMetrics(t), Config{DrainTimeout: time.Second}, zap.NewNop())
+
+		// Results that arrive while the confirmer is already stopping.
+		for i := 0; i < 3; i++ {
+			confirmChan <- ConfirmResult{ID: fmt.Sprintf("late-%d", i)}
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		require.NoError(t, c.Run(ctx))
+
+		assert.ElementsMatch(t, []string{"late-0", "late-1", "late-2"}, repo.GetUpdateAsSentIDs())
+		assert.Empty(t, confirmChan)
+	})
+}
+
+func TestConfirmer_Tenants(t *testing.T) {
+	t.Run("confirms results in the tenant of each message", func(t *testing.T) {
+		repo := newMockRepository()
+		c := NewConfirmer(repo, make(chan ConfirmResult), newTestMetrics(t), Config{DrainTimeout: time.Second}, zap.NewNop())
+
+		c.wg.Add(1)
+		c.handleConfirmation(context.Background(), []ConfirmResult{
+			{ID: "acme-1", Tenant: "acme"},
+			{ID: "globex-1", Tenant: "globex"},
+			{ID: "acme-2", Tenant: "acme"},
+			{ID: "shared-1"},
+		})
+
+		assert.ElementsMatch(t, []string{"acme", "globex", ""}, repo.sentTenants)
+		assert.ElementsMatch(t, []string{"acme-1", "acme-2", "globex-1", "shared-1"}, repo.GetUpdateAsSentIDs())
 	})
 }
 
@@ -174,7 +212,7 @@ func TestNewConfirmer(t *testing.T) {
 		confirmChan := make(chan ConfirmResult)
 		logger := zap.NewNop()
 
-		c := NewConfirmer(repo, confirmChan, logger)
+		c := NewConfirmer(repo, confirmChan, newTestMetrics(t), Config{DrainTimeout: time.Second}, logger)
 
 		assert.NotNil(t, c)
 		assert.Equal(t, repo, c.outboxRepository)
@@ -0,0 +1,83 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/producer"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+// DLQSender copies outbox messages that exhausted their delivery attempts to a Dead Letter Queue.
+type DLQSender interface {
+	// SendToDLQ synchronously produces the entity to the DLQ topic.
+	SendToDLQ(ctx context.Context, entity *OutboxEntity) error
+}
+
+type dlqSender struct {
+	producer        producer.Producer
+	dlqTopic        string
+	tracePropagator TracePropagator
+	logger          *zap.Logger
+}
+
+// NewDLQSender creates a DLQSender for Config.DLQTopic.
+// Returns a no-op implementation if no DLQ topic is configured.
+func NewDLQSender(producer producer.Producer, config Config, tracePropagator TracePropagator, logger *zap.Logger) DLQSender {
+	if config.DLQTopic == "" {
+		return &noopDLQSender{}
+	}
+	return &dlqSender{
+		producer:        producer,
+		dlqTopic:        config.DLQTopic,
+		tracePropagator: tracePropagator,
+		logger:          logger,
+	}
+}
+
+func (s *dlqSender) SendToDLQ(ctx context.Context, entity *OutboxEntity) error {
+	_, span, kafkaHeaders := s.tracePropagator.StartKafkaProducerSpan(entity.Headers, s.dlqTopic, entity.ID)
+	defer span.End()
+
+	kafkaHeaders = append(kafkaHeaders,
+		kgo.RecordHeader{Key: "dlq.original.topic", Value: []byte(entity.Topic)},
+		kgo.RecordHeader{Key: "dlq.error", Value: []byte(entity.LastError)},
+		kgo.RecordHeader{Key: "dlq.attempts", Value: []byte(fmt.Sprintf("%d", entity.AttemptsToSend))},
+		kgo.RecordHeader{Key: "dlq.timestamp", Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	record := &kgo.Record{
+		Topic:   s.dlqTopic,
+		Key:     []byte(entity.Key),
+		Value:   entity.Payload,
+		Headers: kafkaHeaders,
+	}
+
+	errCh := make(chan error, 1)
+	s.producer.Produce(ctx, record, func(_ *kgo.Record, err error) {
+		errCh <- err
+	})
+
+	if err := <-errCh; err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to deliver message to DLQ")
+		return fmt.Errorf("failed to send outbox message to DLQ: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "message sent to DLQ")
+	s.logger.Info("outbox message sent to DLQ",
+		zap.String("id", entity.ID),
+		zap.String("dlq_topic", s.dlqTopic),
+		zap.String("original_topic", entity.Topic))
+	return nil
+}
+
+// noopDLQSender is used when Config.DLQTopic is not set.
+type noopDLQSender struct{}
+
+func (s *noopDLQSender) SendToDLQ(_ context.Context, _ *OutboxEntity) error {
+	return nil
+}
@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+// mockDLQSender is a mock implementation of DLQSender
+type mockDLQSender struct {
+	mu       sync.Mutex
+	entities []*OutboxEntity
+	err      error
+}
+
+func (m *mockDLQSender) SendToDLQ(ctx context.Context, entity *OutboxEntity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entities = append(m.entities, entity)
+	return m.err
+}
+
+func (m *mockDLQSender) GetEntities() []*OutboxEntity {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]*OutboxEntity, len(m.entities))
+	copy(result, m.entities)
+	return result
+}
+
+func TestNewDLQSender(t *testing.T) {
+	t.Run("returns noop sender when DLQ topic is not configured", func(t *testing.T) {
+		s := NewDLQSender(&mockProducer{}, Config{}, NewTracePropagator(noop.NewTracerProvider()), zap.NewNop())
+
+		assert.IsType(t, &noopDLQSender{}, s)
+		assert.NoError(t, s.SendToDLQ(context.Background(), &OutboxEntity{ID: "id"}))
+	})
+
+	t.Run("returns kafka sender when DLQ topic is configured", func(t *testing.T) {
+		s := NewDLQSender(&mockProducer{}, Config{DLQTopic: "outbox.dlq"}, NewTracePropagator(noop.NewTracerProvider()), zap.NewNop())
+
+		assert.IsType(t, &dlqSender{}, s)
+	})
+}
+
+func TestDLQSender_SendToDLQ(t *testing.T) {
+	t.Run("produces entity to DLQ topic with failure headers", func(t *testing.T) {
+		producer := &mockProducer{}
+		s := NewDLQSender(producer, Config{DLQTopic: "outbox.dlq"}, NewTracePropagator(noop.NewTracerProvider()), zap.NewNop())
+
+		err := s.SendToDLQ(context.Background(), &OutboxEntity{
+			ID:             "failed-id",
+			Payload:        []byte("payload"),
+			Key:            "key",
+			Topic:          "orders",
+			Headers:        map[string]string{"event_id": "failed-id"},
+			LastError:      "broker unavailable",
+			AttemptsToSend: 15,
+		})
+
+		require.NoError(t, err)
+		records := producer.GetRecords()
+		require.Len(t, records, 1)
+		assert.Equal(t, "outbox.dlq", records[0].Topic)
+		assert.Equal(t, []byte("key"), records[0].Key)
+		assert.Equal(t, []byte("payload"), records[0].Value)
+
+		headers := make(map[string]string)
+		for _, h := range records[0].Headers {
+			headers[h.Key] = string(h.Value)
+		}
+		assert.Equal(t, "failed-id", headers["event_id"])
+		assert.Equal(t, "orders", headers["dlq.original.topic"])
+		assert.Equal(t, "broker unavailable", headers["dlq.error"])
+		assert.Equal(t, "15", headers["dlq.attempts"])
+		assert.NotEmpty(t, headers["dlq.timestamp"])
+	})
+
+	t.Run("returns error when delivery fails", func(t *testing.T) {
+		producer := &mockProducer{
+			produceFunc: func(ctx context.Context, record *kgo.Record, promise func(*kgo.Record, error)) {
+				promise(record, errors.New("delivery failed"))
+			},
+		}
+		s := NewDLQSender(producer, Config{DLQTopic: "outbox.dlq"}, NewTracePropagator(noop.NewTracerProvider()), zap.NewNop())
+
+		err := s.SendToDLQ(context.Background(), &OutboxEntity{ID: "failed-id", Topic: "orders"})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to send outbox message to DLQ")
+	})
+}
@@ -2,22 +2,46 @@ package outbox
 
 import (
 	"context"
-	"errors"
 	"time"
 
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
 	"go.uber.org/zap"
 )
 
+// backlogRefreshInterval limits how often the backlog gauges are recomputed.
+const backlogRefreshInterval = 30 * time.Second
+
 type Fetcher struct {
 	outboxRepository Repository
 	entitiesChan     chan<- *OutboxEntity
+	wakeups          <-chan time.Time
+	dlqSender        DLQSender
+	tenants          tenant.Repository
+	metrics          *Metrics
+	backlogUpdatedAt time.Time
+	maxAttempts      int32
+	batchSize        int
+	pollInterval     time.Duration
+	errorBackoff     time.Duration
 	logger           *zap.Logger
 }
 
-func NewFetcher(outboxRepository Repository, entitiesChan chan *OutboxEntity, logger *zap.Logger) *Fetcher {
+// NewFetcher creates a Fetcher. Due times received on wakeups (see ChangeWatcher)
+// cut the wait between polls short; a nil channel disables this.
+// With a non-nil tenants repository the fetcher dispatches from the outbox of every
+// active tenant in turn; nil means a single shared outbox collection.
+func NewFetcher(outboxRepository Repository, entitiesChan chan *OutboxEntity, wakeups chan time.Time, dlqSender DLQSender, tenants tenant.Repository, metrics *Metrics, config Config, logger *zap.Logger) *Fetcher {
 	return &Fetcher{
 		outboxRepository: outboxRepository,
 		entitiesChan:     entitiesChan,
+		wakeups:          wakeups,
+		dlqSender:        dlqSender,
+		tenants:          tenants,
+		metrics:          metrics,
+		maxAttempts:      config.MaxAttempts,
+		batchSize:        config.BatchSize,
+		pollInterval:     config.PollInterval,
+		errorBackoff:     config.ErrorBackoff,
 		logger:           logger,
 	}
 }
@@ -30,29 +54,169 @@ func (f *Fetcher) Run(ctx context.Context) error {
 		default:
 		}
 
-		entity, err := f.outboxRepository.FetchAndLock(ctx)
+		slugs, err := f.tenantSlugs(ctx)
 		if err != nil {
-			if errors.Is(err, errEntityNotFound) {
-				select {
-				case <-ctx.Done():
-					return nil
-				case <-time.After(5 * time.Second):
-				}
-				continue
+			f.logger.Error("failed to list tenants for outbox dispatch", zap.Error(err))
+			if !f.waitForRetry(ctx) {
+				return nil
 			}
-			f.logger.Error("failed to get outbox entity", zap.Error(err))
-			select {
-			case <-ctx.Done():
+			continue
+		}
+		f.refreshBacklog(ctx, slugs)
+
+		// Every tenant gets one batch per round so a large backlog in one tenant
+		// does not starve the others.
+		dispatched, failed := 0, false
+		for _, slug := range slugs {
+			n, dispatchErr := f.dispatch(withTenant(ctx, slug))
+			if ctx.Err() != nil {
 				return nil
-			case <-time.After(10 * time.Second):
 			}
+			if dispatchErr != nil {
+				f.logger.Error("failed to get outbox entities", zap.String("tenant", slug), zap.Error(dispatchErr))
+				failed = true
+			}
+			dispatched += n
+		}
+
+		switch {
+		case dispatched > 0:
+			continue
+		case failed:
+			if !f.waitForRetry(ctx) {
+				return nil
+			}
+		default:
+			if !f.waitForWork(ctx) {
+				return nil
+			}
+		}
+	}
+}
+
+// tenantSlugs returns the tenants to dispatch from, or a single empty slug for the shared outbox.
+func (f *Fetcher) tenantSlugs(ctx context.Context) ([]string, error) {
+	return activeTenantSlugs(ctx, f.tenants)
+}
+
+// activeTenantSlugs returns the slugs of all active tenants, or a single empty slug
+// for the shared outbox when tenants is nil.
+func activeTenantSlugs(ctx context.Context, tenants tenant.Repository) ([]string, error) {
+	if tenants == nil {
+		return []string{""}, nil
+	}
+	records, err := tenants.FindActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slugs := make([]string, 0, len(records))
+	for _, rec := range records {
+		slugs = append(slugs, rec.Slug)
+	}
+	return slugs, nil
+}
+
+// refreshBacklog recomputes the backlog gauges across all tenants at most once per backlogRefreshInterval.
+func (f *Fetcher) refreshBacklog(ctx context.Context, slugs []string) {
+	if time.Since(f.backlogUpdatedAt) < backlogRefreshInterval {
+		return
+	}
+	f.backlogUpdatedAt = time.Now()
+
+	byTopic := make(map[string]TopicBacklog)
+	for _, slug := range slugs {
+		backlog, err := f.outboxRepository.PendingBacklog(withTenant(ctx, slug))
+		if err != nil {
+			f.logger.Warn("failed to compute outbox backlog", zap.String("tenant", slug), zap.Error(err))
+			return
+		}
+		for _, b := range backlog {
+			merged, ok := byTopic[b.Topic]
+			if ok && merged.OldestCreatedAt.Before(b.OldestCreatedAt) {
+				b.OldestCreatedAt = merged.OldestCreatedAt
+			}
+			b.Count += merged.Count
+			byTopic[b.Topic] = b
+		}
+	}
+
+	backlog := make([]TopicBacklog, 0, len(byTopic))
+	for _, b := range byTopic {
+		backlog = append(backlog, b)
+	}
+	f.metrics.recordBacklog(backlog)
+}
+
+// dispatch locks one batch and hands it to the sender. Returns the number of entities fetched.
+func (f *Fetcher) dispatch(ctx context.Context) (int, error) {
+	entities, err := f.outboxRepository.FetchAndLockBatch(ctx, f.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entity := range entities {
+		// FetchAndLockBatch has already counted the upcoming attempt
+		if f.maxAttempts > 0 && entity.AttemptsToSend > f.maxAttempts {
+			f.fail(ctx, entity)
 			continue
 		}
 
 		select {
 		case <-ctx.Done():
-			return nil
+			return len(entities), nil
 		case f.entitiesChan <- entity:
 		}
 	}
+	return len(entities), nil
+}
+
+// waitForRetry waits for the error backoff. Returns false if the context is done.
+func (f *Fetcher) waitForRetry(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(f.errorBackoff):
+		return true
+	}
+}
+
+// waitForWork waits for the poll interval or until a message announced on wakeups is due,
+// whichever comes first. Returns false if the context is done.
+func (f *Fetcher) waitForWork(ctx context.Context) bool {
+	deadline := time.Now().Add(f.pollInterval)
+	timer := time.NewTimer(f.pollInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case due := <-f.wakeups:
+			if due.Before(deadline) {
+				deadline = due
+				timer.Reset(time.Until(due))
+			}
+		}
+	}
+}
+
+// fail moves an entity that exhausted its delivery attempts to StatusFailed
+// and copies it to the DLQ if one is configured.
+func (f *Fetcher) fail(ctx context.Context, entity *OutboxEntity) {
+	f.logger.Error("outbox message exceeded max delivery attempts - marking as failed",
+		zap.String("id", entity.ID),
+		zap.String("topic", entity.Topic),
+		zap.Int32("attempts", entity.AttemptsToSend-1),
+		zap.String("last_error", entity.LastError))
+
+	if err := f.outboxRepository.MarkAsFailed(ctx, entity.ID); err != nil {
+		f.logger.Error("failed to mark outbox message as failed", zap.String("id", entity.ID), zap.Error(err))
+		return
+	}
+
+	if err := f.dlqSender.SendToDLQ(ctx, entity); err != nil {
+		f.logger.Error("failed to copy outbox message to DLQ", zap.String("id", entity.ID), zap.Error(err))
+	}
 }
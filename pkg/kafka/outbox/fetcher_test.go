@@ -2,15 +2,26 @@ package outbox
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
+func testFetcherConfig() Config {
+	return Config{
+		MaxAttempts:  3,
+		BatchSize:    10,
+		PollInterval: 5 * time.Second,
+		ErrorBackoff: 10 * time.Second,
+	}
+}
+
 func TestFetcher_Run(t *testing.T) {
 	t.Run("fetches entity and sends to channel", func(t *testing.T) {
 		repo := newMockRepository()
@@ -22,7 +33,7 @@ func TestFetcher_Run(t *testing.T) {
 		repo.SetFetchAndLockEntity(entity)
 
 		entitiesChan := make(chan *OutboxEntity, 10)
-		f := NewFetcher(repo, entitiesChan, zap.NewNop())
+		f := NewFetcher(repo, entitiesChan, nil, &mockDLQSender{}, nil, newTestMetrics(t), testFetcherConfig(), zap.NewNop())
 
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 		defer cancel()
@@ -50,7 +61,7 @@ func TestFetcher_Run(t *testing.T) {
 	t.Run("returns nil when context is cancelled", func(t *testing.T) {
 		repo := newMockRepository()
 		entitiesChan := make(chan *OutboxEntity, 10)
-		f := NewFetcher(repo, entitiesChan, zap.NewNop())
+		f := NewFetcher(repo, entitiesChan, nil, &mockDLQSender{}, nil, newTestMetrics(t), testFetcherConfig(), zap.NewNop())
 
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel()
@@ -65,7 +76,7 @@ func TestFetcher_Run(t *testing.T) {
 		repo.SetFetchAndLockError(errEntityNotFound)
 
 		entitiesChan := make(chan *OutboxEntity, 10)
-		f := NewFetcher(repo, entitiesChan, zap.NewNop())
+		f := NewFetcher(repo, entitiesChan, nil, &mockDLQSender{}, nil, newTestMetrics(t), testFetcherConfig(), zap.NewNop())
 
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 		defer cancel()
@@ -95,7 +106,7 @@ func TestFetcher_Run(t *testing.T) {
 		})
 
 		entitiesChan := make(chan *OutboxEntity, 10)
-		f := NewFetcher(repo, entitiesChan, zap.NewNop())
+		f := NewFetcher(repo, entitiesChan, nil, &mockDLQSender{}, nil, newTestMetrics(t), testFetcherConfig(), zap.NewNop())
 
 		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
 		defer cancel()
@@ -132,17 +143,277 @@ func TestFetcher_Run(t *testing.T) {
 	})
 }
 
+func TestFetcher_Wakeups(t *testing.T) {
+	t.Run("fetches again when announced message is due", func(t *testing.T) {
+		entity := &OutboxEntity{ID: "inserted"}
+		fetches := 0
+		repo := newMockRepositoryWithFetchFunc(func(ctx context.Context) (*OutboxEntity, error) {
+			fetches++
+			if fetches == 2 {
+				return entity, nil
+			}
+			return nil, errEntityNotFound
+		})
+
+		wakeups := make(chan time.Time, 1)
+		entitiesChan := make(chan *OutboxEntity, 1)
+		f := NewFetcher(repo, entitiesChan, wakeups, &mockDLQSender{}, nil, newTestMetrics(t), testFetcherConfig(), zap.NewNop())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() { _ = f.Run(ctx) }()
+
+		wakeups <- time.Now().Add(20 * time.Millisecond)
+
+		select {
+		case received := <-entitiesChan:
+			assert.Equal(t, "inserted", received.ID)
+		case <-time.After(time.Second):
+			t.Fatal("expected fetcher to wake up before the poll interval")
+		}
+	})
+
+	t.Run("ignores wakeups due after the next poll", func(t *testing.T) {
+		cfg := testFetcherConfig()
+		cfg.PollInterval = 50 * time.Millisecond
+		wakeups := make(chan time.Time, 1)
+		f := NewFetcher(newMockRepository(), nil, wakeups, &mockDLQSender{}, nil, newTestMetrics(t), cfg, zap.NewNop())
+		wakeups <- time.Now().Add(time.Hour)
+
+		start := time.Now()
+		require.True(t, f.waitForWork(context.Background()))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+// mockTenantRepository is a mock implementation of tenant.Repository
+type mockTenantRepository struct {
+	active []tenant.Record
+	err    error
+}
+
+func (m *mockTenantRepository) Upsert(ctx context.Context, slug string) error { return nil }
+
+func (m *mockTenantRepository) MarkForDeletion(ctx context.Context, slug string, deleteAfter time.Time) error {
+	return nil
+}
+
+func (m *mockTenantRepository) FindPendingDeletion(ctx context.Context) ([]tenant.Record, error) {
+	return nil, nil
+}
+
+func (m *mockTenantRepository) FindActive(ctx context.Context) ([]tenant.Record, error) {
+	return m.active, m.err
+}
+
+func (m *mockTenantRepository) Remove(ctx context.Context, slug string) error { return nil }
+
+func TestFetcher_Tenants(t *testing.T) {
+	t.Run("dispatches from every active tenant", func(t *testing.T) {
+		var mu sync.Mutex
+		fetched := map[string]bool{}
+		repo := newMockRepository()
+		repo.fetchBatchFunc = func(ctx context.Context, n int) ([]*OutboxEntity, error) {
+			slug, ok := tenant.SlugFromContext(ctx)
+			require.True(t, ok)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if fetched[slug] {
+				return nil, nil
+			}
+			fetched[slug] = true
+			return []*OutboxEntity{{ID: slug + "-1"}}, nil
+		}
+		tenants := &mockTenantRepository{active: []tenant.Record{{Slug: "acme"}, {Slug: "globex"}}}
+
+		entitiesChan := make(chan *OutboxEntity, 10)
+		f := NewFetcher(repo, entitiesChan, nil, &mockDLQSender{}, tenants, newTestMetrics(t), testFetcherConfig(), zap.NewNop())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		require.NoError(t, f.Run(ctx))
+
+		require.Len(t, entitiesChan, 2)
+		ids := []string{(<-entitiesChan).ID, (<-entitiesChan).ID}
+		assert.ElementsMatch(t, []string{"acme-1", "globex-1"}, ids)
+	})
+
+	t.Run("keeps dispatching other tenants when one fails", func(t *testing.T) {
+		var mu sync.Mutex
+		delivered := false
+		repo := newMockRepository()
+		repo.fetchBatchFunc = func(ctx context.Context, n int) ([]*OutboxEntity, error) {
+			slug, _ := tenant.SlugFromContext(ctx)
+			if slug == "broken" {
+				return nil, errors.New("database error")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if delivered {
+				return nil, nil
+			}
+			delivered = true
+			return []*OutboxEntity{{ID: "healthy-1"}}, nil
+		}
+		tenants := &mockTenantRepository{active: []tenant.Record{{Slug: "broken"}, {Slug: "healthy"}}}
+
+		entitiesChan := make(chan *OutboxEntity, 10)
+		f := NewFetcher(repo, entitiesChan, nil, &mockDLQSender{}, tenants, newTestMetrics(t), testFetcherConfig(), zap.NewNop())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		require.NoError(t, f.Run(ctx))
+
+		require.Len(t, entitiesChan, 1)
+		assert.Equal(t, "healthy-1", (<-entitiesChan).ID)
+	})
+}
+
+func TestFetcher_Batch(t *testing.T) {
+	t.Run("requests configured batch size and forwards all entities", func(t *testing.T) {
+		fetched := false
+		repo := newMockRepository()
+		repo.fetchBatchFunc = func(ctx context.Context, n int) ([]*OutboxEntity, error) {
+			if fetched {
+				return nil, nil
+			}
+			fetched = true
+			return []*OutboxEntity{{ID: "entity-1"}, {ID: "entity-2"}, {ID: "entity-3"}}, nil
+		}
+
+		cfg := testFetcherConfig()
+		cfg.BatchSize = 25
+
+		entitiesChan := make(chan *OutboxEntity, 10)
+		f := NewFetcher(repo, entitiesChan, nil, &mockDLQSender{}, nil, newTestMetrics(t), cfg, zap.NewNop())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		require.NoError(t, f.Run(ctx))
+
+		require.Len(t, entitiesChan, 3)
+		assert.Equal(t, "entity-1", (<-entitiesChan).ID)
+		assert.Equal(t, "entity-2", (<-entitiesChan).ID)
+		assert.Equal(t, "entity-3", (<-entitiesChan).ID)
+		assert.Equal(t, 25, repo.GetFetchBatchSizes()[0])
+	})
+
+	t.Run("fails exhausted entities without dropping the rest of the batch", func(t *testing.T) {
+		fetched := false
+		repo := newMockRepository()
+		repo.fetchBatchFunc = func(ctx context.Context, n int) ([]*OutboxEntity, error) {
+			if fetched {
+				return nil, nil
+			}
+			fetched = true
+			return []*OutboxEntity{{ID: "exhausted", AttemptsToSend: 4}, {ID: "fresh", AttemptsToSend: 1}}, nil
+		}
+		dlq := &mockDLQSender{}
+
+		entitiesChan := make(chan *OutboxEntity, 10)
+		f := NewFetcher(repo, entitiesChan, nil, dlq, nil, newTestMetrics(t), testFetcherConfig(), zap.NewNop())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		require.NoError(t, f.Run(ctx))
+
+		assert.Equal(t, []string{"exhausted"}, repo.GetFailedIDs())
+		require.Len(t, entitiesChan, 1)
+		assert.Equal(t, "fresh", (<-entitiesChan).ID)
+	})
+}
+
+func TestFetcher_MaxAttempts(t *testing.T) {
+	t.Run("marks exhausted entity as failed and sends it to DLQ", func(t *testing.T) {
+		exhausted := &OutboxEntity{ID: "exhausted", AttemptsToSend: 4, LastError: "broker unavailable"}
+		fetched := false
+		repo := newMockRepositoryWithFetchFunc(func(ctx context.Context) (*OutboxEntity, error) {
+			if fetched {
+				return nil, errEntityNotFound
+			}
+			fetched = true
+			return exhausted, nil
+		})
+		dlq := &mockDLQSender{}
+
+		entitiesChan := make(chan *OutboxEntity, 10)
+		f := NewFetcher(repo, entitiesChan, nil, dlq, nil, newTestMetrics(t), testFetcherConfig(), zap.NewNop())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		err := f.Run(ctx)
+
+		require.NoError(t, err)
+		assert.Empty(t, entitiesChan)
+		assert.Equal(t, []string{"exhausted"}, repo.GetFailedIDs())
+		require.Len(t, dlq.GetEntities(), 1)
+		assert.Equal(t, "broker unavailable", dlq.GetEntities()[0].LastError)
+	})
+
+	t.Run("sends entity on its last allowed attempt", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.SetFetchAndLockEntity(&OutboxEntity{ID: "last-attempt", AttemptsToSend: 3})
+
+		entitiesChan := make(chan *OutboxEntity, 1)
+		f := NewFetcher(repo, entitiesChan, nil, &mockDLQSender{}, nil, newTestMetrics(t), testFetcherConfig(), zap.NewNop())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go func() { _ = f.Run(ctx) }()
+
+		select {
+		case received := <-entitiesChan:
+			assert.Equal(t, "last-attempt", received.ID)
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("expected entity in channel")
+		}
+		assert.Empty(t, repo.GetFailedIDs())
+	})
+
+	t.Run("does not send to DLQ when marking as failed fails", func(t *testing.T) {
+		fetched := false
+		repo := newMockRepositoryWithFetchFunc(func(ctx context.Context) (*OutboxEntity, error) {
+			if fetched {
+				return nil, errEntityNotFound
+			}
+			fetched = true
+			return &OutboxEntity{ID: "exhausted", AttemptsToSend: 4}, nil
+		})
+		repo.markAsFailedErr = errors.New("database error")
+		dlq := &mockDLQSender{}
+
+		f := NewFetcher(repo, make(chan *OutboxEntity, 1), nil, dlq, nil, newTestMetrics(t), testFetcherConfig(), zap.NewNop())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		require.NoError(t, f.Run(ctx))
+		assert.Empty(t, dlq.GetEntities())
+	})
+}
+
 func TestNewFetcher(t *testing.T) {
 	t.Run("creates fetcher with dependencies", func(t *testing.T) {
 		repo := newMockRepository()
 		entitiesChan := make(chan *OutboxEntity)
 		logger := zap.NewNop()
 
-		f := NewFetcher(repo, entitiesChan, logger)
+		f := NewFetcher(repo, entitiesChan, nil, &mockDLQSender{}, nil, newTestMetrics(t), testFetcherConfig(), logger)
 
 		assert.NotNil(t, f)
 		assert.Equal(t, repo, f.outboxRepository)
 		assert.NotNil(t, f.entitiesChan)
+		assert.Equal(t, int32(3), f.maxAttempts)
+		assert.Equal(t, 10, f.batchSize)
+		assert.Equal(t, 5*time.Second, f.pollInterval)
+		assert.Equal(t, 10*time.Second, f.errorBackoff)
 		assert.Equal(t, logger, f.logger)
 	})
 }
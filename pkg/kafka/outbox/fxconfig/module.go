@@ -2,6 +2,9 @@ package fxconfig
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"time"
 
 	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
 
@@ -10,6 +13,8 @@ import (
 	"github.com/Sokol111/ecommerce-commons/pkg/core/worker"
 	"github.com/Sokol111/ecommerce-commons/pkg/kafka/kafkaproto"
 	"github.com/Sokol111/ecommerce-commons/pkg/kafka/outbox"
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
@@ -24,22 +29,47 @@ func NewOutboxModule() fx.Option {
 		),
 		fx.Provide(
 			provideConfig,
-			outbox.NewOutboxRepository,
-			outbox.NewFetcher,
+			provideRepository,
+			fx.Annotate(
+				provideFetcher,
+				fx.ParamTags(``, ``, ``, ``, ``, `optional:"true"`, ``, ``),
+			),
+			outbox.NewMetrics,
+			provideArchiveSink,
+			fx.Annotate(
+				provideArchiver,
+				fx.ParamTags(``, ``, ``, `optional:"true"`, ``),
+			),
+			outbox.NewChangeWatcher,
 			outbox.NewSender,
 			outbox.NewConfirmer,
+			outbox.NewDLQSender,
 			outbox.NewTracePropagator,
 			newHeaderPopulator,
 			provideEntitiesChannel,
 			provideConfirmChannel,
+			provideWakeupChannel,
 			fx.Private,
 		),
-		fx.Provide(outbox.NewOutbox),
+		fx.Provide(fx.Annotate(metricViews, fx.ResultTags(`group:"metric_views,flatten"`))),
+		fx.Provide(
+			outbox.NewOutbox,
+			outbox.NewAdmin,
+			outbox.NewReplayer,
+		),
+		// Workers stop in reverse order: the fetcher stops handing out work first, then the sender
+		// drains the producer, and the confirmer stores the remaining results last.
 		fx.Invoke(
-			worker.RunWorker[*outbox.Fetcher]("outbox-fetcher", worker.WithTrafficReady()),
-			worker.RunWorker[*outbox.Sender]("outbox-sender", worker.WithTrafficReady()),
 			worker.RunWorker[*outbox.Confirmer]("outbox-confirmer", worker.WithTrafficReady()),
+			worker.RunWorker[*outbox.Sender]("outbox-sender", worker.WithTrafficReady()),
+			worker.RunWorker[*outbox.ChangeWatcher]("outbox-change-watcher", worker.WithTrafficReady()),
+			worker.RunWorker[*outbox.Fetcher]("outbox-fetcher", worker.WithTrafficReady()),
+			worker.RunWorker[*outbox.Archiver]("outbox-archiver", worker.WithTrafficReady()),
 			ensureSchema,
+			fx.Annotate(
+				registerAdminRoutes,
+				fx.ParamTags(``, ``, `optional:"true"`, ``),
+			),
 		),
 	)
 }
@@ -52,12 +82,74 @@ func newHeaderPopulator(appCfg coreconfig.AppConfig) kafkaproto.HeaderPopulator
 	return kafkaproto.NewHeaderPopulator(appCfg.ServiceName)
 }
 
-func ensureSchema(lc fx.Lifecycle, log *zap.Logger, database *mongodriver.Database, readiness health.ComponentManager) {
+// provideRepository stores messages in the shared database or, in tenant-scoped mode,
+// in the tenant database resolved from context.
+func provideRepository(cfg outbox.Config, database *mongodriver.Database) outbox.Repository {
+	var collections mongo.CollectionProvider
+	if cfg.TenantScoped {
+		collections = tenant.NewMultiTenantCollectionProvider(database, "outbox")
+	} else {
+		collections = mongo.NewStaticCollectionProvider(database.Collection("outbox"))
+	}
+	return outbox.NewOutboxRepository(collections, cfg)
+}
+
+func provideFetcher(
+	cfg outbox.Config,
+	repository outbox.Repository,
+	entitiesChan chan *outbox.OutboxEntity,
+	wakeups chan time.Time,
+	dlqSender outbox.DLQSender,
+	tenants tenant.Repository,
+	metrics *outbox.Metrics,
+	log *zap.Logger,
+) (*outbox.Fetcher, error) {
+	if !cfg.TenantScoped {
+		tenants = nil
+	} else if tenants == nil {
+		return nil, errors.New("outbox tenant-scoped mode requires multi-tenancy to be enabled")
+	}
+	return outbox.NewFetcher(repository, entitiesChan, wakeups, dlqSender, tenants, metrics, cfg, log), nil
+}
+
+// provideArchiveSink returns the configured archive target; the archive collection
+// lives next to the outbox collection.
+func provideArchiveSink(cfg outbox.Config, database *mongodriver.Database) outbox.ArchiveSink {
+	if cfg.Retention.Archive.Target == outbox.ArchiveTargetJSONL {
+		return outbox.NewJSONLArchiveSink(cfg.Retention.Archive.Directory)
+	}
+	if cfg.TenantScoped {
+		return outbox.NewCollectionArchiveSink(tenant.NewMultiTenantCollectionProvider(database, outbox.ArchiveCollectionName))
+	}
+	return outbox.NewCollectionArchiveSink(mongo.NewStaticCollectionProvider(database.Collection(outbox.ArchiveCollectionName)))
+}
+
+func provideArchiver(
+	cfg outbox.Config,
+	repository outbox.Repository,
+	sink outbox.ArchiveSink,
+	tenants tenant.Repository,
+	log *zap.Logger,
+) (*outbox.Archiver, error) {
+	if !cfg.TenantScoped {
+		tenants = nil
+	} else if tenants == nil && cfg.Retention.Archive.Enabled {
+		return nil, errors.New("outbox tenant-scoped mode requires multi-tenancy to be enabled")
+	}
+	return outbox.NewArchiver(repository, sink, tenants, cfg, log), nil
+}
+
+func ensureSchema(lc fx.Lifecycle, log *zap.Logger, cfg outbox.Config, database *mongodriver.Database, readiness health.ComponentManager) {
 	markReady := readiness.AddComponent("outbox-schema")
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
+			if cfg.TenantScoped {
+				// Tenant databases get their indexes on first use.
+				markReady()
+				return nil
+			}
 			log.Info("ensuring outbox indexes")
-			if err := outbox.EnsureIndexes(ctx, database); err != nil {
+			if err := outbox.EnsureIndexes(ctx, database, cfg.Retention); err != nil {
 				return err
 			}
 			log.Info("outbox indexes ready")
@@ -67,6 +159,19 @@ func ensureSchema(lc fx.Lifecycle, log *zap.Logger, database *mongodriver.Databa
 	})
 }
 
+// registerAdminRoutes exposes the outbox admin API when enabled and an HTTP server is present.
+func registerAdminRoutes(cfg outbox.Config, admin outbox.Admin, mux *http.ServeMux, log *zap.Logger) {
+	if !cfg.Admin.Enabled {
+		return
+	}
+	if mux == nil {
+		log.Warn("outbox admin API is enabled but no HTTP server is configured")
+		return
+	}
+	outbox.RegisterAdminRoutes(mux, cfg.Admin.PathPrefix, outbox.NewAdminHandler(admin, log))
+	log.Info("outbox admin API registered", zap.String("path_prefix", cfg.Admin.PathPrefix))
+}
+
 func provideEntitiesChannel() chan *outbox.OutboxEntity {
 	return make(chan *outbox.OutboxEntity, 100)
 }
@@ -74,3 +179,7 @@ func provideEntitiesChannel() chan *outbox.OutboxEntity {
 func provideConfirmChannel() chan outbox.ConfirmResult {
 	return make(chan outbox.ConfirmResult, 1000)
 }
+
+func provideWakeupChannel() chan time.Time {
+	return make(chan time.Time, 100)
+}
@@ -0,0 +1,40 @@
+package fxconfig
+
+import (
+	"github.com/Sokol111/ecommerce-commons/pkg/kafka/outbox"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// metricViews returns SDK views that keep outbox metrics low-cardinality.
+//
+// Outbox instruments are recorded with the topic attribute only; the views drop anything
+// else that may be attached and give the delivery histogram buckets that cover both
+// immediate delivery and messages stuck in retry backoff for hours.
+func metricViews() []sdkmetric.View {
+	topicOnly := attribute.NewAllowKeysFilter(attribute.Key(outbox.MetricAttributeTopic))
+
+	views := []sdkmetric.View{
+		sdkmetric.NewView(
+			sdkmetric.Instrument{Name: outbox.MetricDeliveryDuration},
+			sdkmetric.Stream{
+				AttributeFilter: topicOnly,
+				Aggregation: sdkmetric.AggregationExplicitBucketHistogram{
+					Boundaries: []float64{0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600},
+				},
+			},
+		),
+	}
+	for _, name := range []string{
+		outbox.MetricPendingMessages,
+		outbox.MetricPendingOldestAge,
+		outbox.MetricProduceFailures,
+		outbox.MetricConfirmFailures,
+	} {
+		views = append(views, sdkmetric.NewView(
+			sdkmetric.Instrument{Name: name},
+			sdkmetric.Stream{AttributeFilter: topicOnly},
+		))
+	}
+	return views
+}
@@ -0,0 +1,130 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metric instrument names.
+const (
+	MetricPendingMessages  = "outbox.pending.messages"
+	MetricPendingOldestAge = "outbox.pending.oldest_age"
+	MetricDeliveryDuration = "outbox.delivery.duration"
+	MetricProduceFailures  = "outbox.produce.failures"
+	MetricConfirmFailures  = "outbox.confirm.failures"
+
+	// MetricAttributeTopic is the only attribute recorded on outbox metrics.
+	MetricAttributeTopic = "topic"
+)
+
+// TopicBacklog summarizes the undelivered messages of a topic.
+type TopicBacklog struct {
+	Topic           string    `bson:"_id"`
+	Count           int64     `bson:"count"`
+	OldestCreatedAt time.Time `bson:"oldest"`
+}
+
+// Metrics records outbox delivery metrics. All instruments carry only the topic attribute.
+type Metrics struct {
+	deliveryDuration metric.Float64Histogram
+	produceFailures  metric.Int64Counter
+	confirmFailures  metric.Int64Counter
+
+	mu sync.Mutex
+	// backlog is the latest snapshot per topic; topics that drained are kept with a zero count
+	// so their gauges drop to zero instead of disappearing.
+	backlog map[string]TopicBacklog
+}
+
+// NewMetrics creates the outbox instruments on the "outbox" meter.
+func NewMetrics(meterProvider metric.MeterProvider) (*Metrics, error) {
+	meter := meterProvider.Meter("outbox")
+	m := &Metrics{backlog: make(map[string]TopicBacklog)}
+
+	var err error
+	if m.deliveryDuration, err = meter.Float64Histogram(MetricDeliveryDuration,
+		metric.WithDescription("Time from outbox message creation to confirmed delivery to Kafka."),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to create %s histogram: %w", MetricDeliveryDuration, err)
+	}
+	if m.produceFailures, err = meter.Int64Counter(MetricProduceFailures,
+		metric.WithDescription("Number of failed Kafka produce attempts of outbox messages."),
+		metric.WithUnit("{message}"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to create %s counter: %w", MetricProduceFailures, err)
+	}
+	if m.confirmFailures, err = meter.Int64Counter(MetricConfirmFailures,
+		metric.WithDescription("Number of outbox messages whose delivery status could not be stored."),
+		metric.WithUnit("{message}"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to create %s counter: %w", MetricConfirmFailures, err)
+	}
+
+	pending, err := meter.Int64ObservableGauge(MetricPendingMessages,
+		metric.WithDescription("Number of outbox messages waiting for delivery."),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s gauge: %w", MetricPendingMessages, err)
+	}
+	oldestAge, err := meter.Float64ObservableGauge(MetricPendingOldestAge,
+		metric.WithDescription("Age of the oldest outbox message waiting for delivery."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s gauge: %w", MetricPendingOldestAge, err)
+	}
+
+	if _, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for topic, b := range m.backlog {
+			attrs := metric.WithAttributes(attribute.String(MetricAttributeTopic, topic))
+			o.ObserveInt64(pending, b.Count, attrs)
+			age := 0.0
+			if b.Count > 0 {
+				age = time.Since(b.OldestCreatedAt).Seconds()
+			}
+			o.ObserveFloat64(oldestAge, age, attrs)
+		}
+		return nil
+	}, pending, oldestAge); err != nil {
+		return nil, fmt.Errorf("failed to register outbox backlog callback: %w", err)
+	}
+
+	return m, nil
+}
+
+func (m *Metrics) recordDelivered(ctx context.Context, topic string, createdAt time.Time) {
+	if createdAt.IsZero() {
+		return
+	}
+	m.deliveryDuration.Record(ctx, time.Since(createdAt).Seconds(),
+		metric.WithAttributes(attribute.String(MetricAttributeTopic, topic)))
+}
+
+func (m *Metrics) recordProduceFailure(ctx context.Context, topic string) {
+	m.produceFailures.Add(ctx, 1, metric.WithAttributes(attribute.String(MetricAttributeTopic, topic)))
+}
+
+func (m *Metrics) recordConfirmFailures(ctx context.Context, topic string, count int) {
+	m.confirmFailures.Add(ctx, int64(count), metric.WithAttributes(attribute.String(MetricAttributeTopic, topic)))
+}
+
+// recordBacklog replaces the backlog snapshot.
+func (m *Metrics) recordBacklog(backlog []TopicBacklog) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for topic := range m.backlog {
+		m.backlog[topic] = TopicBacklog{Topic: topic}
+	}
+	for _, b := range backlog {
+		m.backlog[b.Topic] = b
+	}
+}
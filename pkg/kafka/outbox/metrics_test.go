@@ -0,0 +1,163 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+)
+
+func newTestMetrics(tb testing.TB) *Metrics {
+	tb.Helper()
+
+	m, err := NewMetrics(noop.NewMeterProvider())
+	require.NoError(tb, err)
+	return m
+}
+
+// newRecordingMetrics returns Metrics backed by a manual reader for inspecting recorded values.
+func newRecordingMetrics(t *testing.T) (*Metrics, *sdkmetric.ManualReader) {
+	t.Helper()
+
+	reader := sdkmetric.NewManualReader()
+	m, err := NewMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	require.NoError(t, err)
+	return m, reader
+}
+
+func collectMetric(t *testing.T, reader *sdkmetric.ManualReader, name string) metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	t.Fatalf("metric %s not recorded", name)
+	return nil
+}
+
+func topicOf(attrs attribute.Set) string {
+	v, _ := attrs.Value(MetricAttributeTopic)
+	return v.AsString()
+}
+
+func TestMetrics_Confirmer(t *testing.T) {
+	t.Run("records delivery duration per topic", func(t *testing.T) {
+		m, reader := newRecordingMetrics(t)
+		c := NewConfirmer(newMockRepository(), make(chan ConfirmResult), m, Config{DrainTimeout: time.Second}, zap.NewNop())
+
+		c.confirm(context.Background(), []ConfirmResult{
+			{ID: "a", Topic: "orders", CreatedAt: time.Now().Add(-2 * time.Second)},
+			{ID: "b", Topic: "orders", CreatedAt: time.Now().Add(-time.Second)},
+		})
+
+		hist, ok := collectMetric(t, reader, MetricDeliveryDuration).(metricdata.Histogram[float64])
+		require.True(t, ok)
+		require.Len(t, hist.DataPoints, 1)
+		assert.Equal(t, "orders", topicOf(hist.DataPoints[0].Attributes))
+		assert.Equal(t, uint64(2), hist.DataPoints[0].Count)
+		assert.GreaterOrEqual(t, hist.DataPoints[0].Sum, 3.0)
+	})
+
+	t.Run("counts confirm update failures", func(t *testing.T) {
+		m, reader := newRecordingMetrics(t)
+		repo := newMockRepository()
+		repo.updateAsSentErr = errors.New("database error")
+		c := NewConfirmer(repo, make(chan ConfirmResult), m, Config{DrainTimeout: time.Second}, zap.NewNop())
+
+		c.confirm(context.Background(), []ConfirmResult{
+			{ID: "a", Topic: "orders"},
+			{ID: "b", Topic: "orders"},
+		})
+
+		sum, ok := collectMetric(t, reader, MetricConfirmFailures).(metricdata.Sum[int64])
+		require.True(t, ok)
+		require.Len(t, sum.DataPoints, 1)
+		assert.Equal(t, int64(2), sum.DataPoints[0].Value)
+	})
+}
+
+func TestMetrics_Sender(t *testing.T) {
+	m, reader := newRecordingMetrics(t)
+	producer := &mockProducer{
+		produceFunc: func(_ context.Context, record *kgo.Record, promise func(*kgo.Record, error)) {
+			promise(record, errors.New("broker unavailable"))
+		},
+	}
+	confirmChan := make(chan ConfirmResult, 1)
+	s := NewSender(producer, make(chan *OutboxEntity), confirmChan, zap.NewNop(), &mockSenderTracePropagator{}, m, Config{DrainTimeout: time.Second})
+
+	s.send(context.Background(), &OutboxEntity{ID: "a", Topic: "orders", CreatedAt: time.Now()})
+
+	result := <-confirmChan
+	assert.Equal(t, "orders", result.Topic)
+	assert.False(t, result.CreatedAt.IsZero())
+
+	sum, ok := collectMetric(t, reader, MetricProduceFailures).(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, "orders", topicOf(sum.DataPoints[0].Attributes))
+	assert.Equal(t, int64(1), sum.DataPoints[0].Value)
+}
+
+func TestMetrics_Backlog(t *testing.T) {
+	m, reader := newRecordingMetrics(t)
+	repo := newMockRepository()
+	repo.backlog = []TopicBacklog{
+		{Topic: "orders", Count: 3, OldestCreatedAt: time.Now().Add(-time.Minute)},
+		{Topic: "payments", Count: 1, OldestCreatedAt: time.Now()},
+	}
+	f := NewFetcher(repo, nil, nil, &mockDLQSender{}, nil, m, testFetcherConfig(), zap.NewNop())
+
+	pendingByTopic := func() map[string]int64 {
+		gauge, ok := collectMetric(t, reader, MetricPendingMessages).(metricdata.Gauge[int64])
+		require.True(t, ok)
+		byTopic := make(map[string]int64)
+		for _, dp := range gauge.DataPoints {
+			byTopic[topicOf(dp.Attributes)] = dp.Value
+		}
+		return byTopic
+	}
+
+	t.Run("sums backlog across tenants", func(t *testing.T) {
+		f.refreshBacklog(context.Background(), []string{"acme", "globex"})
+
+		assert.Equal(t, map[string]int64{"orders": 6, "payments": 2}, pendingByTopic())
+
+		age, ok := collectMetric(t, reader, MetricPendingOldestAge).(metricdata.Gauge[float64])
+		require.True(t, ok)
+		for _, dp := range age.DataPoints {
+			if topicOf(dp.Attributes) == "orders" {
+				assert.GreaterOrEqual(t, dp.Value, 60.0)
+			}
+		}
+	})
+
+	t.Run("is refreshed at most once per interval", func(t *testing.T) {
+		repo.backlog = nil
+		f.refreshBacklog(context.Background(), []string{""})
+
+		assert.Equal(t, int64(6), pendingByTopic()["orders"])
+	})
+
+	t.Run("drained topics drop to zero", func(t *testing.T) {
+		f.backlogUpdatedAt = time.Time{}
+		f.refreshBacklog(context.Background(), []string{""})
+
+		assert.Equal(t, map[string]int64{"orders": 0, "payments": 0}, pendingByTopic())
+	})
+}
@@ -2,12 +2,17 @@ package outbox
 
 import (
 	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
 )
 
 // Outbox message status constants.
 const (
 	StatusProcessing = "PROCESSING"
 	StatusSent       = "SENT"
+	// StatusFailed is a terminal status for messages that exceeded Config.MaxAttempts.
+	// Failed messages are kept for RetentionConfig.Failed, indefinitely by default, so they can be inspected.
+	StatusFailed = "FAILED"
 )
 
 type OutboxEntity struct {
@@ -19,7 +24,15 @@ type OutboxEntity struct {
 	Status           string            `bson:"status"`
 	CreatedAt        time.Time         `bson:"createdAt"`
 	SentAt           time.Time         `bson:"sentAt,omitempty"`
+	FailedAt         time.Time         `bson:"failedAt,omitempty"`
+	LastError        string            `bson:"lastError,omitempty"`
 	LockExpiresAt    time.Time         `bson:"lockExpiresAt,omitempty"`
+	LockOwner        string            `bson:"lockOwner,omitempty"`
 	NextAttemptAfter time.Time         `bson:"nextAttemptAfter"`
 	AttemptsToSend   int32             `bson:"attemptsToSend"`
 }
+
+// tenantSlug returns the tenant the entity was created for, empty for shared outbox messages.
+func (e *OutboxEntity) tenantSlug() string {
+	return e.Headers[tenant.HeaderKey]
+}
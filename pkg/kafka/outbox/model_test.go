@@ -50,5 +50,6 @@ func TestOutboxStatus(t *testing.T) {
 	t.Run("status constants are defined correctly", func(t *testing.T) {
 		assert.Equal(t, "PROCESSING", StatusProcessing)
 		assert.Equal(t, "SENT", StatusSent)
+		assert.Equal(t, "FAILED", StatusFailed)
 	})
 }
@@ -2,6 +2,7 @@ package outbox
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,12 +13,40 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// ErrInvalidMessage is returned by Outbox.Create when a message sets neither or both
+// of Event and Payload, a Payload without ContentType, or both DeliverAt and Delay.
+var ErrInvalidMessage = errors.New("invalid outbox message")
+
+// ErrDuplicateIdempotencyKey is returned by Outbox.Create when a concurrent Create inserted a
+// message with the same IdempotencyKey first. The duplicate key error aborts the transaction of
+// the caller, which can retry it; the retry finds the existing message.
+var ErrDuplicateIdempotencyKey = errors.New("outbox message with the idempotency key already exists")
+
 // Message represents a message to be sent via the outbox pattern.
+// Exactly one of Event and Payload must be set.
 type Message struct {
-	Event   proto.Message     // Proto event payload
-	Topic   string            // Kafka topic to publish to
-	Key     string            // Kafka partition key for ordering guarantees
-	Headers map[string]string // Kafka headers for trace propagation, etc.
+	Event       proto.Message     // Proto event, encoded with the codec configured for Topic
+	Payload     []byte            // Pre-encoded payload, sent as is
+	ContentType string            // Content type of Payload, written to the content-type header
+	EventType   string            // Optional event_type header of Payload
+	Topic       string            // Kafka topic to publish to
+	Key         string            // Kafka partition key for ordering guarantees
+	Headers     map[string]string // Kafka headers for trace propagation, etc.
+
+	// IdempotencyKey identifies the logical event across retries of the calling request.
+	// It becomes the outbox id and the event_id header instead of a random UUID.
+	// Creating a message with a key that already exists stores nothing. Its SendFunc locks the
+	// existing message like the fetcher does and hands it off if it is due; a message that is
+	// locked, scheduled or already sent is left to the fetcher and the SendFunc does nothing.
+	// Losing a race with a concurrent Create of the key fails with ErrDuplicateIdempotencyKey.
+	IdempotencyKey string
+
+	// DeliverAt schedules delivery for a future time. Scheduled messages skip the
+	// hand-off after commit and are delivered by the fetcher once due.
+	// A zero or past DeliverAt delivers right away.
+	DeliverAt time.Time
+	// Delay schedules delivery relative to Create, as an alternative to DeliverAt.
+	Delay time.Duration
 }
 
 // Outbox defines the interface for creating outbox messages.
@@ -29,19 +58,25 @@ type outbox struct {
 	outboxRepository Repository
 	logger           *zap.Logger
 	entitiesChan     chan<- *OutboxEntity
-	serializer       kafkaproto.Serializer
+	codecs           *codecRegistry
 	tracePropagator  TracePropagator
 	headerPopulator  kafkaproto.HeaderPopulator
+	strictOrdering   bool
+	requireTx        bool
 }
 
-func NewOutbox(logger *zap.Logger, outboxRepository Repository, entitiesChan chan *OutboxEntity, serializer kafkaproto.Serializer, tracePropagator TracePropagator, headerPopulator kafkaproto.HeaderPopulator) Outbox {
+// NewOutbox creates an Outbox. Events are encoded with the codec configured for their topic
+// (see Config.Codec); the proto codecs use serializer.
+func NewOutbox(logger *zap.Logger, outboxRepository Repository, entitiesChan chan *OutboxEntity, serializer kafkaproto.Serializer, tracePropagator TracePropagator, headerPopulator kafkaproto.HeaderPopulator, config Config) Outbox {
 	return &outbox{
 		outboxRepository: outboxRepository,
 		logger:           logger,
 		entitiesChan:     entitiesChan,
-		serializer:       serializer,
+		codecs:           newCodecRegistry(config, serializer),
 		tracePropagator:  tracePropagator,
 		headerPopulator:  headerPopulator,
+		strictOrdering:   config.StrictOrdering,
+		requireTx:        config.RequireTransaction,
 	}
 }
 
@@ -49,12 +84,44 @@ func NewOutbox(logger *zap.Logger, outboxRepository Repository, entitiesChan cha
 type SendFunc func(ctx context.Context) error
 
 func (o *outbox) Create(ctx context.Context, msg Message) (SendFunc, error) {
+	if o.requireTx && !inTransaction(ctx) {
+		return nil, ErrNoTransaction
+	}
+
+	if (msg.Event == nil) == (msg.Payload == nil) {
+		return nil, fmt.Errorf("%w: exactly one of Event and Payload must be set", ErrInvalidMessage)
+	}
+	if msg.Payload != nil && msg.ContentType == "" {
+		return nil, fmt.Errorf("%w: Payload requires ContentType", ErrInvalidMessage)
+	}
+	if !msg.DeliverAt.IsZero() && msg.Delay != 0 {
+		return nil, fmt.Errorf("%w: DeliverAt and Delay are mutually exclusive", ErrInvalidMessage)
+	}
+	deliverAt := msg.deliverAt(time.Now())
+
 	if msg.Headers == nil {
 		msg.Headers = make(map[string]string)
 	}
 
-	// Populate event metadata into headers (event_id, event_type, source, timestamp, trace_id)
-	eventID := o.headerPopulator.PopulateHeaders(msg.Event, msg.Headers)
+	// Populate event metadata into headers (event_id, event_type, source, timestamp)
+	var eventID string
+	if msg.Event != nil {
+		eventID = o.headerPopulator.PopulateHeaders(msg.Event, msg.Headers)
+	} else {
+		eventID = o.headerPopulator.PopulateMetadata(msg.EventType, msg.Headers)
+	}
+	if msg.IdempotencyKey != "" {
+		// Look up first: inside a transaction a duplicate key error aborts the whole transaction.
+		existing, err := o.outboxRepository.FindByID(ctx, msg.IdempotencyKey)
+		if err == nil {
+			return o.duplicateSendFunc(ctx, existing), nil
+		}
+		if !errors.Is(err, errEntityNotFound) {
+			return nil, fmt.Errorf("failed to check outbox idempotency key: %w", err)
+		}
+		eventID = msg.IdempotencyKey
+		msg.Headers["event_id"] = eventID
+	}
 
 	// Save trace context into headers for storage in outbox
 	msg.Headers = o.tracePropagator.SaveTraceContext(ctx, msg.Headers)
@@ -62,24 +129,84 @@ func (o *outbox) Create(ctx context.Context, msg Message) (SendFunc, error) {
 	// Save tenant context into headers for cross-service propagation
 	msg.Headers = tenant.SaveToHeaders(ctx, msg.Headers)
 
-	// Serialize the event
-	serializedMsg, err := o.serializer.Serialize(msg.Event)
+	payload, err := o.encode(msg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to serialize outbox message: %w", err)
 	}
 
-	entity, err := o.outboxRepository.Create(ctx, serializedMsg, eventID, msg.Key, msg.Topic, msg.Headers)
+	entity, err := o.outboxRepository.Create(ctx, payload, eventID, msg.Key, msg.Topic, msg.Headers, deliverAt)
 	if err != nil {
+		if msg.IdempotencyKey != "" && errors.Is(err, errDuplicateEntity) {
+			// Lost a race with a concurrent Create for the same key. Inside a transaction the
+			// duplicate key error aborted it, so the existing message can't be read here.
+			return nil, fmt.Errorf("failed to create outbox message: %w: %w", ErrDuplicateIdempotencyKey, err)
+		}
 		return nil, fmt.Errorf("failed to create outbox message: %w", err)
 	}
 
+	if !deliverAt.IsZero() {
+		o.log(ctx).Debug("outbox scheduled", zap.String("id", entity.ID), zap.Time("deliver_at", deliverAt))
+		return func(context.Context) error { return nil }, nil
+	}
+
 	o.log(ctx).Debug("outbox created", zap.String("id", entity.ID))
 
 	return o.createSendFunc(entity), nil
 }
 
+// duplicateSendFunc returns the SendFunc for a message created earlier with the same idempotency key.
+// It hands the message off only under a lock taken like the fetcher takes it, so the two never
+// deliver it both.
+func (o *outbox) duplicateSendFunc(ctx context.Context, existing *OutboxEntity) SendFunc {
+	o.log(ctx).Debug("outbox message already exists for idempotency key", zap.String("id", existing.ID))
+	return func(ctx context.Context) error {
+		entity, err := o.outboxRepository.LockByID(ctx, existing.ID)
+		if errors.Is(err, errEntityNotFound) {
+			o.log(ctx).Debug("existing outbox message left to fetcher", zap.String("id", existing.ID))
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("outbox didn't sent: %w", err)
+		}
+		return o.createSendFunc(entity)(ctx)
+	}
+}
+
+// deliverAt returns the scheduled delivery time, zero for immediate delivery.
+func (m Message) deliverAt(now time.Time) time.Time {
+	deliverAt := m.DeliverAt
+	if m.Delay > 0 {
+		deliverAt = now.Add(m.Delay)
+	}
+	if !deliverAt.After(now) {
+		return time.Time{}
+	}
+	return deliverAt
+}
+
+// encode returns the Kafka payload of msg and sets its content-type header.
+func (o *outbox) encode(msg Message) ([]byte, error) {
+	if msg.Payload != nil {
+		msg.Headers[HeaderContentType] = msg.ContentType
+		return msg.Payload, nil
+	}
+	return o.codecs.forTopic(msg.Topic).Encode(msg.Event, msg.Headers)
+}
+
 func (o *outbox) createSendFunc(entity *OutboxEntity) SendFunc {
 	return func(ctx context.Context) error {
+		if o.strictOrdering {
+			blocked, err := o.outboxRepository.HasUnsentPredecessor(ctx, entity)
+			if err != nil {
+				return fmt.Errorf("outbox didn't sent: %w", err)
+			}
+			if blocked {
+				// An earlier message with the same key is pending; the fetcher delivers this one after it.
+				o.log(ctx).Debug("outbox message deferred to fetcher, predecessor pending", zap.String("id", entity.ID))
+				return nil
+			}
+		}
+
 		timer := time.NewTimer(1 * time.Second)
 		defer timer.Stop()
 		select {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/Sokol111/ecommerce-commons/pkg/core/logger"
 	"github.com/Sokol111/ecommerce-commons/pkg/kafka/kafkaproto"
@@ -31,6 +32,15 @@ func (m *mockHeaderPopulator) PopulateHeaders(event proto.Message, headers map[s
 	return "generated-event-id"
 }
 
+func (m *mockHeaderPopulator) PopulateMetadata(eventType string, headers map[string]string) string {
+	headers["event_id"] = "generated-event-id"
+	if eventType != "" {
+		headers["event_type"] = eventType
+	}
+	headers["source"] = "test-service"
+	return "generated-event-id"
+}
+
 // mockSerializer is a mock implementation of serde.Serializer
 type mockSerializer struct {
 	serializeFunc func(event proto.Message) ([]byte, error)
@@ -88,7 +98,7 @@ func TestOutbox_Create(t *testing.T) {
 		headerPopulator := &mockHeaderPopulator{}
 		log := zap.NewNop()
 
-		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator)
+		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator, Config{})
 
 		msg := newTestMessage()
 
@@ -115,7 +125,7 @@ func TestOutbox_Create(t *testing.T) {
 		headerPopulator := &mockHeaderPopulator{}
 		log := zap.NewNop()
 
-		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator)
+		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator, Config{})
 
 		msg := Message{
 			Event: &emptypb.Empty{},
@@ -139,7 +149,7 @@ func TestOutbox_Create(t *testing.T) {
 		headerPopulator := &mockHeaderPopulator{}
 		log := zap.NewNop()
 
-		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator)
+		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator, Config{})
 
 		msg := Message{
 			Event: &emptypb.Empty{},
@@ -170,7 +180,7 @@ func TestOutbox_Create(t *testing.T) {
 		headerPopulator := &mockHeaderPopulator{}
 		log := zap.NewNop()
 
-		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator)
+		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator, Config{})
 
 		msg := Message{
 			Event:   &emptypb.Empty{},
@@ -197,7 +207,7 @@ func TestOutbox_SendFunc(t *testing.T) {
 		headerPopulator := &mockHeaderPopulator{}
 		log := zap.NewNop()
 
-		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator)
+		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator, Config{})
 
 		msg := Message{
 			Event: &emptypb.Empty{},
@@ -227,7 +237,7 @@ func TestOutbox_SendFunc(t *testing.T) {
 		headerPopulator := &mockHeaderPopulator{}
 		log := zap.NewNop()
 
-		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator)
+		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator, Config{})
 
 		msg := Message{
 			Event: &emptypb.Empty{},
@@ -254,7 +264,7 @@ func TestOutbox_SendFunc(t *testing.T) {
 		headerPopulator := &mockHeaderPopulator{}
 		log := zap.NewNop()
 
-		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator)
+		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator, Config{})
 
 		msg := Message{
 			Event: &emptypb.Empty{},
@@ -272,6 +282,54 @@ func TestOutbox_SendFunc(t *testing.T) {
 	})
 }
 
+func TestOutbox_StrictOrdering(t *testing.T) {
+	newStrictOutbox := func(repo *mockRepository, entitiesChan chan *OutboxEntity) Outbox {
+		return NewOutbox(zap.NewNop(), repo, entitiesChan, &mockSerializer{}, &mockTracePropagator{}, &mockHeaderPopulator{}, Config{StrictOrdering: true})
+	}
+
+	t.Run("hands off message when no predecessor is pending", func(t *testing.T) {
+		repo := newMockRepository()
+		entitiesChan := make(chan *OutboxEntity, 1)
+		o := newStrictOutbox(repo, entitiesChan)
+
+		ctx := logger.With(context.Background(), zap.NewNop())
+		sendFunc, err := o.Create(ctx, newTestMessage())
+		require.NoError(t, err)
+
+		require.NoError(t, sendFunc(ctx))
+		assert.Len(t, entitiesChan, 1)
+	})
+
+	t.Run("defers message to fetcher when predecessor is pending", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.hasPredecessor = true
+		entitiesChan := make(chan *OutboxEntity, 1)
+		o := newStrictOutbox(repo, entitiesChan)
+
+		ctx := logger.With(context.Background(), zap.NewNop())
+		sendFunc, err := o.Create(ctx, newTestMessage())
+		require.NoError(t, err)
+
+		require.NoError(t, sendFunc(ctx))
+		assert.Empty(t, entitiesChan)
+	})
+
+	t.Run("returns error when predecessor check fails", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.hasPredecessorErr = errors.New("mongo unavailable")
+		entitiesChan := make(chan *OutboxEntity, 1)
+		o := newStrictOutbox(repo, entitiesChan)
+
+		ctx := logger.With(context.Background(), zap.NewNop())
+		sendFunc, err := o.Create(ctx, newTestMessage())
+		require.NoError(t, err)
+
+		err = sendFunc(ctx)
+		assert.ErrorContains(t, err, "mongo unavailable")
+		assert.Empty(t, entitiesChan)
+	})
+}
+
 func TestOutbox_NilHeaders(t *testing.T) {
 	t.Run("handles nil headers gracefully", func(t *testing.T) {
 		repo := newMockRepository()
@@ -281,7 +339,7 @@ func TestOutbox_NilHeaders(t *testing.T) {
 		headerPopulator := &mockHeaderPopulator{}
 		log := zap.NewNop()
 
-		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator)
+		o := NewOutbox(log, repo, entitiesChan, serializer, propagator, headerPopulator, Config{})
 
 		msg := Message{
 			Event:   &emptypb.Empty{},
@@ -299,3 +357,231 @@ func TestOutbox_NilHeaders(t *testing.T) {
 }
 
 var _ kafkaproto.HeaderPopulator = (*mockHeaderPopulator)(nil)
+
+func TestOutbox_Codecs(t *testing.T) {
+	newCodecOutbox := func(repo *mockRepository, cfg Config) Outbox {
+		return NewOutbox(zap.NewNop(), repo, make(chan *OutboxEntity, 10), &mockSerializer{}, &mockTracePropagator{}, &mockHeaderPopulator{}, cfg)
+	}
+
+	t.Run("uses the default proto codec", func(t *testing.T) {
+		repo := newMockRepository()
+		o := newCodecOutbox(repo, Config{})
+
+		_, err := o.Create(context.Background(), newTestMessage())
+
+		require.NoError(t, err)
+		assert.Equal(t, []byte("serialized"), repo.created[0].Payload)
+		assert.Equal(t, ContentTypeProtobuf, repo.created[0].Headers[HeaderContentType])
+	})
+
+	t.Run("uses the codec configured for the topic", func(t *testing.T) {
+		repo := newMockRepository()
+		o := newCodecOutbox(repo, Config{
+			Codec:       CodecProto,
+			TopicCodecs: []TopicCodecConfig{{Topic: "partner.events", Codec: CodecProtoJSON}},
+		})
+
+		_, err := o.Create(context.Background(), Message{Event: &emptypb.Empty{}, Topic: "partner.events"})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{}`, string(repo.created[0].Payload))
+		assert.Equal(t, ContentTypeJSON, repo.created[0].Headers[HeaderContentType])
+	})
+
+	t.Run("stores pre-encoded payload as is", func(t *testing.T) {
+		repo := newMockRepository()
+		o := newCodecOutbox(repo, Config{})
+
+		_, err := o.Create(context.Background(), Message{
+			Payload:     []byte(`{"id":"1"}`),
+			ContentType: ContentTypeJSON,
+			EventType:   "com.example.order.created",
+			Topic:       "partner.events",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []byte(`{"id":"1"}`), repo.created[0].Payload)
+		assert.Equal(t, ContentTypeJSON, repo.created[0].Headers[HeaderContentType])
+		assert.Equal(t, "com.example.order.created", repo.created[0].Headers[HeaderEventType])
+		assert.Equal(t, "generated-event-id", repo.created[0].ID)
+	})
+
+	t.Run("rejects invalid messages", func(t *testing.T) {
+		o := newCodecOutbox(newMockRepository(), Config{})
+
+		for name, msg := range map[string]Message{
+			"neither event nor payload": {Topic: "t"},
+			"both event and payload":    {Event: &emptypb.Empty{}, Payload: []byte("x"), ContentType: ContentTypeJSON, Topic: "t"},
+			"payload without type":      {Payload: []byte("x"), Topic: "t"},
+		} {
+			_, err := o.Create(context.Background(), msg)
+			assert.ErrorIs(t, err, ErrInvalidMessage, name)
+		}
+	})
+}
+
+func TestOutbox_ScheduledDelivery(t *testing.T) {
+	newScheduledOutbox := func(repo *mockRepository, entitiesChan chan *OutboxEntity) Outbox {
+		return NewOutbox(zap.NewNop(), repo, entitiesChan, &mockSerializer{}, &mockTracePropagator{}, &mockHeaderPopulator{}, Config{})
+	}
+
+	t.Run("DeliverAt sets the first attempt and skips the hand-off", func(t *testing.T) {
+		repo := newMockRepository()
+		entitiesChan := make(chan *OutboxEntity, 1)
+		o := newScheduledOutbox(repo, entitiesChan)
+
+		deliverAt := time.Now().Add(30 * time.Minute)
+		msg := newTestMessage()
+		msg.DeliverAt = deliverAt
+
+		sendFunc, err := o.Create(context.Background(), msg)
+		require.NoError(t, err)
+		require.NoError(t, sendFunc(context.Background()))
+
+		assert.Empty(t, entitiesChan)
+		assert.True(t, repo.created[0].NextAttemptAfter.Equal(deliverAt))
+	})
+
+	t.Run("Delay is relative to Create", func(t *testing.T) {
+		repo := newMockRepository()
+		entitiesChan := make(chan *OutboxEntity, 1)
+		o := newScheduledOutbox(repo, entitiesChan)
+
+		msg := newTestMessage()
+		msg.Delay = time.Hour
+
+		sendFunc, err := o.Create(context.Background(), msg)
+		require.NoError(t, err)
+		require.NoError(t, sendFunc(context.Background()))
+
+		assert.Empty(t, entitiesChan)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), repo.created[0].NextAttemptAfter, time.Second)
+	})
+
+	t.Run("past DeliverAt is delivered right away", func(t *testing.T) {
+		repo := newMockRepository()
+		entitiesChan := make(chan *OutboxEntity, 1)
+		o := newScheduledOutbox(repo, entitiesChan)
+
+		msg := newTestMessage()
+		msg.DeliverAt = time.Now().Add(-time.Minute)
+
+		sendFunc, err := o.Create(context.Background(), msg)
+		require.NoError(t, err)
+		require.NoError(t, sendFunc(context.Background()))
+
+		assert.Len(t, entitiesChan, 1)
+	})
+
+	t.Run("DeliverAt and Delay are mutually exclusive", func(t *testing.T) {
+		o := newScheduledOutbox(newMockRepository(), make(chan *OutboxEntity, 1))
+
+		msg := newTestMessage()
+		msg.DeliverAt = time.Now().Add(time.Hour)
+		msg.Delay = time.Hour
+
+		_, err := o.Create(context.Background(), msg)
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+	})
+}
+
+func TestOutbox_IdempotencyKey(t *testing.T) {
+	newIdempotentOutbox := func(repo *mockRepository, entitiesChan chan *OutboxEntity) Outbox {
+		return NewOutbox(zap.NewNop(), repo, entitiesChan, &mockSerializer{}, &mockTracePropagator{}, &mockHeaderPopulator{}, Config{})
+	}
+
+	t.Run("key becomes id and event_id", func(t *testing.T) {
+		repo := newMockRepository()
+		o := newIdempotentOutbox(repo, make(chan *OutboxEntity, 1))
+
+		msg := newTestMessage()
+		msg.IdempotencyKey = "order-1-created"
+
+		_, err := o.Create(context.Background(), msg)
+		require.NoError(t, err)
+
+		require.Len(t, repo.created, 1)
+		assert.Equal(t, "order-1-created", repo.created[0].ID)
+		assert.Equal(t, "order-1-created", repo.created[0].Headers["event_id"])
+	})
+
+	t.Run("retry leaves a message still locked by its creator to the fetcher", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.byID = map[string]*OutboxEntity{}
+		entitiesChan := make(chan *OutboxEntity, 2)
+		o := newIdempotentOutbox(repo, entitiesChan)
+
+		msg := newTestMessage()
+		msg.IdempotencyKey = "order-1-created"
+
+		_, err := o.Create(context.Background(), msg)
+		require.NoError(t, err)
+
+		retry := newTestMessage()
+		retry.IdempotencyKey = "order-1-created"
+		sendFunc, err := o.Create(context.Background(), retry)
+		require.NoError(t, err)
+		require.NoError(t, sendFunc(context.Background()))
+
+		assert.Len(t, repo.created, 1)
+		assert.Empty(t, entitiesChan)
+	})
+
+	t.Run("retry hands off an existing due message under a new lock", func(t *testing.T) {
+		due := time.Now().Add(-time.Minute)
+		existing := &OutboxEntity{ID: "order-1-created", Status: StatusProcessing, LockExpiresAt: due, NextAttemptAfter: due}
+		repo := newMockRepository()
+		repo.byID = map[string]*OutboxEntity{existing.ID: existing}
+		entitiesChan := make(chan *OutboxEntity, 1)
+		o := newIdempotentOutbox(repo, entitiesChan)
+
+		msg := newTestMessage()
+		msg.IdempotencyKey = "order-1-created"
+		sendFunc, err := o.Create(context.Background(), msg)
+		require.NoError(t, err)
+		require.NoError(t, sendFunc(context.Background()))
+
+		assert.Empty(t, repo.created)
+		require.Len(t, entitiesChan, 1)
+		assert.Equal(t, existing, <-entitiesChan)
+		assert.True(t, existing.LockExpiresAt.After(time.Now()))
+	})
+
+	t.Run("existing sent message is not handed off again", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.byID = map[string]*OutboxEntity{"order-1-created": {ID: "order-1-created", Status: StatusSent}}
+		entitiesChan := make(chan *OutboxEntity, 1)
+		o := newIdempotentOutbox(repo, entitiesChan)
+
+		msg := newTestMessage()
+		msg.IdempotencyKey = "order-1-created"
+		sendFunc, err := o.Create(context.Background(), msg)
+		require.NoError(t, err)
+		require.NoError(t, sendFunc(context.Background()))
+
+		assert.Empty(t, repo.created)
+		assert.Empty(t, entitiesChan)
+	})
+
+	t.Run("concurrent duplicate fails with ErrDuplicateIdempotencyKey", func(t *testing.T) {
+		repo := &racingRepository{mockRepository: newMockRepository()}
+		o := NewOutbox(zap.NewNop(), repo, make(chan *OutboxEntity, 1), &mockSerializer{}, &mockTracePropagator{}, &mockHeaderPopulator{}, Config{})
+
+		msg := newTestMessage()
+		msg.IdempotencyKey = "order-1-created"
+		sendFunc, err := o.Create(context.Background(), msg)
+
+		require.ErrorIs(t, err, ErrDuplicateIdempotencyKey)
+		assert.Nil(t, sendFunc)
+	})
+}
+
+// racingRepository finds no entity but reports a duplicate on insert, as if a concurrent Create
+// inserted it in between.
+type racingRepository struct {
+	*mockRepository
+}
+
+func (r *racingRepository) Create(ctx context.Context, payload []byte, id string, key string, topic string, headers map[string]string, deliverAt time.Time) (*OutboxEntity, error) {
+	return nil, errDuplicateEntity
+}
@@ -0,0 +1,149 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Headers set on replayed messages so consumers can tell them apart from the original delivery.
+const (
+	// HeaderReplay is "true" on replayed messages.
+	HeaderReplay = "replay"
+	// HeaderReplayOf holds the id of the outbox message that was replayed.
+	HeaderReplayOf = "replay_of"
+)
+
+// replayBatchSize is the number of messages read and enqueued per round trip.
+const replayBatchSize = 500
+
+// ErrInvalidReplay is returned by Replayer.Replay for a request without a topic.
+var ErrInvalidReplay = errors.New("invalid outbox replay request")
+
+// ReplayRequest selects SENT messages to publish again.
+type ReplayRequest struct {
+	// Topic of the original messages. Required.
+	Topic string
+	// Key optionally restricts the replay to a single aggregate.
+	Key string
+	// CreatedAfter and CreatedBefore bound the creation time of the original messages;
+	// zero values are open. CreatedBefore is capped at the time the replay starts.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// TargetTopic overrides the topic the copies are published to.
+	TargetTopic string
+	// DryRun only counts the matching messages.
+	DryRun bool
+}
+
+// ReplayResult summarizes a replay.
+type ReplayResult struct {
+	// Matched is the number of SENT messages selected by the request.
+	Matched int64
+	// Enqueued is the number of copies created for delivery, zero for a dry run.
+	Enqueued int64
+}
+
+// Replayer republishes historical outbox messages.
+type Replayer interface {
+	// Replay enqueues a copy of every SENT message matching the request. Copies get a new id,
+	// keep the payload and headers (including event_id) and carry the replay headers.
+	// On error the result reports the copies enqueued so far.
+	Replay(ctx context.Context, req ReplayRequest) (ReplayResult, error)
+}
+
+type replayer struct {
+	outboxRepository Repository
+}
+
+// NewReplayer creates a Replayer backed by the outbox repository.
+func NewReplayer(outboxRepository Repository) Replayer {
+	return &replayer{outboxRepository: outboxRepository}
+}
+
+func (r *replayer) Replay(ctx context.Context, req ReplayRequest) (ReplayResult, error) {
+	if req.Topic == "" {
+		return ReplayResult{}, fmt.Errorf("%w: topic is required", ErrInvalidReplay)
+	}
+
+	now := time.Now().UTC()
+	filter := ListFilter{
+		Status:        StatusSent,
+		Topic:         req.Topic,
+		Key:           req.Key,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+	}
+	// Copies are created now, so they can never match a replay that ends before it started.
+	if filter.CreatedBefore.IsZero() || filter.CreatedBefore.After(now) {
+		filter.CreatedBefore = now
+	}
+
+	if req.DryRun {
+		matched, err := r.outboxRepository.Count(ctx, filter)
+		if err != nil {
+			return ReplayResult{}, fmt.Errorf("failed to count outbox messages for replay: %w", err)
+		}
+		return ReplayResult{Matched: matched}, nil
+	}
+
+	targetTopic := req.TargetTopic
+	if targetTopic == "" {
+		targetTopic = req.Topic
+	}
+
+	var (
+		result ReplayResult
+		after  *OutboxEntity
+	)
+	for {
+		entities, err := r.outboxRepository.FindForReplay(ctx, filter, after, replayBatchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to find outbox messages for replay: %w", err)
+		}
+		if len(entities) == 0 {
+			return result, nil
+		}
+		result.Matched += int64(len(entities))
+
+		copies := make([]*OutboxEntity, 0, len(entities))
+		for _, e := range entities {
+			copies = append(copies, newReplayCopy(e, targetTopic, now))
+		}
+		if err = r.outboxRepository.Enqueue(ctx, copies); err != nil {
+			return result, fmt.Errorf("failed to enqueue outbox replay: %w", err)
+		}
+		result.Enqueued += int64(len(copies))
+
+		if len(entities) < replayBatchSize {
+			return result, nil
+		}
+		after = entities[len(entities)-1]
+	}
+}
+
+// newReplayCopy returns a PROCESSING copy of e that is due right away.
+func newReplayCopy(e *OutboxEntity, topic string, now time.Time) *OutboxEntity {
+	headers := maps.Clone(e.Headers)
+	if headers == nil {
+		headers = make(map[string]string, 2)
+	}
+	headers[HeaderReplay] = "true"
+	headers[HeaderReplayOf] = e.ID
+
+	return &OutboxEntity{
+		ID:               uuid.NewString(),
+		Payload:          e.Payload,
+		Key:              e.Key,
+		Topic:            topic,
+		Headers:          headers,
+		Status:           StatusProcessing,
+		CreatedAt:        now,
+		LockExpiresAt:    now,
+		NextAttemptAfter: now,
+	}
+}
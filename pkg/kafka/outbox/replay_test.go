@@ -0,0 +1,113 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReplayableEntities(n int) []*OutboxEntity {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entities := make([]*OutboxEntity, 0, n)
+	for i := range n {
+		entities = append(entities, &OutboxEntity{
+			ID:        fmt.Sprintf("id-%d", i),
+			Payload:   []byte("payload"),
+			Key:       "order-1",
+			Topic:     "orders",
+			Headers:   map[string]string{"event_id": fmt.Sprintf("event-%d", i)},
+			Status:    StatusSent,
+			CreatedAt: created.Add(time.Duration(i) * time.Second),
+			SentAt:    created.Add(time.Duration(i) * time.Second),
+		})
+	}
+	return entities
+}
+
+func TestReplayer_Replay(t *testing.T) {
+	t.Run("requires topic", func(t *testing.T) {
+		r := NewReplayer(newMockRepository())
+
+		_, err := r.Replay(context.Background(), ReplayRequest{Key: "order-1"})
+
+		assert.ErrorIs(t, err, ErrInvalidReplay)
+	})
+
+	t.Run("dry run only counts", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.replayable = newReplayableEntities(3)
+		r := NewReplayer(repo)
+
+		result, err := r.Replay(context.Background(), ReplayRequest{Topic: "orders", DryRun: true})
+
+		require.NoError(t, err)
+		assert.Equal(t, ReplayResult{Matched: 3}, result)
+		assert.Empty(t, repo.enqueued)
+	})
+
+	t.Run("selects sent messages and caps the range at the start time", func(t *testing.T) {
+		repo := newMockRepository()
+		r := NewReplayer(repo)
+		after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		_, err := r.Replay(context.Background(), ReplayRequest{
+			Topic:         "orders",
+			Key:           "order-1",
+			CreatedAfter:  after,
+			CreatedBefore: time.Now().Add(time.Hour),
+		})
+
+		require.NoError(t, err)
+		require.NotEmpty(t, repo.replayFilters)
+		filter := repo.replayFilters[0]
+		assert.Equal(t, StatusSent, filter.Status)
+		assert.Equal(t, "orders", filter.Topic)
+		assert.Equal(t, "order-1", filter.Key)
+		assert.Equal(t, after, filter.CreatedAfter)
+		assert.False(t, filter.CreatedBefore.After(time.Now()))
+	})
+
+	t.Run("enqueues marked copies in batches", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.replayable = newReplayableEntities(replayBatchSize + 2)
+		r := NewReplayer(repo)
+
+		result, err := r.Replay(context.Background(), ReplayRequest{Topic: "orders"})
+
+		require.NoError(t, err)
+		assert.Equal(t, ReplayResult{Matched: replayBatchSize + 2, Enqueued: replayBatchSize + 2}, result)
+		require.Len(t, repo.enqueued, replayBatchSize+2)
+
+		replayed := repo.enqueued[0]
+		original := repo.replayable[0]
+		assert.NotEqual(t, original.ID, replayed.ID)
+		assert.Equal(t, "orders", replayed.Topic)
+		assert.Equal(t, original.Key, replayed.Key)
+		assert.Equal(t, original.Payload, replayed.Payload)
+		assert.Equal(t, StatusProcessing, replayed.Status)
+		assert.Zero(t, replayed.AttemptsToSend)
+		assert.Equal(t, replayed.CreatedAt, replayed.NextAttemptAfter)
+		assert.Equal(t, "event-0", replayed.Headers["event_id"])
+		assert.Equal(t, "true", replayed.Headers[HeaderReplay])
+		assert.Equal(t, original.ID, replayed.Headers[HeaderReplayOf])
+		assert.NotContains(t, original.Headers, HeaderReplay, "original headers must not be modified")
+	})
+
+	t.Run("overrides target topic", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.replayable = newReplayableEntities(2)
+		r := NewReplayer(repo)
+
+		result, err := r.Replay(context.Background(), ReplayRequest{Topic: "orders", TargetTopic: "orders-rebuild"})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), result.Enqueued)
+		for _, e := range repo.enqueued {
+			assert.Equal(t, "orders-rebuild", e.Topic)
+		}
+	})
+}
@@ -4,8 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"sync"
 	"time"
 
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
@@ -13,52 +18,340 @@ import (
 
 var errEntityNotFound = errors.New("entity not found in database")
 
+var errDuplicateEntity = errors.New("entity already exists in database")
+
+// ErrTenantRequired is returned in tenant-scoped mode when the context carries no tenant slug.
+var ErrTenantRequired = errors.New("outbox is tenant-scoped but no tenant in context")
+
 type Repository interface {
 
 	// can return errEntityNotFound.
 	FetchAndLock(ctx context.Context) (*OutboxEntity, error)
 
-	Create(ctx context.Context, payload []byte, id string, key string, topic string, headers map[string]string) (*OutboxEntity, error)
+	// FetchAndLockBatch locks up to n eligible entities in one round trip under a fresh
+	// lease owner id. Returns an empty slice when nothing is due.
+	FetchAndLockBatch(ctx context.Context, n int) ([]*OutboxEntity, error)
+
+	// Create inserts a new entity. With a zero deliverAt the entity is briefly locked for
+	// the direct hand-off after commit; otherwise it becomes due for the fetcher at deliverAt.
+	// Returns errDuplicateEntity if an entity with the id already exists.
+	Create(ctx context.Context, payload []byte, id string, key string, topic string, headers map[string]string, deliverAt time.Time) (*OutboxEntity, error)
 
 	UpdateAsSentByIDs(ctx context.Context, ids []string) error
+
+	// UpdateLastError stores the latest delivery error without changing the status.
+	UpdateLastError(ctx context.Context, id string, lastError string) error
+
+	// MarkAsFailed moves an entity to the terminal StatusFailed.
+	MarkAsFailed(ctx context.Context, id string) error
+
+	// Find returns entities matching the filter, newest first.
+	Find(ctx context.Context, filter ListFilter) ([]*OutboxEntity, error)
+
+	// FindByID can return errEntityNotFound.
+	FindByID(ctx context.Context, id string) (*OutboxEntity, error)
+
+	// LockByID locks the entity with the id under a fresh lease owner, like FetchAndLock,
+	// if it is due for a delivery attempt. Returns errEntityNotFound if it is missing,
+	// not due, locked or no longer pending.
+	LockByID(ctx context.Context, id string) (*OutboxEntity, error)
+
+	// Requeue makes a PROCESSING or FAILED entity immediately eligible for delivery
+	// with a fresh attempts counter. Can return errEntityNotFound.
+	Requeue(ctx context.Context, id string) error
+
+	// Delete removes an entity. Can return errEntityNotFound.
+	Delete(ctx context.Context, id string) error
+
+	// Count returns the number of entities matching the filter; Limit and Skip are ignored.
+	Count(ctx context.Context, filter ListFilter) (int64, error)
+
+	// FindForReplay returns up to limit entities matching the filter in (createdAt, _id) order,
+	// starting after the given entity; nil starts from the beginning.
+	FindForReplay(ctx context.Context, filter ListFilter, after *OutboxEntity, limit int64) ([]*OutboxEntity, error)
+
+	// Enqueue inserts ready-made entities, e.g. replay copies.
+	Enqueue(ctx context.Context, entities []*OutboxEntity) error
+
+	// FindSentBefore returns up to limit SENT entities delivered before the given time, oldest first.
+	FindSentBefore(ctx context.Context, before time.Time, limit int) ([]*OutboxEntity, error)
+
+	// DeleteSent deletes the SENT entities with the given ids and returns how many were deleted.
+	DeleteSent(ctx context.Context, ids []string) (int64, error)
+
+	// PendingBacklog returns the number of PROCESSING entities and the oldest creation time per topic.
+	PendingBacklog(ctx context.Context) ([]TopicBacklog, error)
+
+	// HasUnsentPredecessor reports whether an earlier PROCESSING entity with the same key
+	// and topic exists. Used to keep per-key ordering in strict ordering mode.
+	HasUnsentPredecessor(ctx context.Context, entity *OutboxEntity) (bool, error)
 }
 
+// orderedFetchRetries bounds how often fetchAndLockOrdered retries after losing a lock race.
+const orderedFetchRetries = 3
+
 type outboxRepository struct {
-	coll             *mongodriver.Collection
+	collections      mongo.CollectionProvider
 	maxBackoffMillis int64
+	strictOrdering   bool
+	tenantScoped     bool
+	retention        RetentionConfig
+	ownerID          string
+	// indexedTenants holds the tenant slugs whose outbox indexes were ensured by this instance.
+	indexedTenants sync.Map
 }
 
-func NewOutboxRepository(database *mongodriver.Database, config Config) Repository {
+// NewOutboxRepository creates a Repository on top of the given collection provider.
+// With Config.TenantScoped the provider must resolve the tenant collection from context
+// (see tenant.MultiTenantCollectionProvider) and every call requires a tenant slug in context.
+func NewOutboxRepository(collections mongo.CollectionProvider, config Config) Repository {
+	ownerID, err := os.Hostname()
+	if err != nil || ownerID == "" {
+		ownerID = "outbox"
+	}
 	return &outboxRepository{
-		coll:             database.Collection("outbox"),
+		collections:      collections,
 		maxBackoffMillis: config.MaxBackoff.Milliseconds(),
+		strictOrdering:   config.StrictOrdering,
+		tenantScoped:     config.TenantScoped,
+		retention:        config.Retention,
+		ownerID:          ownerID,
 	}
 }
 
-func (r *outboxRepository) FetchAndLock(ctx context.Context) (*OutboxEntity, error) {
-	var entity OutboxEntity
+// collection resolves the outbox collection for ctx.
+// Returns ErrTenantRequired instead of panicking when a tenant-scoped call has no tenant.
+func (r *outboxRepository) collection(ctx context.Context) (*mongodriver.Collection, error) {
+	if r.tenantScoped {
+		if _, ok := tenant.SlugFromContext(ctx); !ok {
+			return nil, ErrTenantRequired
+		}
+	}
+	return r.collections.GetCollection(ctx), nil
+}
+
+// withTenant scopes ctx to the tenant; an empty slug leaves ctx unchanged.
+func withTenant(ctx context.Context, slug string) context.Context {
+	if slug == "" {
+		return ctx
+	}
+	return tenant.ContextWithSlug(ctx, slug)
+}
 
+// ensureTenantIndexes creates the outbox indexes in a tenant database on first use,
+// so tenants created after startup are covered without a restart.
+func (r *outboxRepository) ensureTenantIndexes(ctx context.Context, coll *mongodriver.Collection) error {
+	if !r.tenantScoped {
+		return nil
+	}
+	slug, _ := tenant.SlugFromContext(ctx)
+	if _, done := r.indexedTenants.Load(slug); done {
+		return nil
+	}
+	if err := ensureCollectionIndexes(ctx, coll, r.retention); err != nil {
+		return fmt.Errorf("failed to ensure outbox indexes for tenant %q: %w", slug, err)
+	}
+	r.indexedTenants.Store(slug, struct{}{})
+	return nil
+}
+
+// newLeaseOwner returns a unique owner id for a single lock claim.
+// The host name prefix makes it easy to see which instance holds a lock.
+func (r *outboxRepository) newLeaseOwner() string {
+	return r.ownerID + "-" + uuid.NewString()
+}
+
+func (r *outboxRepository) FetchAndLock(ctx context.Context) (*OutboxEntity, error) {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.ensureTenantIndexes(ctx, coll); err != nil {
+		return nil, err
+	}
 	now := time.Now().UTC()
 
+	if r.strictOrdering {
+		return r.fetchAndLockOrdered(ctx, coll, now)
+	}
+
 	opts := options.FindOneAndUpdate().SetSort(bson.D{
 		{Key: "nextAttemptAfter", Value: 1},
 		{Key: "createdAt", Value: 1},
 	}).SetReturnDocument(options.After)
 
-	filter := bson.M{
-		"$and": []bson.M{
-			{"nextAttemptAfter": bson.M{"$lt": now}},
-			{"lockExpiresAt": bson.M{"$lt": now}},
-			{"status": StatusProcessing},
-		},
+	return r.lockOne(ctx, coll, eligibleFilter(now), now, opts)
+}
+
+func (r *outboxRepository) FetchAndLockBatch(ctx context.Context, n int) ([]*OutboxEntity, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.ensureTenantIndexes(ctx, coll); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+
+	ids, err := r.findCandidateIDs(ctx, coll, now, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// Candidates locked by another instance in the meantime no longer match the eligibility filter,
+	// so only the documents that carry our lease owner after the update belong to this batch.
+	owner := r.newLeaseOwner()
+	filter := eligibleFilter(now)
+	filter["_id"] = bson.M{"$in": ids}
+
+	if _, err = coll.UpdateMany(ctx, filter, r.lockUpdate(now, owner)); err != nil {
+		return nil, fmt.Errorf("failed to lock outbox entities: %w", err)
 	}
 
+	opts := options.Find().SetSort(bson.D{
+		{Key: "createdAt", Value: 1},
+		{Key: "_id", Value: 1},
+	})
+	cursor, err := coll.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "lockOwner": owner}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch locked outbox entities: %w", err)
+	}
+	defer func() { _ = cursor.Close(ctx) }() //nolint:errcheck // Best effort cleanup
+
+	entities := make([]*OutboxEntity, 0, len(ids))
+	if err = cursor.All(ctx, &entities); err != nil {
+		return nil, fmt.Errorf("failed to decode locked outbox entities: %w", err)
+	}
+	return entities, nil
+}
+
+// findCandidateIDs returns up to n ids of entities that are due for delivery,
+// respecting per-key ordering in strict ordering mode.
+func (r *outboxRepository) findCandidateIDs(ctx context.Context, coll *mongodriver.Collection, now time.Time, n int) ([]string, error) {
+	var (
+		cursor *mongodriver.Cursor
+		err    error
+	)
+	if r.strictOrdering {
+		cursor, err = coll.Aggregate(ctx, orderedCandidatesPipeline(coll.Name(), now, n))
+	} else {
+		opts := options.Find().
+			SetSort(bson.D{
+				{Key: "nextAttemptAfter", Value: 1},
+				{Key: "createdAt", Value: 1},
+			}).
+			SetLimit(int64(n)).
+			SetProjection(bson.M{"_id": 1})
+		cursor, err = coll.Find(ctx, eligibleFilter(now), opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find outbox candidates: %w", err)
+	}
+	defer func() { _ = cursor.Close(ctx) }() //nolint:errcheck // Best effort cleanup
+
+	var candidates []struct {
+		ID string `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &candidates); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox candidates: %w", err)
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
+
+// fetchAndLockOrdered picks the oldest eligible entity that has no earlier unsent entity
+// with the same key and topic, so a failed message blocks its successors until it is delivered.
+func (r *outboxRepository) fetchAndLockOrdered(ctx context.Context, coll *mongodriver.Collection, now time.Time) (*OutboxEntity, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	// Another instance may lock the candidate between the lookup and the update.
+	for range orderedFetchRetries {
+		ids, err := r.findCandidateIDs(ctx, coll, now, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("failed to fetch outbox entity: %w", errEntityNotFound)
+		}
+
+		filter := eligibleFilter(now)
+		filter["_id"] = ids[0]
+
+		entity, err := r.lockOne(ctx, coll, filter, now, opts)
+		if errors.Is(err, errEntityNotFound) {
+			continue
+		}
+		return entity, err
+	}
+
+	return nil, fmt.Errorf("failed to fetch outbox entity: %w", errEntityNotFound)
+}
+
+// orderedCandidatesPipeline selects eligible entities without an earlier PROCESSING entity
+// of the same key and topic. FAILED entities are terminal and do not block. Entities created
+// within the same millisecond are ordered by _id.
+func orderedCandidatesPipeline(collName string, now time.Time, limit int) mongodriver.Pipeline {
+	return mongodriver.Pipeline{
+		{{Key: "$match", Value: eligibleFilter(now)}},
+		{{Key: "$sort", Value: bson.D{
+			{Key: "nextAttemptAfter", Value: 1},
+			{Key: "createdAt", Value: 1},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": collName,
+			"let":  bson.M{"key": "$key", "topic": "$topic", "createdAt": "$createdAt", "id": "$_id"},
+			"pipeline": mongodriver.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$topic", "$$topic"}},
+					bson.M{"$eq": bson.A{"$key", "$$key"}},
+					bson.M{"$eq": bson.A{"$status", StatusProcessing}},
+					bson.M{"$or": bson.A{
+						bson.M{"$lt": bson.A{"$createdAt", "$$createdAt"}},
+						bson.M{"$and": bson.A{
+							bson.M{"$eq": bson.A{"$createdAt", "$$createdAt"}},
+							bson.M{"$lt": bson.A{"$_id", "$$id"}},
+						}},
+					}},
+				}}}}},
+				{{Key: "$limit", Value: 1}},
+				{{Key: "$project", Value: bson.M{"_id": 1}}},
+			},
+			"as": "predecessors",
+		}}},
+		{{Key: "$match", Value: bson.M{"predecessors": bson.M{"$size": 0}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{"_id": 1}}},
+	}
+}
+
+// eligibleFilter matches entities that are due for a delivery attempt and not locked.
+func eligibleFilter(now time.Time) bson.M {
+	return bson.M{
+		"status":           StatusProcessing,
+		"nextAttemptAfter": bson.M{"$lt": now},
+		"lockExpiresAt":    bson.M{"$lt": now},
+	}
+}
+
+// lockUpdate locks an entity for the given owner and schedules its next attempt.
+func (r *outboxRepository) lockUpdate(now time.Time, owner string) mongodriver.Pipeline {
 	// Aggregation pipeline to calculate exponential backoff:
 	// delay = min(baseDelay * 2^attempts, maxDelay)
 	// baseDelay = 30s, maxDelay = config.MaxBackoff
-	update := mongodriver.Pipeline{
+	return mongodriver.Pipeline{
 		bson.D{{Key: "$set", Value: bson.M{
 			"lockExpiresAt":  now.Add(30 * time.Second),
+			"lockOwner":      owner,
 			"attemptsToSend": bson.M{"$add": []any{"$attemptsToSend", 1}},
 			"nextAttemptAfter": bson.M{
 				"$add": []any{
@@ -74,8 +367,13 @@ func (r *outboxRepository) FetchAndLock(ctx context.Context) (*OutboxEntity, err
 			},
 		}}},
 	}
+}
 
-	err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&entity)
+// lockOne locks the first entity matching the filter.
+func (r *outboxRepository) lockOne(ctx context.Context, coll *mongodriver.Collection, filter bson.M, now time.Time, opts *options.FindOneAndUpdateOptionsBuilder) (*OutboxEntity, error) {
+	var entity OutboxEntity
+
+	err := coll.FindOneAndUpdate(ctx, filter, r.lockUpdate(now, r.newLeaseOwner()), opts).Decode(&entity)
 
 	if err != nil {
 		if errors.Is(err, mongodriver.ErrNoDocuments) {
@@ -87,8 +385,16 @@ func (r *outboxRepository) FetchAndLock(ctx context.Context) (*OutboxEntity, err
 	return &entity, nil
 }
 
-func (r *outboxRepository) Create(ctx context.Context, payload []byte, id string, key string, topic string, headers map[string]string) (*OutboxEntity, error) {
+func (r *outboxRepository) Create(ctx context.Context, payload []byte, id string, key string, topic string, headers map[string]string, deliverAt time.Time) (*OutboxEntity, error) {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
 	now := time.Now().UTC()
+	lockExpiresAt, nextAttemptAfter := now.Add(10*time.Second), now.Add(10*time.Second)
+	if !deliverAt.IsZero() {
+		lockExpiresAt, nextAttemptAfter = now, deliverAt.UTC()
+	}
 	entity := OutboxEntity{
 		ID:               id,
 		Payload:          payload,
@@ -97,19 +403,25 @@ func (r *outboxRepository) Create(ctx context.Context, payload []byte, id string
 		Headers:          headers,
 		CreatedAt:        now,
 		Status:           StatusProcessing,
-		LockExpiresAt:    now.Add(10 * time.Second),
-		NextAttemptAfter: now.Add(10 * time.Second),
+		LockExpiresAt:    lockExpiresAt,
+		NextAttemptAfter: nextAttemptAfter,
 		AttemptsToSend:   0,
 	}
-	_, err := r.coll.InsertOne(ctx, entity)
-	if err != nil {
+	if _, err = coll.InsertOne(ctx, entity); err != nil {
+		if mongodriver.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to insert outbox entity %s: %w: %w", id, errDuplicateEntity, err)
+		}
 		return nil, fmt.Errorf("failed to insert outbox entity: %w", err)
 	}
 	return &entity, nil
 }
 
 func (r *outboxRepository) UpdateAsSentByIDs(ctx context.Context, ids []string) error {
-	_, err := r.coll.UpdateMany(ctx,
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = coll.UpdateMany(ctx,
 		bson.M{"_id": bson.M{"$in": ids}},
 		bson.M{
 			"$set": bson.M{
@@ -118,6 +430,7 @@ func (r *outboxRepository) UpdateAsSentByIDs(ctx context.Context, ids []string)
 			},
 			"$unset": bson.M{
 				"lockExpiresAt":    "",
+				"lockOwner":        "",
 				"nextAttemptAfter": "",
 			},
 			"$inc": bson.M{"confirmations": 1},
@@ -127,3 +440,274 @@ func (r *outboxRepository) UpdateAsSentByIDs(ctx context.Context, ids []string)
 	}
 	return nil
 }
+
+func (r *outboxRepository) UpdateLastError(ctx context.Context, id string, lastError string) error {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = coll.UpdateOne(ctx,
+		bson.M{"_id": id, "status": StatusProcessing},
+		bson.M{"$set": bson.M{"lastError": lastError}})
+	if err != nil {
+		return fmt.Errorf("failed to update outbox last error: %w", err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) MarkAsFailed(ctx context.Context, id string) error {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = coll.UpdateOne(ctx,
+		bson.M{"_id": id, "status": StatusProcessing},
+		bson.M{
+			"$set": bson.M{
+				"status":   StatusFailed,
+				"failedAt": time.Now().UTC(),
+			},
+			"$unset": bson.M{
+				"lockExpiresAt":    "",
+				"lockOwner":        "",
+				"nextAttemptAfter": "",
+			},
+		})
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message as failed: %w", err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) Find(ctx context.Context, filter ListFilter) ([]*OutboxEntity, error) {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip(filter.Skip).
+		SetLimit(filter.Limit)
+
+	cursor, err := coll.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find outbox entities: %w", err)
+	}
+	defer func() { _ = cursor.Close(ctx) }() //nolint:errcheck // Best effort cleanup
+
+	entities := make([]*OutboxEntity, 0)
+	if err = cursor.All(ctx, &entities); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox entities: %w", err)
+	}
+	return entities, nil
+}
+
+func (r *outboxRepository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count, err := coll.CountDocuments(ctx, filter.toBSON())
+	if err != nil {
+		return 0, fmt.Errorf("failed to count outbox entities: %w", err)
+	}
+	return count, nil
+}
+
+func (r *outboxRepository) FindForReplay(ctx context.Context, filter ListFilter, after *OutboxEntity, limit int64) ([]*OutboxEntity, error) {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := filter.toBSON()
+	if after != nil {
+		query["$or"] = bson.A{
+			bson.M{"createdAt": bson.M{"$gt": after.CreatedAt}},
+			bson.M{"createdAt": after.CreatedAt, "_id": bson.M{"$gt": after.ID}},
+		}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+
+	cursor, err := coll.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find outbox entities: %w", err)
+	}
+	defer func() { _ = cursor.Close(ctx) }() //nolint:errcheck // Best effort cleanup
+
+	entities := make([]*OutboxEntity, 0)
+	if err = cursor.All(ctx, &entities); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox entities: %w", err)
+	}
+	return entities, nil
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, entities []*OutboxEntity) error {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return err
+	}
+	docs := make([]any, 0, len(entities))
+	for _, e := range entities {
+		docs = append(docs, e)
+	}
+	if _, err = coll.InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to insert outbox entities: %w", err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) FindByID(ctx context.Context, id string) (*OutboxEntity, error) {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var entity OutboxEntity
+	err = coll.FindOne(ctx, bson.M{"_id": id}).Decode(&entity)
+	if err != nil {
+		if errors.Is(err, mongodriver.ErrNoDocuments) {
+			return nil, fmt.Errorf("failed to find outbox entity %s: %w", id, errEntityNotFound)
+		}
+		return nil, fmt.Errorf("failed to find outbox entity %s: %w", id, err)
+	}
+	return &entity, nil
+}
+
+func (r *outboxRepository) LockByID(ctx context.Context, id string) (*OutboxEntity, error) {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	filter := eligibleFilter(now)
+	filter["_id"] = id
+	return r.lockOne(ctx, coll, filter, now, options.FindOneAndUpdate().SetReturnDocument(options.After))
+}
+
+func (r *outboxRepository) Requeue(ctx context.Context, id string) error {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	result, err := coll.UpdateOne(ctx,
+		bson.M{
+			"_id":    id,
+			"status": bson.M{"$in": bson.A{StatusProcessing, StatusFailed}},
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":           StatusProcessing,
+				"nextAttemptAfter": now,
+				"lockExpiresAt":    now,
+				"attemptsToSend":   0,
+			},
+			"$unset": bson.M{
+				"failedAt":  "",
+				"lockOwner": "",
+			},
+		})
+	if err != nil {
+		return fmt.Errorf("failed to requeue outbox entity %s: %w", id, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("failed to requeue outbox entity %s: %w", id, errEntityNotFound)
+	}
+	return nil
+}
+
+func (r *outboxRepository) Delete(ctx context.Context, id string) error {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete outbox entity %s: %w", id, err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("failed to delete outbox entity %s: %w", id, errEntityNotFound)
+	}
+	return nil
+}
+
+func (r *outboxRepository) FindSentBefore(ctx context.Context, before time.Time, limit int) ([]*OutboxEntity, error) {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "sentAt", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := coll.Find(ctx, bson.M{"status": StatusSent, "sentAt": bson.M{"$lt": before}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sent outbox entities: %w", err)
+	}
+	defer func() { _ = cursor.Close(ctx) }() //nolint:errcheck // Best effort cleanup
+
+	entities := make([]*OutboxEntity, 0)
+	if err = cursor.All(ctx, &entities); err != nil {
+		return nil, fmt.Errorf("failed to decode sent outbox entities: %w", err)
+	}
+	return entities, nil
+}
+
+func (r *outboxRepository) DeleteSent(ctx context.Context, ids []string) (int64, error) {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return 0, err
+	}
+	result, err := coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "status": StatusSent})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sent outbox entities: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *outboxRepository) HasUnsentPredecessor(ctx context.Context, entity *OutboxEntity) (bool, error) {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return false, err
+	}
+	filter := bson.M{
+		"topic":  entity.Topic,
+		"key":    entity.Key,
+		"status": StatusProcessing,
+		"$or": bson.A{
+			bson.M{"createdAt": bson.M{"$lt": entity.CreatedAt}},
+			bson.M{"createdAt": entity.CreatedAt, "_id": bson.M{"$lt": entity.ID}},
+		},
+	}
+	count, err := coll.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check outbox predecessors: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *outboxRepository) PendingBacklog(ctx context.Context) ([]TopicBacklog, error) {
+	coll, err := r.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := mongodriver.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": StatusProcessing}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$topic",
+			"count":  bson.M{"$sum": 1},
+			"oldest": bson.M{"$min": "$createdAt"},
+		}}},
+	}
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate outbox backlog: %w", err)
+	}
+	defer func() { _ = cursor.Close(ctx) }() //nolint:errcheck // Best effort cleanup
+
+	backlog := make([]TopicBacklog, 0)
+	if err = cursor.All(ctx, &backlog); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox backlog: %w", err)
+	}
+	return backlog, nil
+}
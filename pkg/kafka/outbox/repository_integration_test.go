@@ -0,0 +1,409 @@
+//go:build integration
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
+	"github.com/Sokol111/ecommerce-commons/pkg/testutil/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+)
+
+var testMongo *container.MongoDBContainer
+
+func TestMain(m *testing.M) {
+	testMongo = container.StartDefaultMongoDBContainer()
+	code := m.Run()
+	_ = testMongo.Terminate()
+	os.Exit(code)
+}
+
+// testRetention returns the default retention configuration.
+func testRetention() RetentionConfig {
+	cfg := Config{}
+	cfg.ApplyDefaults()
+	return cfg.Retention
+}
+
+func newTestRepository(db *mongodriver.Database, cfg Config) Repository {
+	return NewOutboxRepository(mongo.NewStaticCollectionProvider(db.Collection(collectionName)), cfg)
+}
+
+func TestRepository_StrictOrdering(t *testing.T) {
+	ctx := context.Background()
+	db := testMongo.Database("outbox_ordering_test")
+	require.NoError(t, EnsureIndexes(ctx, db, testRetention()))
+
+	repo := newTestRepository(db, Config{MaxBackoff: time.Hour, StrictOrdering: true})
+
+	a, err := repo.Create(ctx, []byte("A"), "a", "order-1", "orders", nil, time.Time{})
+	require.NoError(t, err)
+	b, err := repo.Create(ctx, []byte("B"), "b", "order-1", "orders", nil, time.Time{})
+	require.NoError(t, err)
+	c, err := repo.Create(ctx, []byte("C"), "c", "order-2", "orders", nil, time.Time{})
+	require.NoError(t, err)
+
+	// Make every message due right away instead of waiting for the initial delay.
+	makeDue := func(ids ...string) {
+		for _, id := range ids {
+			require.NoError(t, repo.Requeue(ctx, id))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	makeDue(a.ID, b.ID, c.ID)
+
+	t.Run("unsent predecessor is reported", func(t *testing.T) {
+		blocked, err := repo.HasUnsentPredecessor(ctx, b)
+		require.NoError(t, err)
+		assert.True(t, blocked)
+
+		blocked, err = repo.HasUnsentPredecessor(ctx, a)
+		require.NoError(t, err)
+		assert.False(t, blocked)
+	})
+
+	// First attempt of A fails.
+	failures := 1
+	producer := &mockProducer{
+		produceFunc: func(_ context.Context, record *kgo.Record, promise func(*kgo.Record, error)) {
+			if string(record.Value) == "A" && failures > 0 {
+				failures--
+				promise(record, errors.New("broker unavailable"))
+				return
+			}
+			promise(record, nil)
+		},
+	}
+
+	for range 10 {
+		entity, err := repo.FetchAndLock(ctx)
+		if errors.Is(err, errEntityNotFound) {
+			break
+		}
+		require.NoError(t, err)
+
+		var produceErr error
+		producer.Produce(ctx, &kgo.Record{Topic: entity.Topic, Key: []byte(entity.Key), Value: entity.Payload},
+			func(_ *kgo.Record, err error) { produceErr = err })
+
+		if produceErr != nil {
+			// Simulate the backoff elapsing so the failed message competes with its successor.
+			makeDue(entity.ID)
+			continue
+		}
+		require.NoError(t, repo.UpdateAsSentByIDs(ctx, []string{entity.ID}))
+	}
+
+	var order1 []string
+	for _, r := range producer.GetRecords() {
+		if string(r.Key) == "order-1" {
+			order1 = append(order1, string(r.Value))
+		}
+	}
+	assert.Equal(t, []string{"A", "A", "B"}, order1)
+	assert.Len(t, producer.GetRecords(), 4, "unrelated key must not be blocked")
+}
+
+func TestRepository_FetchAndLockBatch(t *testing.T) {
+	ctx := context.Background()
+	db := testMongo.Database("outbox_batch_test")
+	require.NoError(t, EnsureIndexes(ctx, db, testRetention()))
+
+	repo := newTestRepository(db, Config{MaxBackoff: time.Hour})
+	seedDueEntities(t, db.Collection(collectionName), 5)
+
+	first, err := repo.FetchAndLockBatch(ctx, 3)
+	require.NoError(t, err)
+	require.Len(t, first, 3)
+	for _, e := range first {
+		assert.Equal(t, int32(1), e.AttemptsToSend)
+		assert.Equal(t, first[0].LockOwner, e.LockOwner)
+		assert.True(t, e.LockExpiresAt.After(time.Now()))
+	}
+
+	second, err := repo.FetchAndLockBatch(ctx, 3)
+	require.NoError(t, err)
+	require.Len(t, second, 2, "locked entities must not be claimed again")
+	assert.NotEqual(t, first[0].LockOwner, second[0].LockOwner)
+
+	third, err := repo.FetchAndLockBatch(ctx, 3)
+	require.NoError(t, err)
+	assert.Empty(t, third)
+}
+
+// seedDueEntities inserts count entities that are immediately eligible for delivery.
+func seedDueEntities(tb testing.TB, coll *mongodriver.Collection, count int) {
+	tb.Helper()
+
+	past := time.Now().UTC().Add(-time.Minute)
+	docs := make([]any, 0, count)
+	for i := range count {
+		docs = append(docs, OutboxEntity{
+			ID:               fmt.Sprintf("seed-%d-%d", time.Now().UnixNano(), i),
+			Payload:          []byte("payload"),
+			Key:              fmt.Sprintf("key-%d", i),
+			Topic:            "orders",
+			Status:           StatusProcessing,
+			CreatedAt:        past,
+			LockExpiresAt:    past,
+			NextAttemptAfter: past,
+		})
+	}
+	_, err := coll.InsertMany(context.Background(), docs)
+	require.NoError(tb, err)
+}
+
+func newBenchmarkRepository(b *testing.B) Repository {
+	b.Helper()
+
+	ctx := context.Background()
+	db := testMongo.Database("outbox_benchmark")
+	require.NoError(b, db.Drop(ctx))
+	require.NoError(b, EnsureIndexes(ctx, db, testRetention()))
+
+	seedDueEntities(b, db.Collection(collectionName), b.N)
+	return newTestRepository(db, Config{MaxBackoff: time.Hour})
+}
+
+func BenchmarkRepository_FetchAndLock(b *testing.B) {
+	repo := newBenchmarkRepository(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.FetchAndLock(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRepository_FetchAndLockBatch(b *testing.B) {
+	for _, size := range []int{10, 100, 500} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			repo := newBenchmarkRepository(b)
+			ctx := context.Background()
+
+			b.ResetTimer()
+			for locked := 0; locked < b.N; {
+				entities, err := repo.FetchAndLockBatch(ctx, size)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(entities) == 0 {
+					b.Fatal("ran out of eligible entities")
+				}
+				locked += len(entities)
+			}
+		})
+	}
+}
+
+func TestRepository_TenantScoped(t *testing.T) {
+	ctx := context.Background()
+	shared := testMongo.Database("outbox_tenant_test")
+	cfg := Config{MaxBackoff: time.Hour, TenantScoped: true}
+	repo := NewOutboxRepository(tenant.NewMultiTenantCollectionProvider(shared, collectionName), cfg)
+
+	t.Run("requires tenant in context", func(t *testing.T) {
+		_, err := repo.Create(ctx, []byte("payload"), "no-tenant", "key", "orders", nil, time.Time{})
+		assert.ErrorIs(t, err, ErrTenantRequired)
+	})
+
+	t.Run("stores messages in tenant database", func(t *testing.T) {
+		acmeCtx := tenant.ContextWithSlug(ctx, "acme")
+		_, err := repo.Create(acmeCtx, []byte("payload"), "acme-1", "key", "orders", nil, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, repo.Requeue(acmeCtx, "acme-1"))
+		time.Sleep(10 * time.Millisecond)
+
+		count, err := testMongo.Client.Database("outbox_tenant_test_acme").Collection(collectionName).CountDocuments(ctx, bson.M{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		globexCtx := tenant.ContextWithSlug(ctx, "globex")
+		entities, err := repo.FetchAndLockBatch(globexCtx, 10)
+		require.NoError(t, err)
+		assert.Empty(t, entities, "tenants must not see each other's messages")
+
+		entities, err = repo.FetchAndLockBatch(acmeCtx, 10)
+		require.NoError(t, err)
+		require.Len(t, entities, 1)
+		assert.Equal(t, "acme-1", entities[0].ID)
+
+		indexes, err := testMongo.Client.Database("outbox_tenant_test_acme").Collection(collectionName).Indexes().ListSpecifications(ctx)
+		require.NoError(t, err)
+		names := make([]string, 0, len(indexes))
+		for _, idx := range indexes {
+			names = append(names, idx.Name)
+		}
+		assert.Contains(t, names, idxStatusNextAttemptLock, "indexes are created on first fetch")
+	})
+}
+
+func TestRepository_ScheduledDelivery(t *testing.T) {
+	ctx := context.Background()
+	db := testMongo.Database("outbox_scheduled_test")
+	require.NoError(t, EnsureIndexes(ctx, db, testRetention()))
+
+	repo := newTestRepository(db, Config{MaxBackoff: time.Hour})
+
+	deliverAt := time.Now().Add(300 * time.Millisecond)
+	scheduled, err := repo.Create(ctx, []byte("payload"), "scheduled-1", "key", "orders", nil, deliverAt)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), scheduled.AttemptsToSend)
+
+	entities, err := repo.FetchAndLockBatch(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, entities, "scheduled message must not be fetched before it is due")
+
+	time.Sleep(time.Until(deliverAt) + 10*time.Millisecond)
+
+	entities, err = repo.FetchAndLockBatch(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, entities, 1)
+	assert.Equal(t, "scheduled-1", entities[0].ID)
+
+	t.Run("only sent messages expire", func(t *testing.T) {
+		indexes, listErr := db.Collection(collectionName).Indexes().ListSpecifications(ctx)
+		require.NoError(t, listErr)
+
+		var ttlIndexes []string
+		for _, idx := range indexes {
+			if idx.ExpireAfterSeconds != nil {
+				ttlIndexes = append(ttlIndexes, idx.Name)
+			}
+		}
+		assert.Equal(t, []string{idxSentAtTTL}, ttlIndexes)
+	})
+}
+
+func TestRepository_DuplicateID(t *testing.T) {
+	ctx := context.Background()
+	db := testMongo.Database("outbox_duplicate_test")
+	require.NoError(t, EnsureIndexes(ctx, db, testRetention()))
+
+	repo := newTestRepository(db, Config{MaxBackoff: time.Hour})
+
+	_, err := repo.Create(ctx, []byte("payload"), "order-1-created", "key", "orders", nil, time.Time{})
+	require.NoError(t, err)
+
+	_, err = repo.Create(ctx, []byte("payload"), "order-1-created", "key", "orders", nil, time.Time{})
+	assert.ErrorIs(t, err, errDuplicateEntity)
+}
+
+func TestEnsureIndexes_Retention(t *testing.T) {
+	ctx := context.Background()
+	db := testMongo.Database("outbox_retention_test")
+	coll := db.Collection(collectionName)
+
+	ttlIndexes := func() map[string]int32 {
+		specs, err := coll.Indexes().ListSpecifications(ctx)
+		require.NoError(t, err)
+		result := make(map[string]int32)
+		for _, spec := range specs {
+			if spec.ExpireAfterSeconds != nil {
+				result[spec.Name] = *spec.ExpireAfterSeconds
+			}
+		}
+		return result
+	}
+
+	require.NoError(t, EnsureIndexes(ctx, db, RetentionConfig{Sent: time.Hour}))
+	assert.Equal(t, map[string]int32{idxSentAtTTL: 3600}, ttlIndexes())
+
+	t.Run("changed periods replace the TTL indexes", func(t *testing.T) {
+		retention := RetentionConfig{Sent: 2 * time.Hour, Failed: 24 * time.Hour}
+		require.NoError(t, EnsureIndexes(ctx, db, retention))
+		assert.Equal(t, map[string]int32{idxSentAtTTL: 7200, idxFailedAtTTL: 86400}, ttlIndexes())
+	})
+
+	t.Run("archival replaces the TTL index for sent messages", func(t *testing.T) {
+		retention := RetentionConfig{Sent: 2 * time.Hour, Failed: 24 * time.Hour, Archive: ArchiveConfig{Enabled: true}}
+		require.NoError(t, EnsureIndexes(ctx, db, retention))
+		assert.Equal(t, map[string]int32{idxFailedAtTTL: 86400}, ttlIndexes())
+	})
+}
+
+func TestArchiver_Collection(t *testing.T) {
+	ctx := context.Background()
+	db := testMongo.Database("outbox_archiver_test")
+	require.NoError(t, EnsureIndexes(ctx, db, RetentionConfig{Sent: time.Hour, Archive: ArchiveConfig{Enabled: true}}))
+
+	repo := newTestRepository(db, Config{MaxBackoff: time.Hour})
+	sentAt := time.Now().UTC().Add(-2 * time.Hour)
+	_, err := db.Collection(collectionName).InsertMany(ctx, []any{
+		OutboxEntity{ID: "old", Topic: "orders", Status: StatusSent, CreatedAt: sentAt, SentAt: sentAt},
+		OutboxEntity{ID: "recent", Topic: "orders", Status: StatusSent, CreatedAt: time.Now().UTC(), SentAt: time.Now().UTC()},
+		OutboxEntity{ID: "pending", Topic: "orders", Status: StatusProcessing, CreatedAt: sentAt},
+	})
+	require.NoError(t, err)
+
+	sink := NewCollectionArchiveSink(mongo.NewStaticCollectionProvider(db.Collection(ArchiveCollectionName)))
+	cfg := Config{}
+	cfg.ApplyDefaults()
+	cfg.Retention.Sent = time.Hour
+	cfg.Retention.Archive.Enabled = true
+	a := NewArchiver(repo, sink, nil, cfg, zap.NewNop())
+
+	archived, err := a.archive(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), archived)
+
+	var copied OutboxEntity
+	require.NoError(t, db.Collection(ArchiveCollectionName).FindOne(ctx, bson.M{"_id": "old"}).Decode(&copied))
+	assert.Equal(t, StatusSent, copied.Status)
+
+	remaining, err := db.Collection(collectionName).CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), remaining)
+
+	t.Run("archiving again is idempotent", func(t *testing.T) {
+		require.NoError(t, sink.Archive(ctx, []*OutboxEntity{&copied}))
+	})
+}
+
+func TestReplayer_Mongo(t *testing.T) {
+	ctx := context.Background()
+	db := testMongo.Database("outbox_replay_test")
+	require.NoError(t, EnsureIndexes(ctx, db, testRetention()))
+
+	repo := newTestRepository(db, Config{MaxBackoff: time.Hour})
+	created := time.Now().UTC().Add(-time.Hour).Truncate(time.Millisecond)
+	docs := make([]any, 0, replayBatchSize+1)
+	for i := range replayBatchSize + 1 {
+		// Every message shares createdAt so paging relies on the _id tie-breaker.
+		docs = append(docs, OutboxEntity{ID: fmt.Sprintf("sent-%04d", i), Topic: "orders", Status: StatusSent, CreatedAt: created, SentAt: created})
+	}
+	docs = append(docs, OutboxEntity{ID: "pending", Topic: "orders", Status: StatusProcessing, CreatedAt: created})
+	_, err := db.Collection(collectionName).InsertMany(ctx, docs)
+	require.NoError(t, err)
+
+	r := NewReplayer(repo)
+
+	dryRun, err := r.Replay(ctx, ReplayRequest{Topic: "orders", DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, ReplayResult{Matched: replayBatchSize + 1}, dryRun)
+
+	result, err := r.Replay(ctx, ReplayRequest{Topic: "orders"})
+	require.NoError(t, err)
+	assert.Equal(t, ReplayResult{Matched: replayBatchSize + 1, Enqueued: replayBatchSize + 1}, result)
+
+	replayed, err := db.Collection(collectionName).CountDocuments(ctx, bson.M{
+		"status":                  StatusProcessing,
+		"headers." + HeaderReplay: "true",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(replayBatchSize+1), replayed)
+}
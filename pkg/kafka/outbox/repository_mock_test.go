@@ -2,8 +2,11 @@ package outbox
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
 )
 
 // mockRepository is a mock implementation of repository interface for testing
@@ -15,9 +18,29 @@ type mockRepository struct {
 	fetchAndLockErr    error
 	fetchAndLockCalls  int
 	fetchAndLockFunc   func(ctx context.Context) (*OutboxEntity, error)
+	fetchBatchFunc     func(ctx context.Context, n int) ([]*OutboxEntity, error)
+	fetchBatchSizes    []int
 	updateAsSentIDs    []string
 	updateAsSentErr    error
 	updateAsSentCalls  int
+	lastErrors         map[string]string
+	failedIDs          []string
+	markAsFailedErr    error
+	findFilter         ListFilter
+	findResult         []*OutboxEntity
+	findErr            error
+	byID               map[string]*OutboxEntity
+	requeuedIDs        []string
+	deletedIDs         []string
+	sentTenants        []string
+	backlog            []TopicBacklog
+	sentBefore         []*OutboxEntity
+	deletedSentIDs     []string
+	replayable         []*OutboxEntity
+	replayFilters      []ListFilter
+	enqueued           []*OutboxEntity
+	hasPredecessor     bool
+	hasPredecessorErr  error
 }
 
 func newMockRepository() *mockRepository {
@@ -33,13 +56,16 @@ func newMockRepositoryWithFetchFunc(fetchFunc func(ctx context.Context) (*Outbox
 	}
 }
 
-func (m *mockRepository) Create(ctx context.Context, payload []byte, id string, key string, topic string, headers map[string]string) (*OutboxEntity, error) {
+func (m *mockRepository) Create(ctx context.Context, payload []byte, id string, key string, topic string, headers map[string]string, deliverAt time.Time) (*OutboxEntity, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if m.createErr != nil {
 		return nil, m.createErr
 	}
+	if _, ok := m.byID[id]; ok {
+		return nil, errDuplicateEntity
+	}
 
 	entity := &OutboxEntity{
 		ID:             id,
@@ -52,7 +78,14 @@ func (m *mockRepository) Create(ctx context.Context, payload []byte, id string,
 		LockExpiresAt:  time.Now().Add(10 * time.Second),
 		AttemptsToSend: 0,
 	}
+	if !deliverAt.IsZero() {
+		entity.LockExpiresAt = entity.CreatedAt
+		entity.NextAttemptAfter = deliverAt
+	}
 	m.created = append(m.created, entity)
+	if m.byID != nil {
+		m.byID[id] = entity
+	}
 	return entity, nil
 }
 
@@ -80,12 +113,44 @@ func (m *mockRepository) FetchAndLock(ctx context.Context) (*OutboxEntity, error
 	return nil, errEntityNotFound
 }
 
+// FetchAndLockBatch delegates to fetchBatchFunc if set, otherwise returns
+// at most one entity from FetchAndLock per call.
+func (m *mockRepository) FetchAndLockBatch(ctx context.Context, n int) ([]*OutboxEntity, error) {
+	m.mu.Lock()
+	m.fetchBatchSizes = append(m.fetchBatchSizes, n)
+	batchFunc := m.fetchBatchFunc
+	m.mu.Unlock()
+
+	if batchFunc != nil {
+		return batchFunc(ctx, n)
+	}
+
+	entity, err := m.FetchAndLock(ctx)
+	if errors.Is(err, errEntityNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []*OutboxEntity{entity}, nil
+}
+
+func (m *mockRepository) GetFetchBatchSizes() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]int, len(m.fetchBatchSizes))
+	copy(result, m.fetchBatchSizes)
+	return result
+}
+
 func (m *mockRepository) UpdateAsSentByIDs(ctx context.Context, ids []string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.updateAsSentCalls++
 	m.updateAsSentIDs = append(m.updateAsSentIDs, ids...)
+	slug, _ := tenant.SlugFromContext(ctx)
+	m.sentTenants = append(m.sentTenants, slug)
 
 	if m.updateAsSentErr != nil {
 		return m.updateAsSentErr
@@ -94,6 +159,184 @@ func (m *mockRepository) UpdateAsSentByIDs(ctx context.Context, ids []string) er
 	return nil
 }
 
+func (m *mockRepository) UpdateLastError(ctx context.Context, id string, lastError string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.lastErrors == nil {
+		m.lastErrors = make(map[string]string)
+	}
+	m.lastErrors[id] = lastError
+	return nil
+}
+
+func (m *mockRepository) MarkAsFailed(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.markAsFailedErr != nil {
+		return m.markAsFailedErr
+	}
+
+	m.failedIDs = append(m.failedIDs, id)
+	return nil
+}
+
+func (m *mockRepository) Find(ctx context.Context, filter ListFilter) ([]*OutboxEntity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.findFilter = filter
+	return m.findResult, m.findErr
+}
+
+func (m *mockRepository) FindByID(ctx context.Context, id string) (*OutboxEntity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entity, ok := m.byID[id]
+	if !ok {
+		return nil, errEntityNotFound
+	}
+	return entity, nil
+}
+
+func (m *mockRepository) LockByID(ctx context.Context, id string) (*OutboxEntity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	entity, ok := m.byID[id]
+	if !ok || entity.Status != StatusProcessing || !entity.LockExpiresAt.Before(now) || !entity.NextAttemptAfter.Before(now) {
+		return nil, errEntityNotFound
+	}
+	entity.LockExpiresAt = now.Add(30 * time.Second)
+	return entity, nil
+}
+
+func (m *mockRepository) Requeue(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.byID[id]; !ok {
+		return errEntityNotFound
+	}
+	m.requeuedIDs = append(m.requeuedIDs, id)
+	return nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.byID[id]; !ok {
+		return errEntityNotFound
+	}
+	delete(m.byID, id)
+	m.deletedIDs = append(m.deletedIDs, id)
+	return nil
+}
+
+func (m *mockRepository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.replayFilters = append(m.replayFilters, filter)
+	return int64(len(m.replayable)), nil
+}
+
+func (m *mockRepository) FindForReplay(ctx context.Context, filter ListFilter, after *OutboxEntity, limit int64) ([]*OutboxEntity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.replayFilters = append(m.replayFilters, filter)
+	start := 0
+	if after != nil {
+		for i, e := range m.replayable {
+			if e.ID == after.ID {
+				start = i + 1
+			}
+		}
+	}
+	end := min(start+int(limit), len(m.replayable))
+	return m.replayable[start:end], nil
+}
+
+func (m *mockRepository) Enqueue(ctx context.Context, entities []*OutboxEntity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.enqueued = append(m.enqueued, entities...)
+	return nil
+}
+
+func (m *mockRepository) FindSentBefore(ctx context.Context, before time.Time, limit int) ([]*OutboxEntity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]*OutboxEntity, 0, limit)
+	for _, e := range m.sentBefore {
+		if len(result) == limit {
+			break
+		}
+		if e.SentAt.Before(before) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockRepository) DeleteSent(ctx context.Context, ids []string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+	remaining := m.sentBefore[:0]
+	for _, e := range m.sentBefore {
+		if !deleted[e.ID] {
+			remaining = append(remaining, e)
+		}
+	}
+	m.sentBefore = remaining
+	m.deletedSentIDs = append(m.deletedSentIDs, ids...)
+	return int64(len(ids)), nil
+}
+
+func (m *mockRepository) PendingBacklog(ctx context.Context) ([]TopicBacklog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.backlog, nil
+}
+
+func (m *mockRepository) HasUnsentPredecessor(ctx context.Context, entity *OutboxEntity) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.hasPredecessor, m.hasPredecessorErr
+}
+
+func (m *mockRepository) GetLastErrors() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make(map[string]string, len(m.lastErrors))
+	for k, v := range m.lastErrors {
+		result[k] = v
+	}
+	return result
+}
+
+func (m *mockRepository) GetFailedIDs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]string, len(m.failedIDs))
+	copy(result, m.failedIDs)
+	return result
+}
+
 func (m *mockRepository) SetFetchAndLockEntity(entity *OutboxEntity) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
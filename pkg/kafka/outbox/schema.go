@@ -2,8 +2,10 @@ package outbox
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
@@ -13,25 +15,27 @@ const (
 	collectionName = "outbox"
 
 	// Index names.
-	idxCreatedAtTTL          = "outbox_createdAt_ttl"
+	idxSentAtTTL             = "outbox_sentAt_ttl"
+	idxFailedAtTTL           = "outbox_failedAt_ttl"
+	idxStatusSentAt          = "outbox_status_sentAt"
 	idxStatusNextAttemptLock = "outbox_status_nextAttemptAfter_lockExpiresAt"
+	idxTopicKeyStatusCreated = "outbox_topic_key_status_createdAt"
 
-	// TTL for outbox documents (5 days).
-	ttlSeconds = 5 * 24 * 60 * 60 // 432000 seconds
+	// idxLegacyCreatedAtTTL expired documents regardless of status, including pending ones;
+	// it is replaced by the per-status TTL indexes.
+	idxLegacyCreatedAtTTL = "outbox_createdAt_ttl"
 )
 
-// EnsureIndexes creates required indexes for outbox collection.
-// This is idempotent - safe to call multiple times.
-func EnsureIndexes(ctx context.Context, database *mongodriver.Database) error {
-	coll := database.Collection(collectionName)
+// EnsureIndexes creates required indexes for outbox collection and applies the retention
+// as TTL indexes. This is idempotent - safe to call multiple times; changed retention
+// periods replace the existing TTL indexes.
+// In tenant-scoped mode the repository ensures the indexes of each tenant database on first use.
+func EnsureIndexes(ctx context.Context, database *mongodriver.Database, retention RetentionConfig) error {
+	return ensureCollectionIndexes(ctx, database.Collection(collectionName), retention)
+}
 
+func ensureCollectionIndexes(ctx context.Context, coll *mongodriver.Collection, retention RetentionConfig) error {
 	indexes := []mongodriver.IndexModel{
-		{
-			Keys: bson.D{{Key: "createdAt", Value: 1}},
-			Options: options.Index().
-				SetName(idxCreatedAtTTL).
-				SetExpireAfterSeconds(ttlSeconds),
-		},
 		{
 			Keys: bson.D{
 				{Key: "status", Value: 1},
@@ -41,11 +45,82 @@ func EnsureIndexes(ctx context.Context, database *mongodriver.Database) error {
 			Options: options.Index().
 				SetName(idxStatusNextAttemptLock),
 		},
+		{
+			// Supports the predecessor lookup used by Config.StrictOrdering.
+			Keys: bson.D{
+				{Key: "topic", Value: 1},
+				{Key: "key", Value: 1},
+				{Key: "status", Value: 1},
+				{Key: "createdAt", Value: 1},
+			},
+			Options: options.Index().
+				SetName(idxTopicKeyStatusCreated),
+		},
+	}
+
+	if retention.Archive.Enabled {
+		// Supports the Archiver scan, which replaces the TTL index for SENT messages.
+		indexes = append(indexes, mongodriver.IndexModel{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "sentAt", Value: 1}},
+			Options: options.Index().SetName(idxStatusSentAt),
+		})
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	_, err := coll.Indexes().CreateMany(ctx, indexes)
-	return err
+	if err := mongo.DropIndexIfExists(ctx, coll, idxLegacyCreatedAtTTL); err != nil {
+		return err
+	}
+
+	if _, err := coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return err
+	}
+
+	// Only delivered and failed documents expire: pending and scheduled messages must never
+	// be deleted. With archival enabled the Archiver deletes SENT messages instead.
+	sentTTL := retention.Sent
+	if retention.Archive.Enabled {
+		sentTTL = 0
+	}
+	if err := ensureTTLIndex(ctx, coll, idxSentAtTTL, "sentAt", StatusSent, sentTTL); err != nil {
+		return err
+	}
+	return ensureTTLIndex(ctx, coll, idxFailedAtTTL, "failedAt", StatusFailed, retention.Failed)
+}
+
+// ensureTTLIndex makes documents with the given status expire ttl after field.
+// A zero ttl removes the index; an index with a different ttl is recreated.
+func ensureTTLIndex(ctx context.Context, coll *mongodriver.Collection, name, field, status string, ttl time.Duration) error {
+	seconds := int32(ttl / time.Second) //nolint:gosec // retention periods are far below the int32 range
+	specs, err := coll.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list indexes: %w", err)
+	}
+	for _, spec := range specs {
+		if spec.Name != name {
+			continue
+		}
+		if seconds > 0 && spec.ExpireAfterSeconds != nil && *spec.ExpireAfterSeconds == seconds {
+			return nil
+		}
+		if err = mongo.DropIndexIfExists(ctx, coll, name); err != nil {
+			return err
+		}
+	}
+	if seconds <= 0 {
+		return nil
+	}
+
+	_, err = coll.Indexes().CreateOne(ctx, mongodriver.IndexModel{
+		Keys: bson.D{{Key: field, Value: 1}},
+		Options: options.Index().
+			SetName(name).
+			SetExpireAfterSeconds(seconds).
+			SetPartialFilterExpression(bson.M{"status": status}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create index %s: %w", name, err)
+	}
+	return nil
 }
@@ -2,18 +2,31 @@ package outbox
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/Sokol111/ecommerce-commons/pkg/kafka/producer"
 	"github.com/twmb/franz-go/pkg/kgo"
 	"go.uber.org/zap"
 )
 
+// flusher is implemented by producers that buffer records, such as *kgo.Client.
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
 type Sender struct {
 	producer        producer.Producer
 	entitiesChan    <-chan *OutboxEntity
 	confirmChan     chan<- ConfirmResult
 	logger          *zap.Logger
 	tracePropagator TracePropagator
+	metrics         *Metrics
+	drainTimeout    time.Duration
+	// inFlight counts records handed to the producer whose result has not reached confirmChan yet.
+	inFlight sync.WaitGroup
+	// drained is closed once the drain ended; the Confirmer may be stopped from then on.
+	drained chan struct{}
 }
 
 func NewSender(
@@ -22,6 +35,8 @@ func NewSender(
 	confirmChan chan ConfirmResult,
 	logger *zap.Logger,
 	tracePropagator TracePropagator,
+	metrics *Metrics,
+	config Config,
 ) *Sender {
 	return &Sender{
 		producer:        producer,
@@ -29,21 +44,29 @@ func NewSender(
 		confirmChan:     confirmChan,
 		logger:          logger,
 		tracePropagator: tracePropagator,
+		metrics:         metrics,
+		drainTimeout:    config.DrainTimeout,
+		drained:         make(chan struct{}),
 	}
 }
 
+// Run sends entities until ctx is cancelled, then drains: it stops taking new entities,
+// flushes the producer and waits until every in-flight result reached the Confirmer.
+// The Confirmer must therefore be stopped after the Sender.
 func (s *Sender) Run(ctx context.Context) error {
 	defer s.logger.Info("sender worker stopped")
 
 	for {
 		select {
 		case <-ctx.Done():
+			s.drain()
 			return nil
 		default:
 		}
 
 		select {
 		case <-ctx.Done():
+			s.drain()
 			return nil
 		case entity := <-s.entitiesChan:
 			s.send(ctx, entity)
@@ -52,6 +75,32 @@ func (s *Sender) Run(ctx context.Context) error {
 	}
 }
 
+// drain waits for the in-flight results up to the drain timeout. Results arriving later are
+// dropped, as the Confirmer may already be stopped; their messages are sent again.
+func (s *Sender) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.drainTimeout)
+	defer cancel()
+	defer close(s.drained)
+
+	if f, ok := s.producer.(flusher); ok {
+		if err := f.Flush(ctx); err != nil {
+			s.logger.Warn("failed to flush producer on shutdown", zap.Error(err))
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.logger.Info("sender drained")
+	case <-ctx.Done():
+		s.logger.Warn("sender drain timed out, unconfirmed messages will be sent again")
+	}
+}
+
 func (s *Sender) send(ctx context.Context, entity *OutboxEntity) {
 	_, span, kafkaHeaders := s.tracePropagator.StartKafkaProducerSpan(entity.Headers, entity.Topic, entity.ID)
 	defer span.End()
@@ -63,9 +112,29 @@ func (s *Sender) send(ctx context.Context, entity *OutboxEntity) {
 		Headers: kafkaHeaders,
 	}
 
-	entityID := entity.ID
+	result := ConfirmResult{
+		ID:        entity.ID,
+		Tenant:    entity.tenantSlug(),
+		Topic:     entity.Topic,
+		CreatedAt: entity.CreatedAt,
+	}
 	confirmChan := s.confirmChan
-	s.producer.Produce(ctx, record, func(_ *kgo.Record, err error) {
-		confirmChan <- ConfirmResult{ID: entityID, Err: err}
+	drained := s.drained
+	logger := s.logger
+	metrics := s.metrics
+	s.inFlight.Add(1)
+	// Shutdown must not abort records that are already buffered; drain bounds their delivery.
+	s.producer.Produce(context.WithoutCancel(ctx), record, func(_ *kgo.Record, err error) {
+		defer s.inFlight.Done()
+		if err != nil {
+			metrics.recordProduceFailure(ctx, result.Topic)
+		}
+		result.Err = err
+		select {
+		case confirmChan <- result:
+		case <-drained:
+			logger.Warn("dropping send result after drain, message will be sent again",
+				zap.String("id", result.ID), zap.Error(err))
+		}
 	})
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Sokol111/ecommerce-commons/pkg/tenant"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/twmb/franz-go/pkg/kgo"
@@ -32,6 +33,39 @@ func (m *mockProducer) Produce(ctx context.Context, record *kgo.Record, promise
 	}
 }
 
+// bufferingProducer completes produce callbacks only on Flush, like a lingering kgo client.
+type bufferingProducer struct {
+	mu       sync.Mutex
+	pending  []func()
+	flushed  bool
+	produced []*kgo.Record
+}
+
+func (p *bufferingProducer) Produce(_ context.Context, record *kgo.Record, promise func(*kgo.Record, error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = append(p.pending, func() {
+		p.produced = append(p.produced, record)
+		promise(record, nil)
+	})
+}
+
+func (p *bufferingProducer) Flush(context.Context) error {
+	p.mu.Lock()
+	pending := p.pending
+	p.pending = nil
+	p.flushed = true
+	p.mu.Unlock()
+
+	// Complete asynchronously, as kgo does from its own goroutines.
+	go func() {
+		for _, complete := range pending {
+			complete()
+		}
+	}()
+	return nil
+}
+
 func (m *mockProducer) GetRecords() []*kgo.Record {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -66,13 +100,24 @@ type senderNoopSpan struct {
 func (n senderNoopSpan) End(options ...trace.SpanEndOption) {}
 
 func TestSender_Run(t *testing.T) {
+	t.Run("propagates tenant slug to confirm result", func(t *testing.T) {
+		confirmChan := make(chan ConfirmResult, 1)
+		s := NewSender(&mockProducer{}, make(chan *OutboxEntity), confirmChan, zap.NewNop(), &mockSenderTracePropagator{}, newTestMetrics(t), Config{DrainTimeout: time.Second})
+
+		s.send(context.Background(), &OutboxEntity{ID: "acme-1", Headers: map[string]string{tenant.HeaderKey: "acme"}})
+
+		result := <-confirmChan
+		assert.Equal(t, "acme-1", result.ID)
+		assert.Equal(t, "acme", result.Tenant)
+	})
+
 	t.Run("sends entity to kafka", func(t *testing.T) {
 		producer := &mockProducer{}
 		entitiesChan := make(chan *OutboxEntity, 10)
 		confirmChan := make(chan ConfirmResult, 10)
 		propagator := &mockSenderTracePropagator{}
 
-		s := NewSender(producer, entitiesChan, confirmChan, zap.NewNop(), propagator)
+		s := NewSender(producer, entitiesChan, confirmChan, zap.NewNop(), propagator, newTestMetrics(t), Config{DrainTimeout: time.Second})
 
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 		defer cancel()
@@ -120,7 +165,7 @@ func TestSender_Run(t *testing.T) {
 		confirmChan := make(chan ConfirmResult, 10)
 		propagator := &mockSenderTracePropagator{}
 
-		s := NewSender(producer, entitiesChan, confirmChan, zap.NewNop(), propagator)
+		s := NewSender(producer, entitiesChan, confirmChan, zap.NewNop(), propagator, newTestMetrics(t), Config{DrainTimeout: time.Second})
 
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel()
@@ -136,7 +181,7 @@ func TestSender_Run(t *testing.T) {
 		confirmChan := make(chan ConfirmResult, 10)
 		propagator := &mockSenderTracePropagator{}
 
-		s := NewSender(producer, entitiesChan, confirmChan, zap.NewNop(), propagator)
+		s := NewSender(producer, entitiesChan, confirmChan, zap.NewNop(), propagator, newTestMetrics(t), Config{DrainTimeout: time.Second})
 
 		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
 		defer cancel()
@@ -174,7 +219,7 @@ func TestSender_Run(t *testing.T) {
 			},
 		}
 
-		s := NewSender(producer, entitiesChan, confirmChan, zap.NewNop(), propagator)
+		s := NewSender(producer, entitiesChan, confirmChan, zap.NewNop(), propagator, newTestMetrics(t), Config{DrainTimeout: time.Second})
 
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 		defer cancel()
@@ -204,6 +249,75 @@ func TestSender_Run(t *testing.T) {
 	})
 }
 
+func TestSender_Drain(t *testing.T) {
+	producer := &bufferingProducer{}
+	entitiesChan := make(chan *OutboxEntity, 10)
+	confirmChan := make(chan ConfirmResult, 10)
+	s := NewSender(producer, entitiesChan, confirmChan, zap.NewNop(), &mockSenderTracePropagator{}, newTestMetrics(t), Config{DrainTimeout: time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = s.Run(ctx)
+	}()
+
+	entitiesChan <- &OutboxEntity{ID: "a", Topic: "orders"}
+	entitiesChan <- &OutboxEntity{ID: "b", Topic: "orders"}
+	require.Eventually(t, func() bool { return len(entitiesChan) == 0 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+
+	assert.True(t, producer.flushed)
+	require.Len(t, confirmChan, 2, "run must return only after every in-flight result is confirmed")
+	for range 2 {
+		result := <-confirmChan
+		assert.NoError(t, result.Err)
+	}
+}
+
+func TestSender_DrainTimeout(t *testing.T) {
+	var promises []func(*kgo.Record, error)
+	var mu sync.Mutex
+	producer := &mockProducer{produceFunc: func(_ context.Context, _ *kgo.Record, promise func(*kgo.Record, error)) {
+		mu.Lock()
+		defer mu.Unlock()
+		promises = append(promises, promise)
+	}}
+	entitiesChan := make(chan *OutboxEntity, 10)
+	confirmChan := make(chan ConfirmResult) // No confirmer reads after the drain
+	s := NewSender(producer, entitiesChan, confirmChan, zap.NewNop(), &mockSenderTracePropagator{}, newTestMetrics(t), Config{DrainTimeout: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = s.Run(ctx)
+	}()
+
+	entitiesChan <- &OutboxEntity{ID: "a", Topic: "orders"}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(promises) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+
+	completed := make(chan struct{})
+	go func() {
+		defer close(completed)
+		promises[0](nil, nil)
+	}()
+	select {
+	case <-completed:
+	case <-time.After(time.Second):
+		t.Fatal("late produce callback blocked after the drain timed out")
+	}
+}
+
 func TestNewSender(t *testing.T) {
 	t.Run("creates sender with dependencies", func(t *testing.T) {
 		producer := &mockProducer{}
@@ -212,7 +326,7 @@ func TestNewSender(t *testing.T) {
 		log := zap.NewNop()
 		propagator := &mockSenderTracePropagator{}
 
-		s := NewSender(producer, entitiesChan, confirmChan, log, propagator)
+		s := NewSender(producer, entitiesChan, confirmChan, log, propagator, newTestMetrics(t), Config{DrainTimeout: time.Second})
 
 		assert.NotNil(t, s)
 		assert.Equal(t, producer, s.producer)
@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/core/logger"
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+)
+
+// ErrNoTransaction is returned by Outbox.Create when Config.RequireTransaction is set
+// and the context does not carry an active MongoDB transaction.
+var ErrNoTransaction = errors.New("outbox message must be created inside a MongoDB transaction")
+
+// inTransaction reports whether ctx carries a session with a started or running transaction,
+// as is the case inside mongo.TxManager.WithTransaction.
+func inTransaction(ctx context.Context) bool {
+	sess := mongodriver.SessionFromContext(ctx)
+	if sess == nil {
+		return false
+	}
+	return sess.ClientSession().TransactionRunning()
+}
+
+// PublishInTx runs fn and creates the outbox messages in a single transaction,
+// then triggers delivery of the messages after commit.
+//
+// A failed hand-off after commit is only logged: the messages are already persisted
+// and the fetcher delivers them on its next poll.
+//
+// Example:
+//
+//	err := outbox.PublishInTx(ctx, txManager, ob, func(txCtx context.Context) error {
+//	    return repo.Insert(txCtx, order)
+//	}, outbox.Message{Event: event, Topic: "orders", Key: order.ID})
+func PublishInTx(ctx context.Context, txManager mongo.TxManager, o Outbox, fn func(txCtx context.Context) error, msgs ...Message) error {
+	sends, err := mongo.WithTransaction(ctx, txManager, func(txCtx context.Context) ([]SendFunc, error) {
+		if err := fn(txCtx); err != nil {
+			return nil, err
+		}
+
+		sends := make([]SendFunc, 0, len(msgs))
+		for _, msg := range msgs {
+			send, err := o.Create(txCtx, msg)
+			if err != nil {
+				return nil, err
+			}
+			sends = append(sends, send)
+		}
+		return sends, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish outbox messages: %w", err)
+	}
+
+	for _, send := range sends {
+		if sendErr := send(ctx); sendErr != nil {
+			logger.Get(ctx).Warn("outbox hand-off after commit failed, message will be delivered by fetcher", zap.Error(sendErr))
+		}
+	}
+	return nil
+}
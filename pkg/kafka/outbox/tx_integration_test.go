@@ -0,0 +1,124 @@
+//go:build integration
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/core/logger"
+	"github.com/Sokol111/ecommerce-commons/pkg/mongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+func TestPublishInTx_Mongo(t *testing.T) {
+	ctx := logger.With(context.Background(), zap.NewNop())
+	db := testMongo.Database("outbox_tx_test")
+	require.NoError(t, EnsureIndexes(ctx, db, testRetention()))
+
+	cfg := Config{RequireTransaction: true}
+	repo := newTestRepository(db, cfg)
+	o := NewOutbox(zap.NewNop(), repo, make(chan *OutboxEntity, 10), &mockSerializer{}, &mockTracePropagator{}, &mockHeaderPopulator{}, cfg)
+	txManager := mongo.NewTxManager(testMongo.Client, zap.NewNop())
+	orders := db.Collection("orders")
+
+	t.Run("rejects create without transaction", func(t *testing.T) {
+		_, err := o.Create(ctx, newTestMessage())
+		assert.ErrorIs(t, err, ErrNoTransaction)
+	})
+
+	t.Run("commits business write and outbox message together", func(t *testing.T) {
+		err := PublishInTx(ctx, txManager, o, func(txCtx context.Context) error {
+			_, err := orders.InsertOne(txCtx, bson.M{"_id": "order-1"})
+			return err
+		}, newTestMessage())
+		require.NoError(t, err)
+
+		count, err := db.Collection(collectionName).CountDocuments(ctx, bson.M{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("rolls back outbox message with failed business write", func(t *testing.T) {
+		before, err := db.Collection(collectionName).CountDocuments(ctx, bson.M{})
+		require.NoError(t, err)
+
+		err = PublishInTx(ctx, txManager, o, func(txCtx context.Context) error {
+			if _, insertErr := orders.InsertOne(txCtx, bson.M{"_id": "order-2"}); insertErr != nil {
+				return insertErr
+			}
+			return errors.New("validation failed")
+		}, newTestMessage())
+		require.Error(t, err)
+
+		after, err := db.Collection(collectionName).CountDocuments(ctx, bson.M{})
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+}
+
+func TestIdempotencyKeyRace_Mongo(t *testing.T) {
+	ctx := logger.With(context.Background(), zap.NewNop())
+	db := testMongo.Database("outbox_idempotency_race_test")
+	require.NoError(t, EnsureIndexes(ctx, db, testRetention()))
+
+	cfg := Config{RequireTransaction: true}
+	repo := newTestRepository(db, cfg)
+	o := NewOutbox(zap.NewNop(), repo, make(chan *OutboxEntity, 10), &mockSerializer{}, &mockTracePropagator{}, &mockHeaderPopulator{}, cfg)
+	txManager := mongo.NewTxManager(testMongo.Client, zap.NewNop())
+
+	newMessage := func() Message {
+		msg := newTestMessage()
+		msg.IdempotencyKey = "order-1-created"
+		return msg
+	}
+
+	// The first transaction creates the message and stays open until the second one tried too.
+	created, release := make(chan struct{}), make(chan struct{})
+	first := make(chan error, 1)
+	go func() {
+		_, err := txManager.WithTransaction(ctx, func(txCtx context.Context) (any, error) {
+			if _, err := o.Create(txCtx, newMessage()); err != nil {
+				return nil, err
+			}
+			close(created)
+			<-release
+			return nil, nil
+		})
+		first <- err
+	}()
+	<-created
+
+	var attempts atomic.Int32
+	second := make(chan error, 1)
+	go func() {
+		// Callers retry their transaction on ErrDuplicateIdempotencyKey; the driver retries it on
+		// write conflicts with the open transaction.
+		for {
+			_, err := txManager.WithTransaction(ctx, func(txCtx context.Context) (any, error) {
+				attempts.Add(1)
+				return o.Create(txCtx, newMessage())
+			})
+			if !errors.Is(err, ErrDuplicateIdempotencyKey) {
+				second <- err
+				return
+			}
+		}
+	}()
+
+	require.Eventually(t, func() bool { return attempts.Load() > 0 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	require.NoError(t, <-first)
+	require.NoError(t, <-second)
+	count, err := db.Collection(collectionName).CountDocuments(ctx, bson.M{"_id": "order-1-created"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
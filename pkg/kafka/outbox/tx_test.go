@@ -0,0 +1,91 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Sokol111/ecommerce-commons/pkg/core/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// mockTxManager runs the function directly, recording whether it "committed".
+type mockTxManager struct {
+	committed bool
+}
+
+func (m *mockTxManager) WithTransaction(ctx context.Context, fn func(txCtx context.Context) (any, error)) (any, error) {
+	result, err := fn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.committed = true
+	return result, nil
+}
+
+func TestOutbox_RequireTransaction(t *testing.T) {
+	t.Run("rejects create outside transaction", func(t *testing.T) {
+		repo := newMockRepository()
+		o := NewOutbox(zap.NewNop(), repo, make(chan *OutboxEntity, 1), &mockSerializer{}, &mockTracePropagator{}, &mockHeaderPopulator{}, Config{RequireTransaction: true})
+
+		sendFunc, err := o.Create(context.Background(), newTestMessage())
+
+		assert.ErrorIs(t, err, ErrNoTransaction)
+		assert.Nil(t, sendFunc)
+		assert.Empty(t, repo.created)
+	})
+}
+
+func TestPublishInTx(t *testing.T) {
+	t.Run("creates messages in transaction and sends them after commit", func(t *testing.T) {
+		repo := newMockRepository()
+		entitiesChan := make(chan *OutboxEntity, 10)
+		o := NewOutbox(zap.NewNop(), repo, entitiesChan, &mockSerializer{}, &mockTracePropagator{}, &mockHeaderPopulator{}, Config{})
+		tx := &mockTxManager{}
+		ctx := logger.With(context.Background(), zap.NewNop())
+
+		called := false
+		err := PublishInTx(ctx, tx, o, func(txCtx context.Context) error {
+			called = true
+			return nil
+		}, newTestMessage(), newTestMessage())
+
+		require.NoError(t, err)
+		assert.True(t, called)
+		assert.True(t, tx.committed)
+		assert.Len(t, repo.created, 2)
+		assert.Len(t, entitiesChan, 2)
+	})
+
+	t.Run("does not create messages when business function fails", func(t *testing.T) {
+		repo := newMockRepository()
+		entitiesChan := make(chan *OutboxEntity, 10)
+		o := NewOutbox(zap.NewNop(), repo, entitiesChan, &mockSerializer{}, &mockTracePropagator{}, &mockHeaderPopulator{}, Config{})
+		tx := &mockTxManager{}
+		ctx := logger.With(context.Background(), zap.NewNop())
+
+		err := PublishInTx(ctx, tx, o, func(txCtx context.Context) error {
+			return errors.New("insert failed")
+		}, newTestMessage())
+
+		assert.ErrorContains(t, err, "insert failed")
+		assert.False(t, tx.committed)
+		assert.Empty(t, repo.created)
+		assert.Empty(t, entitiesChan)
+	})
+
+	t.Run("ignores hand-off failure after commit", func(t *testing.T) {
+		repo := newMockRepository()
+		o := NewOutbox(zap.NewNop(), repo, make(chan *OutboxEntity), &mockSerializer{}, &mockTracePropagator{}, &mockHeaderPopulator{}, Config{})
+		tx := &mockTxManager{}
+		ctx := logger.With(context.Background(), zap.NewNop())
+
+		err := PublishInTx(ctx, tx, o, func(txCtx context.Context) error { return nil }, newTestMessage())
+
+		require.NoError(t, err)
+		assert.True(t, tx.committed)
+		assert.Len(t, repo.created, 1)
+	})
+}
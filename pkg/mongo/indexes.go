@@ -0,0 +1,28 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// MongoDB server error codes of a missing index or collection.
+const (
+	codeNamespaceNotFound = 26
+	codeIndexNotFound     = 27
+)
+
+// DropIndexIfExists drops the index with the given name. A missing index or collection is not an error.
+func DropIndexIfExists(ctx context.Context, coll *mongodriver.Collection, name string) error {
+	err := coll.Indexes().DropOne(ctx, name)
+	if err == nil {
+		return nil
+	}
+	var cmdErr mongodriver.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.Code == codeIndexNotFound || cmdErr.Code == codeNamespaceNotFound) {
+		return nil
+	}
+	return fmt.Errorf("failed to drop index %s: %w", name, err)
+}